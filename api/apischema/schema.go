@@ -1,24 +1,111 @@
 package apischema
 
-import "github.com/jponc/competitive-analysis/internal/types"
+import (
+	"time"
+
+	"github.com/jponc/competitive-analysis/internal/types"
+)
 
 type HealthcheckResponse struct {
 	Status string `json:"status"`
 }
 
 type CreateQueryJobRequest struct {
-	Keyword string `json:"keyword"`
+	Keyword        string `json:"keyword"`
+	UserID         string `json:"user_id"`
+	CompareDevices bool   `json:"compare_devices"`
+	TargetDomain   string `json:"target_domain"`
 }
 
 type CreateQueryJobResponse struct {
 	QueryJobID string `json:"query_job_id"`
 }
 
+type GetZenserpUsageResponse struct {
+	MonthStart       time.Time                `json:"month_start"`
+	MonthlyBudget    int                      `json:"monthly_budget"`
+	UsedThisMonth    int                      `json:"used_this_month"`
+	RemainingCredits int                      `json:"remaining_credits"`
+	Users            []types.ZenserpUserUsage `json:"users"`
+}
+
+type GetKeywordClustersRequest struct {
+	QueryJobIDs   []string `json:"query_job_ids"`
+	TopN          int      `json:"top_n"`
+	MinSharedURLs int      `json:"min_shared_urls"`
+}
+
+type GetShareOfVoiceRequest struct {
+	QueryJobIDs   []string       `json:"query_job_ids"`
+	CTRCurve      []float64      `json:"ctr_curve"`
+	SearchVolumes map[string]int `json:"search_volumes"`
+	Limit         int            `json:"limit"`
+}
+
+type CreateWatchlistRequest struct {
+	Name       string   `json:"name"`
+	Domains    []string `json:"domains"`
+	WebhookURL string   `json:"webhook_url"`
+}
+
+type CreateWatchlistResponse struct {
+	WatchlistID string `json:"watchlist_id"`
+}
+
+type DeleteWatchlistResponse struct {
+	Message string `json:"message"`
+}
+
+type CreateWebhookEndpointRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+type CreateWebhookEndpointResponse struct {
+	WebhookEndpointID string `json:"webhook_endpoint_id"`
+	Secret            string `json:"secret"`
+}
+
+type DeleteWebhookEndpointResponse struct {
+	Message string `json:"message"`
+}
+
+type TestWebhookEndpointResponse struct {
+	Message string `json:"message"`
+}
+
 type DeleteQueryJobResponse struct {
 	Message string `json:"message"`
 }
 
 type GetQueryJobsResponse *[]types.QueryJob
-type GetQueryJobResponse *types.QueryJob
+type GetQueryJobResponse struct {
+	*types.QueryJob
+	Locations []types.QueryLocation `json:"locations"`
+}
 type GetQueryJobPositionHits *[]types.QueryJobPositionHit
 type GetQueryJobUrlInfo *types.UrlInfo
+type GetQueryJobDeviceComparison *types.DeviceComparison
+type GetQueryJobVolatility *types.LocationVolatility
+type GetKeywordClustersResponse *types.KeywordClustering
+type GetQueryJobComparison *types.QueryJobComparison
+type GetPageChangesResponse []types.PageChange
+type GetWatchlistsResponse *[]types.Watchlist
+type GetWatchlistReportResponse *types.WatchlistReport
+type GetWatchlistAlertsResponse *[]types.WatchlistAlert
+type GetShareOfVoiceResponse *types.ShareOfVoice
+type GetWebhookEndpointsResponse *[]types.WebhookEndpoint
+type GetWebhookDeliveriesResponse *[]types.WebhookDelivery
+type SearchContentResponse *[]types.ContentSearchResult
+type GetQueryJobCrawlHealthResponse *types.CrawlHealth
+type GetQueryJobContentMetricsResponse *types.ContentMetricsSummary
+type GetQueryJobKeywordUsageResponse *types.KeywordUsageSummary
+
+type RecrawlQueryJobRequest struct {
+	URLs []string `json:"urls"`
+}
+
+type RecrawlQueryJobResponse struct {
+	QueryJobID string   `json:"query_job_id"`
+	URLs       []string `json:"urls"`
+}
@@ -1,10 +1,14 @@
 package eventschema
 
+import "time"
+
 const (
 	QueryJobCreated            string = "QueryJobCreated"
 	ParseQueryJobURL           string = "ParseQueryJobURL"
 	ZenserpBatchDoneProcessing string = "ZenserpBatchDoneProcessing"
 	DoneProcessingQueryJobURL  string = "DoneProcessingQueryJobURL"
+	QueryJobCompleted          string = "QueryJobCompleted"
+	WebhookEvent               string = "WebhookEvent"
 )
 
 type QueryJobCreatedMessage struct {
@@ -20,9 +24,26 @@ type ZenserpBatchDoneProcessingMessage struct {
 type ParseQueryJobURLMessage struct {
 	QueryJobID string `json:"query_job_id"`
 	URL        string `json:"url"`
+	// Attempt is the number of the scrape attempt of the url, the first attempt leaves it empty
+	Attempt int `json:"attempt,omitempty"`
+	// SkipPageCache scrapes the url again even when a fresh page of it is cached
+	SkipPageCache bool `json:"skip_page_cache,omitempty"`
 }
 
 type DoneProcessingQueryJobURLMessage struct {
 	QueryJobID string `json:"query_job_id"`
 	URL        string `json:"url"`
 }
+
+type QueryJobCompletedMessage struct {
+	QueryJobID string `json:"query_job_id"`
+}
+
+// WebhookEventMessage is published again per webhook endpoint subscribed to the event type, and delivered to
+// WebhookEndpointID once it's set
+type WebhookEventMessage struct {
+	EventType         string    `json:"event_type"`
+	QueryJobID        string    `json:"query_job_id"`
+	WebhookEndpointID string    `json:"webhook_endpoint_id"`
+	OccurredAt        time.Time `json:"occurred_at"`
+}
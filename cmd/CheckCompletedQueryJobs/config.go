@@ -8,6 +8,8 @@ import (
 // Config
 type Config struct {
 	RDSConnectionURL string
+	AWSRegion        string
+	SNSPrefix        string
 }
 
 // NewConfig initialises a new config
@@ -17,7 +19,19 @@ func NewConfig() (*Config, error) {
 		return nil, err
 	}
 
+	awsRegion, err := getEnv("AWS_REGION")
+	if err != nil {
+		return nil, err
+	}
+
+	snsPrefix, err := getEnv("SNS_PREFIX")
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
+		AWSRegion:        awsRegion,
+		SNSPrefix:        snsPrefix,
 		RDSConnectionURL: rdsConnectionURL,
 	}, nil
 }
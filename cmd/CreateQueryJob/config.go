@@ -3,13 +3,18 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 )
 
 // Config
 type Config struct {
-	RDSConnectionURL string
-	AWSRegion        string
-	SNSPrefix        string
+	RDSConnectionURL     string
+	AWSRegion            string
+	SNSPrefix            string
+	ZenserpApiKey        string
+	ZenserpMonthlyBudget int
+	SerpCacheTTL         time.Duration
 }
 
 // NewConfig initialises a new config
@@ -29,10 +34,28 @@ func NewConfig() (*Config, error) {
 		return nil, err
 	}
 
+	zenserpApiKey, err := getEnv("ZENSERP_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	zenserpMonthlyBudget, err := getOptionalIntEnv("ZENSERP_MONTHLY_BUDGET")
+	if err != nil {
+		return nil, err
+	}
+
+	serpCacheTTL, err := getDurationEnv("SERP_CACHE_TTL")
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
-		AWSRegion:        awsRegion,
-		SNSPrefix:        snsPrefix,
-		RDSConnectionURL: rdsConnectionURL,
+		AWSRegion:            awsRegion,
+		SNSPrefix:            snsPrefix,
+		RDSConnectionURL:     rdsConnectionURL,
+		ZenserpApiKey:        zenserpApiKey,
+		ZenserpMonthlyBudget: zenserpMonthlyBudget,
+		SerpCacheTTL:         serpCacheTTL,
 	}, nil
 }
 
@@ -45,3 +68,40 @@ func getEnv(key string) (string, error) {
 
 	return v, nil
 }
+
+func getIntEnv(key string) (int, error) {
+	v, err := getEnv(key)
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s environment variable is not a number: %v", key, err)
+	}
+
+	return i, nil
+}
+
+// getOptionalIntEnv returns 0 when the environment variable is unset
+func getOptionalIntEnv(key string) (int, error) {
+	if os.Getenv(key) == "" {
+		return 0, nil
+	}
+
+	return getIntEnv(key)
+}
+
+func getDurationEnv(key string) (time.Duration, error) {
+	v, err := getEnv(key)
+	if err != nil {
+		return 0, err
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s environment variable is not a duration: %v", key, err)
+	}
+
+	return d, nil
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jponc/competitive-analysis/internal/api"
 	"github.com/jponc/competitive-analysis/internal/repository/dbrepository"
 	"github.com/jponc/competitive-analysis/pkg/postgres"
 	"github.com/jponc/competitive-analysis/pkg/sns"
+	"github.com/jponc/competitive-analysis/pkg/zenserp"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -31,6 +35,15 @@ func main() {
 		log.Fatalf("cannot initialise sns client %v", err)
 	}
 
-	service := api.NewService(dbRepository, snsClient, nil)
+	httpClient := &http.Client{
+		Timeout: time.Duration(10 * time.Second),
+	}
+
+	zenserpClient, err := zenserp.NewClient(config.ZenserpApiKey, httpClient, "")
+	if err != nil {
+		log.Fatalf("cannot initialise zenserp client %v", err)
+	}
+
+	service := api.NewService(dbRepository, snsClient, zenserpClient, config.ZenserpMonthlyBudget, config.SerpCacheTTL)
 	lambda.Start(service.CreateQueryJob)
 }
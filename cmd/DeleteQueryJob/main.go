@@ -25,6 +25,6 @@ func main() {
 		log.Fatalf("cannot initialise repository: %v", err)
 	}
 
-	service := api.NewService(dbRepository, nil, nil)
+	service := api.NewService(dbRepository, nil, nil, 0, 0)
 	lambda.Start(service.DeleteQueryJob)
 }
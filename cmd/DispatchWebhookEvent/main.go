@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jponc/competitive-analysis/internal/repository/dbrepository"
+	"github.com/jponc/competitive-analysis/internal/webhooks"
+	"github.com/jponc/competitive-analysis/pkg/postgres"
+	"github.com/jponc/competitive-analysis/pkg/sns"
+	"github.com/jponc/competitive-analysis/pkg/webhook"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+func main() {
+	config, err := NewConfig()
+	if err != nil {
+		log.Fatalf("cannot initialise config %v", err)
+	}
+
+	pgClient, err := postgres.NewClient(config.RDSConnectionURL)
+	if err != nil {
+		log.Fatalf("cannot initialise pg client: %v", err)
+	}
+
+	snsClient, err := sns.NewClient(config.AWSRegion, config.SNSPrefix)
+	if err != nil {
+		log.Fatalf("cannot initialise sns client %v", err)
+	}
+
+	dbRepository, err := dbrepository.NewRepository(pgClient)
+	if err != nil {
+		log.Fatalf("cannot initialise repository: %v", err)
+	}
+
+	webhookClient := webhook.NewClient(&http.Client{Timeout: 10 * time.Second})
+
+	service := webhooks.NewService(dbRepository, snsClient, webhookClient)
+	lambda.Start(service.DispatchWebhookEvent)
+}
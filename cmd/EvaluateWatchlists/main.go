@@ -0,0 +1,36 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jponc/competitive-analysis/internal/repository/dbrepository"
+	"github.com/jponc/competitive-analysis/internal/watchlists"
+	"github.com/jponc/competitive-analysis/pkg/postgres"
+	"github.com/jponc/competitive-analysis/pkg/webhook"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+func main() {
+	config, err := NewConfig()
+	if err != nil {
+		log.Fatalf("cannot initialise config %v", err)
+	}
+
+	pgClient, err := postgres.NewClient(config.RDSConnectionURL)
+	if err != nil {
+		log.Fatalf("cannot initialise pg client: %v", err)
+	}
+
+	dbRepository, err := dbrepository.NewRepository(pgClient)
+	if err != nil {
+		log.Fatalf("cannot initialise repository: %v", err)
+	}
+
+	webhookClient := webhook.NewClient(&http.Client{Timeout: 10 * time.Second})
+
+	service := watchlists.NewService(dbRepository, webhookClient)
+	lambda.Start(service.EvaluateWatchlists)
+}
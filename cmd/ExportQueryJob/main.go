@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jponc/competitive-analysis/internal/exports"
+	"github.com/jponc/competitive-analysis/internal/repository/dbrepository"
+	"github.com/jponc/competitive-analysis/pkg/postgres"
+	"github.com/jponc/competitive-analysis/pkg/s3"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	config, err := NewConfig()
+	if err != nil {
+		log.Fatalf("cannot initialise config %v", err)
+	}
+
+	pgClient, err := postgres.NewClient(config.RDSConnectionURL)
+	if err != nil {
+		log.Fatalf("cannot initialise pg client: %v", err)
+	}
+
+	dbRepository, err := dbrepository.NewRepository(pgClient)
+	if err != nil {
+		log.Fatalf("cannot initialise repository: %v", err)
+	}
+
+	s3Client, err := s3.NewClient(config.AWSRegion, config.ExportsBucket)
+	if err != nil {
+		log.Fatalf("cannot initialise s3 client %v", err)
+	}
+
+	service := exports.NewService(dbRepository, s3Client)
+	lambda.Start(service.ExportQueryJob)
+}
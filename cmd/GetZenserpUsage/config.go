@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Config
+type Config struct {
+	RDSConnectionURL     string
+	ZenserpApiKey        string
+	ZenserpMonthlyBudget int
+}
+
+// NewConfig initialises a new config
+func NewConfig() (*Config, error) {
+	rdsConnectionURL, err := getEnv("DB_CONN_URL")
+	if err != nil {
+		return nil, err
+	}
+
+	zenserpApiKey, err := getEnv("ZENSERP_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	zenserpMonthlyBudget, err := getOptionalIntEnv("ZENSERP_MONTHLY_BUDGET")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{
+		RDSConnectionURL:     rdsConnectionURL,
+		ZenserpApiKey:        zenserpApiKey,
+		ZenserpMonthlyBudget: zenserpMonthlyBudget,
+	}, nil
+}
+
+func getEnv(key string) (string, error) {
+	v := os.Getenv(key)
+
+	if v == "" {
+		return "", fmt.Errorf("%s environment variable missing", key)
+	}
+
+	return v, nil
+}
+
+func getIntEnv(key string) (int, error) {
+	v, err := getEnv(key)
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s environment variable is not a number: %v", key, err)
+	}
+
+	return i, nil
+}
+
+// getOptionalIntEnv returns 0 when the environment variable is unset
+func getOptionalIntEnv(key string) (int, error) {
+	if os.Getenv(key) == "" {
+		return 0, nil
+	}
+
+	return getIntEnv(key)
+}
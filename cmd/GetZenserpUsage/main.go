@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jponc/competitive-analysis/internal/api"
+	"github.com/jponc/competitive-analysis/internal/repository/dbrepository"
+	"github.com/jponc/competitive-analysis/pkg/postgres"
+	"github.com/jponc/competitive-analysis/pkg/zenserp"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	config, err := NewConfig()
+	if err != nil {
+		log.Fatalf("cannot initialise config %v", err)
+	}
+
+	pgClient, err := postgres.NewClient(config.RDSConnectionURL)
+	if err != nil {
+		log.Fatalf("cannot initialise pg client: %v", err)
+	}
+
+	dbRepository, err := dbrepository.NewRepository(pgClient)
+	if err != nil {
+		log.Fatalf("cannot initialise repository: %v", err)
+	}
+
+	httpClient := &http.Client{
+		Timeout: time.Duration(10 * time.Second),
+	}
+
+	zenserpClient, err := zenserp.NewClient(config.ZenserpApiKey, httpClient, "")
+	if err != nil {
+		log.Fatalf("cannot initialise zenserp client %v", err)
+	}
+
+	service := api.NewService(dbRepository, nil, zenserpClient, config.ZenserpMonthlyBudget, 0)
+	lambda.Start(service.GetZenserpUsage)
+}
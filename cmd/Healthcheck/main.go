@@ -6,6 +6,6 @@ import (
 )
 
 func main() {
-	service := api.NewService(nil, nil, nil)
+	service := api.NewService(nil, nil, nil, 0, 0)
 	lambda.Start(service.Healthcheck)
 }
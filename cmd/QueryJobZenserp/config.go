@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 )
 
 // Config
@@ -12,6 +13,7 @@ type Config struct {
 	RDSConnectionURL       string
 	AWSRegion              string
 	SNSPrefix              string
+	SerpCacheTTL           time.Duration
 }
 
 // NewConfig initialises a new config
@@ -41,12 +43,18 @@ func NewConfig() (*Config, error) {
 		return nil, err
 	}
 
+	serpCacheTTL, err := getDurationEnv("SERP_CACHE_TTL")
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		AWSRegion:              awsRegion,
 		SNSPrefix:              snsPrefix,
 		RDSConnectionURL:       rdsConnectionURL,
 		ZenserpApiKey:          zenserpApiKey,
 		ZenserpBatchWebhookURL: zenserpBatchWebhookURL,
+		SerpCacheTTL:           serpCacheTTL,
 	}, nil
 }
 
@@ -59,3 +67,17 @@ func getEnv(key string) (string, error) {
 
 	return v, nil
 }
+
+func getDurationEnv(key string) (time.Duration, error) {
+	v, err := getEnv(key)
+	if err != nil {
+		return 0, err
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s environment variable is not a duration: %v", key, err)
+	}
+
+	return d, nil
+}
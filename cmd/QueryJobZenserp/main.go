@@ -44,6 +44,6 @@ func main() {
 		log.Fatalf("cannot initialise zenserp client %v", err)
 	}
 
-	service := resultrankings.NewService(zenserpClient, dbRepository, snsClient)
+	service := resultrankings.NewService(zenserpClient, dbRepository, snsClient, config.SerpCacheTTL)
 	lambda.Start(service.QueryJobZenserp)
 }
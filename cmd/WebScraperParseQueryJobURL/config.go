@@ -3,6 +3,12 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
+)
+
+const (
+	RendererNone   = "none"
+	RendererChrome = "chrome"
 )
 
 // Config
@@ -11,6 +17,11 @@ type Config struct {
 	TextRazorAPIKey  string
 	AWSRegion        string
 	SNSPrefix        string
+	PageCacheTTL     time.Duration
+	UserAgent        string
+	DomainDelay      time.Duration
+	Renderer         string
+	ChromePath       string
 }
 
 // NewConfig initialises a new config
@@ -35,11 +46,41 @@ func NewConfig() (*Config, error) {
 		return nil, err
 	}
 
+	pageCacheTTL, err := getDurationEnv("PAGE_CACHE_TTL")
+	if err != nil {
+		return nil, err
+	}
+
+	userAgent, err := getEnv("SCRAPER_USER_AGENT")
+	if err != nil {
+		return nil, err
+	}
+
+	domainDelay, err := getDurationEnv("SCRAPER_DOMAIN_DELAY")
+	if err != nil {
+		return nil, err
+	}
+
+	renderer, err := getEnv("SCRAPER_RENDERER")
+	if err != nil {
+		return nil, err
+	}
+
+	if renderer != RendererNone && renderer != RendererChrome {
+		return nil, fmt.Errorf("SCRAPER_RENDERER environment variable must be %s or %s", RendererNone, RendererChrome)
+	}
+
 	return &Config{
 		AWSRegion:        awsRegion,
 		SNSPrefix:        snsPrefix,
 		TextRazorAPIKey:  textrazorApiKey,
 		RDSConnectionURL: rdsConnectionURL,
+		PageCacheTTL:     pageCacheTTL,
+		UserAgent:        userAgent,
+		DomainDelay:      domainDelay,
+		Renderer:         renderer,
+		// optional, Chrome is looked up in its usual install locations
+		ChromePath: os.Getenv("CHROME_PATH"),
 	}, nil
 }
 
@@ -52,3 +93,17 @@ func getEnv(key string) (string, error) {
 
 	return v, nil
 }
+
+func getDurationEnv(key string) (time.Duration, error) {
+	v, err := getEnv(key)
+	if err != nil {
+		return 0, err
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s environment variable is not a duration: %v", key, err)
+	}
+
+	return d, nil
+}
@@ -39,8 +39,16 @@ func main() {
 		Timeout: time.Duration(1 * time.Minute),
 	}
 
-	webscraperClient := webscraper.NewClient(httpClient)
+	var renderer webscraper.Fetcher
+	if config.Renderer == RendererChrome {
+		chromeFetcher := webscraper.NewChromeFetcher(config.ChromePath, config.UserAgent)
+		defer chromeFetcher.Close()
 
-	service := crawler.NewService(webscraperClient, dbRepository, snsClient)
+		renderer = chromeFetcher
+	}
+
+	webscraperClient := webscraper.NewClient(httpClient, config.UserAgent, renderer)
+
+	service := crawler.NewService(webscraperClient, dbRepository, snsClient, config.PageCacheTTL, config.DomainDelay)
 	lambda.Start(service.WebScraperParseQueryJobURL)
 }
@@ -44,6 +44,6 @@ func main() {
 		log.Fatalf("cannot initialise zenserp client %v", err)
 	}
 
-	service := api.NewService(dbRepository, snsClient, zenserpClient)
+	service := api.NewService(dbRepository, snsClient, zenserpClient, 0, 0)
 	lambda.Start(service.ZenserpBatchWebhook)
 }
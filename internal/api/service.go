@@ -2,14 +2,28 @@ package api
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/gofrs/uuid"
 	"github.com/jponc/competitive-analysis/api/apischema"
 	"github.com/jponc/competitive-analysis/api/eventschema"
+	"github.com/jponc/competitive-analysis/internal/contentmetrics"
+	"github.com/jponc/competitive-analysis/internal/crawlhealth"
+	"github.com/jponc/competitive-analysis/internal/keywordusage"
+	"github.com/jponc/competitive-analysis/internal/pagechanges"
+	"github.com/jponc/competitive-analysis/internal/queryjobs"
 	"github.com/jponc/competitive-analysis/internal/repository/dbrepository"
+	"github.com/jponc/competitive-analysis/internal/serpanalysis"
 	"github.com/jponc/competitive-analysis/internal/types"
 	"github.com/jponc/competitive-analysis/pkg/lambdaresponses"
 	"github.com/jponc/competitive-analysis/pkg/zenserp"
@@ -38,21 +52,53 @@ var queryConfigDefaults = QueryConfig{
 	SearchEngine: "google.com",
 }
 
+const (
+	defaultClusterTopN            = 10
+	defaultClusterMinSharedURLs   = 3
+	defaultPageChangesPeriod      = 30 * 24 * time.Hour
+	defaultPageChangesLimit       = 100
+	maxPageChangesLimit           = 500
+	defaultWatchlistAlertsLimit   = 100
+	defaultShareOfVoiceLimit      = 50
+	defaultWebhookDeliveriesLimit = 100
+	maxWebhookDeliveriesLimit     = 500
+	maxWatchlistAlertsLimit       = 500
+	defaultSearchContentLimit     = 20
+	maxSearchContentLimit         = 100
+	maxSearchPhraseLength         = 200
+)
+
+// webhookEventTypes can be subscribed to by webhook endpoints, ping is always delivered when test firing
+var webhookEventTypes = map[string]bool{
+	types.WebhookEventQueryJobCreated:     true,
+	types.WebhookEventQueryJobSerpFetched: true,
+	types.WebhookEventQueryJobCompleted:   true,
+	types.WebhookEventQueryJobFailed:      true,
+}
+
 type SNSClient interface {
 	Publish(ctx context.Context, topic string, message interface{}) error
 }
 
+var errZenserpCreditsExhausted = errors.New("not enough zenserp credits remaining")
+
 type Service struct {
-	dbrepository  *dbrepository.Repository
-	snsClient     SNSClient
-	zenserpClient *zenserp.Client
+	dbrepository         *dbrepository.Repository
+	snsClient            SNSClient
+	zenserpClient        *zenserp.Client
+	zenserpMonthlyBudget int
+	serpCacheTTL         time.Duration
 }
 
-func NewService(dbrepository *dbrepository.Repository, snsClient SNSClient, zenserpClient *zenserp.Client) *Service {
+// NewService instantiates the api service. A zenserpMonthlyBudget of 0 disables the budget check, serpCacheTTL
+// has to match the one searches are cached with so the cached ones aren't charged to the budget.
+func NewService(dbrepository *dbrepository.Repository, snsClient SNSClient, zenserpClient *zenserp.Client, zenserpMonthlyBudget int, serpCacheTTL time.Duration) *Service {
 	s := &Service{
-		dbrepository:  dbrepository,
-		snsClient:     snsClient,
-		zenserpClient: zenserpClient,
+		dbrepository:         dbrepository,
+		snsClient:            snsClient,
+		zenserpClient:        zenserpClient,
+		zenserpMonthlyBudget: zenserpMonthlyBudget,
+		serpCacheTTL:         serpCacheTTL,
 	}
 
 	return s
@@ -81,39 +127,87 @@ func (s *Service) CreateQueryJob(ctx context.Context, request events.APIGatewayP
 		return lambdaresponses.Respond400(fmt.Errorf("bad request"))
 	}
 
+	var targetDomain *string
+	if req.TargetDomain != "" {
+		domain := serpanalysis.NormalizeDomain(req.TargetDomain)
+		if domain == "" {
+			return lambdaresponses.Respond400(fmt.Errorf("invalid target_domain"))
+		}
+		targetDomain = &domain
+	}
+
 	err = s.dbrepository.Connect()
 	if err != nil {
 		log.Errorf("error connecting to repository db: %v", err)
 		return lambdaresponses.Respond500()
 	}
 
-	// Create QueryJob
-	queryJobID, err := s.dbrepository.CreateQueryJob(ctx, req.Keyword)
-	if err != nil {
-		log.Errorf("error creating query job: %v", err)
-		return lambdaresponses.Respond500()
+	defer s.dbrepository.Close()
+
+	devices := []string{queryConfigDefaults.Device}
+	if req.CompareDevices {
+		devices = []string{serpanalysis.DeviceDesktop, serpanalysis.DeviceMobile}
 	}
 
-	// Create QueryLocations
-	for _, location := range queryConfigDefaults.Locations {
-		_, err := s.dbrepository.CreateQueryLocation(
-			ctx,
-			queryJobID.String(),
-			queryConfigDefaults.Device,
-			queryConfigDefaults.SearchEngine,
-			queryConfigDefaults.Num,
-			queryConfigDefaults.Country,
-			location,
-		)
+	var userID *string
+	if req.UserID != "" {
+		userID = &req.UserID
+	}
 
-		if err != nil {
-			log.Errorf("error creating query location: %v", err)
-			return lambdaresponses.Respond500()
+	newQueryJob := types.NewQueryJob{
+		Keyword:          req.Keyword,
+		UserID:           userID,
+		DeviceComparison: req.CompareDevices,
+		TargetDomain:     targetDomain,
+	}
+
+	for _, device := range devices {
+		for _, location := range queryConfigDefaults.Locations {
+			zenserpJob := zenserp.Job{
+				Query:        req.Keyword,
+				Num:          queryConfigDefaults.Num,
+				SearchEngine: queryConfigDefaults.SearchEngine,
+				Device:       device,
+				Country:      queryConfigDefaults.Country,
+				Location:     location,
+			}
+
+			newQueryJob.Locations = append(newQueryJob.Locations, types.NewQueryLocation{
+				Device:       device,
+				SearchEngine: queryConfigDefaults.SearchEngine,
+				Num:          queryConfigDefaults.Num,
+				Country:      queryConfigDefaults.Country,
+				Location:     location,
+				CacheKey:     zenserpJob.CacheKey(),
+			})
 		}
+	}
+
+	// Make sure the Zenserp account can cover every search, cached results may expire before they run
+	err = s.checkZenserpCredits(ctx, len(newQueryJob.Locations))
+	if errors.Is(err, errZenserpCreditsExhausted) {
+		log.Warnf("rejecting query job: %v", err)
+		return lambdaresponses.Respond402(err)
+	}
+	if err != nil {
+		log.Errorf("error checking zenserp credits: %v", err)
+		return lambdaresponses.Respond500()
+	}
 
-		log.Infof("keyword: %s, location: %s", queryJobID, location)
+	// Create QueryJob and QueryLocations, reserving the searches that aren't cached from the monthly budget
+	now := time.Now()
+	queryJobID, err := s.dbrepository.CreateQueryJob(ctx, newQueryJob, now.Add(-s.serpCacheTTL), startOfMonth(now), s.zenserpMonthlyBudget)
+	if errors.Is(err, dbrepository.ErrZenserpBudgetExceeded) {
+		log.Warnf("rejecting query job: %v", err)
+		return lambdaresponses.Respond429(err)
+	}
+	if err != nil {
+		log.Errorf("error creating query job: %v", err)
+		return lambdaresponses.Respond500()
 	}
 
+	log.Infof("created query job %s for keyword: %s, locations: %d", queryJobID, req.Keyword, len(newQueryJob.Locations))
+
 	// Publish QueryJobCreated
 	msg := eventschema.QueryJobCreatedMessage{
 		ID: queryJobID.String(),
@@ -125,9 +219,8 @@ func (s *Service) CreateQueryJob(ctx context.Context, request events.APIGatewayP
 		return lambdaresponses.Respond500()
 	}
 
-	if err := s.dbrepository.Close(); err != nil {
-		log.Fatalf("can't close DB connection")
-	}
+	// Notify webhook endpoints, the query job is already on its way so don't fail the request
+	queryjobs.PublishWebhookEvent(ctx, s.snsClient, types.WebhookEventQueryJobCreated, queryJobID)
 
 	return lambdaresponses.Respond200(apischema.CreateQueryJobResponse{QueryJobID: queryJobID.String()})
 }
@@ -269,11 +362,19 @@ func (s *Service) GetQueryJob(ctx context.Context, request events.APIGatewayProx
 		log.Fatalf("failed to get query job: %v", err)
 	}
 
+	queryLocations, err := s.dbrepository.GetQueryLocations(ctx, queryJobID)
+	if err != nil {
+		log.Fatalf("failed to get query locations: %v", err)
+	}
+
 	if err := s.dbrepository.Close(); err != nil {
 		log.Fatalf("can't close DB connection")
 	}
 
-	return lambdaresponses.Respond200(apischema.GetQueryJobResponse(queryJob))
+	return lambdaresponses.Respond200(apischema.GetQueryJobResponse{
+		QueryJob:  queryJob,
+		Locations: *queryLocations,
+	})
 }
 
 func (s *Service) GetQueryJobPositionHits(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
@@ -341,11 +442,29 @@ func (s *Service) GetQueryJobUrlInfo(ctx context.Context, request events.APIGate
 		log.Fatalf("failed to get query item links: %v", err)
 	}
 
+	fetch, err := s.dbrepository.GetFetchDiagnostic(ctx, queryJobID, url)
+	if err != nil {
+		log.Fatalf("failed to get fetch diagnostic: %v", err)
+	}
+
+	// urls that couldn't be crawled have no body
+	body := ""
+	if queryItem.Body != nil {
+		body = *queryItem.Body
+	}
+
+	mainContent := ""
+	if queryItem.MainContent != nil {
+		mainContent = *queryItem.MainContent
+	}
+
 	urlInfo := types.UrlInfo{
-		Title: queryItem.Title,
-		URL:   queryItem.URL,
-		Body:  *queryItem.Body,
-		Links: *links,
+		Title:       queryItem.Title,
+		URL:         queryItem.URL,
+		Body:        body,
+		MainContent: mainContent,
+		Links:       *links,
+		Fetch:       fetch,
 	}
 
 	if err := s.dbrepository.Close(); err != nil {
@@ -354,3 +473,1137 @@ func (s *Service) GetQueryJobUrlInfo(ctx context.Context, request events.APIGate
 
 	return lambdaresponses.Respond200(apischema.GetQueryJobUrlInfo(&urlInfo))
 }
+
+func (s *Service) GetZenserpUsage(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	if s.zenserpClient == nil {
+		log.Errorf("zenserpClient not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	monthStart := startOfMonth(time.Now())
+
+	used, err := s.dbrepository.GetZenserpUsageCountSince(ctx, monthStart)
+	if err != nil {
+		log.Errorf("failed to get zenserp usage count: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	users, err := s.dbrepository.GetZenserpUsageByUserSince(ctx, monthStart)
+	if err != nil {
+		log.Errorf("failed to get zenserp usage by user: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	status, err := s.zenserpClient.Status(ctx)
+	if err != nil {
+		log.Errorf("failed to get zenserp status: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	res := apischema.GetZenserpUsageResponse{
+		MonthStart:       monthStart,
+		MonthlyBudget:    s.zenserpMonthlyBudget,
+		UsedThisMonth:    used,
+		RemainingCredits: status.RemainingRequests,
+		Users:            *users,
+	}
+
+	return lambdaresponses.Respond200(res)
+}
+
+// checkZenserpCredits returns errZenserpCreditsExhausted when the Zenserp account can't cover requestCount
+// more searches
+func (s *Service) checkZenserpCredits(ctx context.Context, requestCount int) error {
+	if s.zenserpClient == nil {
+		return nil
+	}
+
+	status, err := s.zenserpClient.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get zenserp status: %w", err)
+	}
+
+	if status.RemainingRequests < requestCount {
+		return fmt.Errorf("%w: %d remaining, %d requested", errZenserpCreditsExhausted, status.RemainingRequests, requestCount)
+	}
+
+	return nil
+}
+
+func startOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (s *Service) GetQueryJobDeviceComparison(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	id, found := request.PathParameters["id"]
+	if !found {
+		log.Fatalf("failed to get id path parameter")
+	}
+
+	queryJobID := uuid.FromStringOrNil(id)
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	queryJobs, err := s.dbrepository.GetQueryJobsByIDs(ctx, []uuid.UUID{queryJobID})
+	if err != nil {
+		log.Errorf("failed to get query job: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	if len(*queryJobs) == 0 {
+		return lambdaresponses.Respond404(fmt.Errorf("query job not found"))
+	}
+
+	if !(*queryJobs)[0].DeviceComparison {
+		return lambdaresponses.Respond400(fmt.Errorf("query job was not created with compare_devices"))
+	}
+
+	rankings, err := s.dbrepository.GetQueryJobLocationRankings(ctx, queryJobID)
+	if err != nil {
+		log.Errorf("failed to get query job location rankings: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	return lambdaresponses.Respond200(apischema.GetQueryJobDeviceComparison(serpanalysis.CompareDevices(*rankings)))
+}
+
+func (s *Service) GetQueryJobVolatility(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	id, found := request.PathParameters["id"]
+	if !found {
+		log.Fatalf("failed to get id path parameter")
+	}
+
+	queryJobID, err := uuid.FromString(id)
+	if err != nil {
+		return lambdaresponses.Respond400(fmt.Errorf("invalid query job id: %s", id))
+	}
+
+	err = s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	queryJobs, err := s.dbrepository.GetQueryJobsByIDs(ctx, []uuid.UUID{queryJobID})
+	if err != nil {
+		log.Errorf("failed to get query job: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	if len(*queryJobs) == 0 {
+		return lambdaresponses.Respond404(fmt.Errorf("query job not found"))
+	}
+
+	queryLocations, err := s.dbrepository.GetQueryLocations(ctx, queryJobID)
+	if err != nil {
+		log.Errorf("failed to get query locations: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	rankings, err := s.dbrepository.GetQueryJobLocationRankings(ctx, queryJobID)
+	if err != nil {
+		log.Errorf("failed to get query job location rankings: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	return lambdaresponses.Respond200(apischema.GetQueryJobVolatility(serpanalysis.LocationVolatility(*queryLocations, *rankings)))
+}
+
+func (s *Service) GetKeywordClusters(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	req := &apischema.GetKeywordClustersRequest{}
+
+	err := json.Unmarshal([]byte(request.Body), req)
+	if err != nil || len(req.QueryJobIDs) < 2 || req.TopN < 0 || req.MinSharedURLs < 0 {
+		log.Errorf("failed to Unmarshal or error query job ids")
+		return lambdaresponses.Respond400(fmt.Errorf("bad request"))
+	}
+
+	if req.TopN == 0 {
+		req.TopN = defaultClusterTopN
+	}
+
+	if req.MinSharedURLs == 0 {
+		req.MinSharedURLs = defaultClusterMinSharedURLs
+	}
+
+	var queryJobIDs []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+	for _, id := range req.QueryJobIDs {
+		queryJobID, err := uuid.FromString(id)
+		if err != nil {
+			return lambdaresponses.Respond400(fmt.Errorf("invalid query job id: %s", id))
+		}
+
+		if !seen[queryJobID] {
+			seen[queryJobID] = true
+			queryJobIDs = append(queryJobIDs, queryJobID)
+		}
+	}
+
+	err = s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	queryJobs, err := s.dbrepository.GetQueryJobsByIDs(ctx, queryJobIDs)
+	if err != nil {
+		log.Errorf("failed to get query jobs: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	if len(*queryJobs) != len(queryJobIDs) {
+		return lambdaresponses.Respond404(fmt.Errorf("some query jobs were not found"))
+	}
+
+	for _, queryJob := range *queryJobs {
+		if queryJob.CompletedAt == nil {
+			return lambdaresponses.Respond400(fmt.Errorf("query job %s is not completed yet", queryJob.ID))
+		}
+	}
+
+	rankings, err := s.dbrepository.GetLocationRankingsForQueryJobs(ctx, queryJobIDs)
+	if err != nil {
+		log.Errorf("failed to get location rankings: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	clustering := serpanalysis.ClusterKeywords(*queryJobs, *rankings, req.TopN, req.MinSharedURLs)
+
+	return lambdaresponses.Respond200(apischema.GetKeywordClustersResponse(clustering))
+}
+
+func (s *Service) GetQueryJobComparison(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	id, found := request.PathParameters["id"]
+	if !found {
+		log.Fatalf("failed to get id path parameter")
+	}
+
+	otherID, found := request.PathParameters["otherId"]
+	if !found {
+		log.Fatalf("failed to get otherId path parameter")
+	}
+
+	contentScope := types.ContentScopeMain
+	if v, found := request.QueryStringParameters["content"]; found {
+		contentScope = v
+	}
+
+	if contentScope != types.ContentScopeMain && contentScope != types.ContentScopeFull {
+		return lambdaresponses.Respond400(fmt.Errorf("content must be %s or %s", types.ContentScopeMain, types.ContentScopeFull))
+	}
+
+	fullBody := contentScope == types.ContentScopeFull
+
+	queryJobID, err := uuid.FromString(id)
+	if err != nil {
+		return lambdaresponses.Respond400(fmt.Errorf("invalid query job id: %s", id))
+	}
+
+	otherQueryJobID, err := uuid.FromString(otherID)
+	if err != nil {
+		return lambdaresponses.Respond400(fmt.Errorf("invalid query job id: %s", otherID))
+	}
+
+	err = s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	queryJobs, err := s.dbrepository.GetQueryJobsByIDs(ctx, []uuid.UUID{queryJobID, otherQueryJobID})
+	if err != nil {
+		log.Errorf("failed to get query jobs: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	existing := map[uuid.UUID]bool{}
+	for _, queryJob := range *queryJobs {
+		existing[queryJob.ID] = true
+	}
+
+	if !existing[queryJobID] || !existing[otherQueryJobID] {
+		return lambdaresponses.Respond404(fmt.Errorf("query job not found"))
+	}
+
+	queryJob, err := s.queryJobSnapshot(ctx, queryJobID, fullBody)
+	if err != nil {
+		log.Errorf("failed to get query job snapshot: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	other, err := s.queryJobSnapshot(ctx, otherQueryJobID, fullBody)
+	if err != nil {
+		log.Errorf("failed to get other query job snapshot: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	return lambdaresponses.Respond200(apischema.GetQueryJobComparison(serpanalysis.CompareQueryJobs(*queryJob, *other)))
+}
+
+// queryJobSnapshot loads the locations, rankings and crawled contents of a query job. Expects the repository to be connected.
+func (s *Service) queryJobSnapshot(ctx context.Context, queryJobID uuid.UUID, fullBody bool) (*serpanalysis.QueryJobSnapshot, error) {
+	queryLocations, err := s.dbrepository.GetQueryLocations(ctx, queryJobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query locations: %w", err)
+	}
+
+	rankings, err := s.dbrepository.GetQueryJobLocationRankings(ctx, queryJobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query job location rankings: %w", err)
+	}
+
+	contents, err := s.dbrepository.GetQueryJobURLContents(ctx, queryJobID, fullBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query job url contents: %w", err)
+	}
+
+	return &serpanalysis.QueryJobSnapshot{
+		QueryJobID:     queryJobID,
+		QueryLocations: *queryLocations,
+		Rankings:       *rankings,
+		Contents:       *contents,
+	}, nil
+}
+
+func (s *Service) GetPageChanges(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	since := time.Now().Add(-defaultPageChangesPeriod)
+	if v, found := request.QueryStringParameters["since"]; found {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return lambdaresponses.Respond400(fmt.Errorf("since must be an RFC3339 timestamp"))
+		}
+		since = t
+	}
+
+	var queryJobID *uuid.UUID
+	if v, found := request.QueryStringParameters["query_job_id"]; found {
+		id, err := uuid.FromString(v)
+		if err != nil {
+			return lambdaresponses.Respond400(fmt.Errorf("invalid query_job_id"))
+		}
+		queryJobID = &id
+	}
+
+	limit := defaultPageChangesLimit
+	if v, found := request.QueryStringParameters["limit"]; found {
+		l, err := strconv.Atoi(v)
+		if err != nil || l <= 0 || l > maxPageChangesLimit {
+			return lambdaresponses.Respond400(fmt.Errorf("limit must be between 1 and %d", maxPageChangesLimit))
+		}
+		limit = l
+	}
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	pageSnapshots, err := s.dbrepository.GetChangedPageSnapshots(ctx, since, queryJobID, limit)
+	if err != nil {
+		log.Errorf("failed to get changed page snapshots: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	// Snapshots come in pairs per url, latest first
+	pageChanges := []types.PageChange{}
+	snapshots := *pageSnapshots
+	for i := 0; i+1 < len(snapshots); i++ {
+		current, previous := snapshots[i], snapshots[i+1]
+		if current.URL != previous.URL {
+			continue
+		}
+
+		pageChanges = append(pageChanges, pagechanges.Summarize(previous, current))
+		i++
+	}
+
+	return lambdaresponses.Respond200(apischema.GetPageChangesResponse(pageChanges))
+}
+
+func (s *Service) CreateWatchlist(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	req := &apischema.CreateWatchlistRequest{}
+
+	err := json.Unmarshal([]byte(request.Body), req)
+	if err != nil || req.Name == "" || len(req.Domains) == 0 {
+		log.Errorf("failed to Unmarshal or error name and domains")
+		return lambdaresponses.Respond400(fmt.Errorf("bad request"))
+	}
+
+	domains := []string{}
+	seen := map[string]bool{}
+	for _, input := range req.Domains {
+		domain := serpanalysis.NormalizeDomain(input)
+		if domain == "" {
+			return lambdaresponses.Respond400(fmt.Errorf("invalid domain: %s", input))
+		}
+
+		if !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+
+	var webhookURL *string
+	if req.WebhookURL != "" {
+		u, err := url.Parse(req.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return lambdaresponses.Respond400(fmt.Errorf("invalid webhook_url"))
+		}
+		webhookURL = &req.WebhookURL
+	}
+
+	err = s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	watchlistID, err := s.dbrepository.CreateWatchlist(ctx, req.Name, domains, webhookURL)
+	if err != nil {
+		log.Errorf("failed to create watchlist: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	return lambdaresponses.Respond200(apischema.CreateWatchlistResponse{WatchlistID: watchlistID.String()})
+}
+
+func (s *Service) GetWatchlists(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	watchlists, err := s.dbrepository.GetWatchlists(ctx)
+	if err != nil {
+		log.Errorf("failed to get watchlists: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	return lambdaresponses.Respond200(apischema.GetWatchlistsResponse(watchlists))
+}
+
+func (s *Service) DeleteWatchlist(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	id, found := request.PathParameters["id"]
+	if !found {
+		log.Fatalf("failed to get id path parameter")
+	}
+
+	watchlistID := uuid.FromStringOrNil(id)
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	err = s.dbrepository.DeleteWatchlist(ctx, watchlistID)
+	if err != nil {
+		log.Errorf("error deleting watchlist: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	return lambdaresponses.Respond200(apischema.DeleteWatchlistResponse{Message: "deleted"})
+}
+
+func (s *Service) GetWatchlistReport(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	id, found := request.PathParameters["id"]
+	if !found {
+		log.Fatalf("failed to get id path parameter")
+	}
+
+	watchlistID := uuid.FromStringOrNil(id)
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	watchlist, err := s.dbrepository.GetWatchlist(ctx, watchlistID)
+	if err != nil {
+		log.Errorf("failed to get watchlist: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	if watchlist == nil {
+		return lambdaresponses.Respond404(fmt.Errorf("watchlist not found"))
+	}
+
+	// The report compares the latest query job of every keyword to the one before it
+	queryJobs, err := s.dbrepository.GetLatestCompletedQueryJobs(ctx, 2)
+	if err != nil {
+		log.Errorf("failed to get latest completed query jobs: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	queryJobIDs := []uuid.UUID{}
+	for _, queryJob := range *queryJobs {
+		queryJobIDs = append(queryJobIDs, queryJob.ID)
+	}
+
+	positions, err := s.dbrepository.GetDomainBestPositions(ctx, queryJobIDs, watchlist.Domains)
+	if err != nil {
+		log.Errorf("failed to get domain best positions: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	report := serpanalysis.WatchlistReport(*watchlist, *queryJobs, *positions)
+
+	return lambdaresponses.Respond200(apischema.GetWatchlistReportResponse(report))
+}
+
+func (s *Service) GetWatchlistAlerts(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	id, found := request.PathParameters["id"]
+	if !found {
+		log.Fatalf("failed to get id path parameter")
+	}
+
+	watchlistID := uuid.FromStringOrNil(id)
+
+	limit := defaultWatchlistAlertsLimit
+	if v, found := request.QueryStringParameters["limit"]; found {
+		l, err := strconv.Atoi(v)
+		if err != nil || l <= 0 || l > maxWatchlistAlertsLimit {
+			return lambdaresponses.Respond400(fmt.Errorf("limit must be between 1 and %d", maxWatchlistAlertsLimit))
+		}
+		limit = l
+	}
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	alerts, err := s.dbrepository.GetWatchlistAlerts(ctx, watchlistID, limit)
+	if err != nil {
+		log.Errorf("failed to get watchlist alerts: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	return lambdaresponses.Respond200(apischema.GetWatchlistAlertsResponse(alerts))
+}
+
+func (s *Service) GetShareOfVoice(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	req := &apischema.GetShareOfVoiceRequest{}
+
+	err := json.Unmarshal([]byte(request.Body), req)
+	if err != nil || len(req.QueryJobIDs) == 0 || req.Limit < 0 {
+		log.Errorf("failed to Unmarshal or error query job ids")
+		return lambdaresponses.Respond400(fmt.Errorf("bad request"))
+	}
+
+	ctrCurve := serpanalysis.DefaultCTRCurve
+	if len(req.CTRCurve) > 0 {
+		for _, ctr := range req.CTRCurve {
+			if ctr < 0 || ctr > 1 {
+				return lambdaresponses.Respond400(fmt.Errorf("ctr_curve values must be between 0 and 1"))
+			}
+		}
+		ctrCurve = req.CTRCurve
+	}
+
+	for keyword, volume := range req.SearchVolumes {
+		if volume < 0 {
+			return lambdaresponses.Respond400(fmt.Errorf("invalid search volume for keyword: %s", keyword))
+		}
+	}
+
+	if req.Limit == 0 {
+		req.Limit = defaultShareOfVoiceLimit
+	}
+
+	var queryJobIDs []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+	for _, id := range req.QueryJobIDs {
+		queryJobID, err := uuid.FromString(id)
+		if err != nil {
+			return lambdaresponses.Respond400(fmt.Errorf("invalid query job id: %s", id))
+		}
+
+		if !seen[queryJobID] {
+			seen[queryJobID] = true
+			queryJobIDs = append(queryJobIDs, queryJobID)
+		}
+	}
+
+	err = s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	queryJobs, err := s.dbrepository.GetQueryJobsByIDs(ctx, queryJobIDs)
+	if err != nil {
+		log.Errorf("failed to get query jobs: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	if len(*queryJobs) != len(queryJobIDs) {
+		return lambdaresponses.Respond404(fmt.Errorf("some query jobs were not found"))
+	}
+
+	for _, queryJob := range *queryJobs {
+		if queryJob.CompletedAt == nil {
+			return lambdaresponses.Respond400(fmt.Errorf("query job %s is not completed yet", queryJob.ID))
+		}
+	}
+
+	rankings, err := s.dbrepository.GetLocationRankingsForQueryJobs(ctx, queryJobIDs)
+	if err != nil {
+		log.Errorf("failed to get location rankings: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	// Successive query jobs of a keyword are ordered by when they completed
+	completedQueryJobs := *queryJobs
+	sort.SliceStable(completedQueryJobs, func(i, j int) bool {
+		return completedQueryJobs[i].CompletedAt.Before(*completedQueryJobs[j].CompletedAt)
+	})
+
+	sov := serpanalysis.ShareOfVoice(completedQueryJobs, *rankings, ctrCurve, req.SearchVolumes, req.Limit)
+
+	return lambdaresponses.Respond200(apischema.GetShareOfVoiceResponse(sov))
+}
+
+func (s *Service) CreateWebhookEndpoint(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	req := &apischema.CreateWebhookEndpointRequest{}
+
+	err := json.Unmarshal([]byte(request.Body), req)
+	if err != nil || req.URL == "" {
+		log.Errorf("failed to Unmarshal or error url")
+		return lambdaresponses.Respond400(fmt.Errorf("bad request"))
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return lambdaresponses.Respond400(fmt.Errorf("invalid url"))
+	}
+
+	eventTypes := []string{}
+	for _, eventType := range req.EventTypes {
+		if !webhookEventTypes[eventType] {
+			return lambdaresponses.Respond400(fmt.Errorf("invalid event type: %s", eventType))
+		}
+		eventTypes = append(eventTypes, eventType)
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		log.Errorf("failed to generate webhook secret: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	err = s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	webhookEndpointID, err := s.dbrepository.CreateWebhookEndpoint(ctx, req.URL, secret, eventTypes)
+	if err != nil {
+		log.Errorf("failed to create webhook endpoint: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	// The secret is only ever returned here, it's needed to verify the signature of the deliveries
+	return lambdaresponses.Respond200(apischema.CreateWebhookEndpointResponse{
+		WebhookEndpointID: webhookEndpointID.String(),
+		Secret:            secret,
+	})
+}
+
+func (s *Service) GetWebhookEndpoints(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	webhookEndpoints, err := s.dbrepository.GetWebhookEndpoints(ctx)
+	if err != nil {
+		log.Errorf("failed to get webhook endpoints: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	return lambdaresponses.Respond200(apischema.GetWebhookEndpointsResponse(webhookEndpoints))
+}
+
+func (s *Service) DeleteWebhookEndpoint(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	id, found := request.PathParameters["id"]
+	if !found {
+		log.Fatalf("failed to get id path parameter")
+	}
+
+	webhookEndpointID := uuid.FromStringOrNil(id)
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	err = s.dbrepository.DeleteWebhookEndpoint(ctx, webhookEndpointID)
+	if err != nil {
+		log.Errorf("error deleting webhook endpoint: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	return lambdaresponses.Respond200(apischema.DeleteWebhookEndpointResponse{Message: "deleted"})
+}
+
+func (s *Service) GetWebhookDeliveries(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	id, found := request.PathParameters["id"]
+	if !found {
+		log.Fatalf("failed to get id path parameter")
+	}
+
+	webhookEndpointID := uuid.FromStringOrNil(id)
+
+	limit := defaultWebhookDeliveriesLimit
+	if v, found := request.QueryStringParameters["limit"]; found {
+		l, err := strconv.Atoi(v)
+		if err != nil || l <= 0 || l > maxWebhookDeliveriesLimit {
+			return lambdaresponses.Respond400(fmt.Errorf("limit must be between 1 and %d", maxWebhookDeliveriesLimit))
+		}
+		limit = l
+	}
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	deliveries, err := s.dbrepository.GetWebhookDeliveries(ctx, webhookEndpointID, limit)
+	if err != nil {
+		log.Errorf("failed to get webhook deliveries: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	return lambdaresponses.Respond200(apischema.GetWebhookDeliveriesResponse(deliveries))
+}
+
+// TestWebhookEndpoint queues a ping event for the webhook endpoint, its outcome shows up in the deliveries
+func (s *Service) TestWebhookEndpoint(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	if s.snsClient == nil {
+		log.Errorf("snsClient not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	id, found := request.PathParameters["id"]
+	if !found {
+		log.Fatalf("failed to get id path parameter")
+	}
+
+	webhookEndpointID := uuid.FromStringOrNil(id)
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	webhookEndpoint, err := s.dbrepository.GetWebhookEndpoint(ctx, webhookEndpointID)
+	if err != nil {
+		log.Errorf("failed to get webhook endpoint: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	if webhookEndpoint == nil {
+		return lambdaresponses.Respond404(fmt.Errorf("webhook endpoint not found"))
+	}
+
+	msg := eventschema.WebhookEventMessage{
+		EventType:         types.WebhookEventPing,
+		WebhookEndpointID: webhookEndpoint.ID.String(),
+		OccurredAt:        time.Now(),
+	}
+
+	err = s.snsClient.Publish(ctx, eventschema.WebhookEvent, msg)
+	if err != nil {
+		log.Errorf("failed to publish SNS: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	return lambdaresponses.Respond200(apischema.TestWebhookEndpointResponse{Message: "ping queued"})
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// SearchContent finds the crawled pages of all query jobs mentioning the phrase, with highlighted snippets
+func (s *Service) SearchContent(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	phrase := strings.TrimSpace(request.QueryStringParameters["q"])
+	if phrase == "" || len(phrase) > maxSearchPhraseLength {
+		return lambdaresponses.Respond400(fmt.Errorf("q must be between 1 and %d characters", maxSearchPhraseLength))
+	}
+
+	limit := defaultSearchContentLimit
+	if v, found := request.QueryStringParameters["limit"]; found {
+		l, err := strconv.Atoi(v)
+		if err != nil || l <= 0 || l > maxSearchContentLimit {
+			return lambdaresponses.Respond400(fmt.Errorf("limit must be between 1 and %d", maxSearchContentLimit))
+		}
+		limit = l
+	}
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	results, err := s.dbrepository.SearchQueryItems(ctx, phrase, limit)
+	if err != nil {
+		log.Errorf("failed to search content: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	return lambdaresponses.Respond200(apischema.SearchContentResponse(results))
+}
+
+// GetQueryJobCrawlHealth summarises how the urls of the query job were crawled: status codes, failure
+// categories, response times and the slowest urls
+func (s *Service) GetQueryJobCrawlHealth(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	id, found := request.PathParameters["id"]
+	if !found {
+		log.Fatalf("failed to get id path parameter")
+	}
+
+	queryJobID := uuid.FromStringOrNil(id)
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	queryJobs, err := s.dbrepository.GetQueryJobsByIDs(ctx, []uuid.UUID{queryJobID})
+	if err != nil {
+		log.Errorf("failed to get query job: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	if len(*queryJobs) == 0 {
+		return lambdaresponses.Respond404(fmt.Errorf("query job not found"))
+	}
+
+	statuses := []types.ExportURLStatus{}
+	err = s.dbrepository.StreamQueryJobURLStatuses(ctx, queryJobID, func(status types.ExportURLStatus) error {
+		statuses = append(statuses, status)
+		return nil
+	})
+	if err != nil {
+		log.Errorf("failed to get query job url statuses: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	diagnostics, err := s.dbrepository.GetFetchDiagnostics(ctx, queryJobID)
+	if err != nil {
+		log.Errorf("failed to get fetch diagnostics: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	health := crawlhealth.Summarize(queryJobID, statuses, *diagnostics)
+
+	return lambdaresponses.Respond200(apischema.GetQueryJobCrawlHealthResponse(health))
+}
+
+// GetQueryJobContentMetrics returns the distribution of the content metrics of the pages crawled by the query job,
+// along with the ranges recommended to compete with its top ranking pages
+func (s *Service) GetQueryJobContentMetrics(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	id, found := request.PathParameters["id"]
+	if !found {
+		log.Fatalf("failed to get id path parameter")
+	}
+
+	queryJobID := uuid.FromStringOrNil(id)
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	queryJobs, err := s.dbrepository.GetQueryJobsByIDs(ctx, []uuid.UUID{queryJobID})
+	if err != nil {
+		log.Errorf("failed to get query job: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	if len(*queryJobs) == 0 {
+		return lambdaresponses.Respond404(fmt.Errorf("query job not found"))
+	}
+
+	metrics, err := s.dbrepository.GetQueryJobContentMetrics(ctx, queryJobID)
+	if err != nil {
+		log.Errorf("failed to get query job content metrics: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	summary := contentmetrics.Summarize(queryJobID, *metrics)
+
+	return lambdaresponses.Respond200(apischema.GetQueryJobContentMetricsResponse(summary))
+}
+
+// GetQueryJobKeywordUsage summarises how the pages ranking for the keyword of the query job use it, comparing the
+// average position of the pages using it in their title, h1, url, description or introduction with the others
+func (s *Service) GetQueryJobKeywordUsage(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	id, found := request.PathParameters["id"]
+	if !found {
+		log.Fatalf("failed to get id path parameter")
+	}
+
+	queryJobID := uuid.FromStringOrNil(id)
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	queryJobs, err := s.dbrepository.GetQueryJobsByIDs(ctx, []uuid.UUID{queryJobID})
+	if err != nil {
+		log.Errorf("failed to get query job: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	if len(*queryJobs) == 0 {
+		return lambdaresponses.Respond404(fmt.Errorf("query job not found"))
+	}
+
+	usages, err := s.dbrepository.GetQueryJobKeywordUsage(ctx, queryJobID)
+	if err != nil {
+		log.Errorf("failed to get query job keyword usage: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	summary := keywordusage.Summarize(queryJobID, (*queryJobs)[0].Keyword, *usages)
+
+	return lambdaresponses.Respond200(apischema.GetQueryJobKeywordUsageResponse(summary))
+}
+
+// RecrawlQueryJob scrapes the given urls of a query job again bypassing the page cache, or all its errored
+// urls when none are given
+func (s *Service) RecrawlQueryJob(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.dbrepository == nil {
+		log.Errorf("dbrepository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	id, found := request.PathParameters["id"]
+	if !found {
+		log.Fatalf("failed to get id path parameter")
+	}
+
+	queryJobID := uuid.FromStringOrNil(id)
+
+	req := &apischema.RecrawlQueryJobRequest{}
+
+	if request.Body != "" {
+		err := json.Unmarshal([]byte(request.Body), req)
+		if err != nil {
+			log.Errorf("failed to Unmarshal recrawl request")
+			return lambdaresponses.Respond400(fmt.Errorf("bad request"))
+		}
+	}
+
+	err := s.dbrepository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.dbrepository.Close()
+
+	queryJobs, err := s.dbrepository.GetQueryJobsByIDs(ctx, []uuid.UUID{queryJobID})
+	if err != nil {
+		log.Errorf("failed to get query job: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	if len(*queryJobs) == 0 {
+		return lambdaresponses.Respond404(fmt.Errorf("query job not found"))
+	}
+
+	urls, err := s.dbrepository.ReopenQueryJobURLs(ctx, queryJobID, req.URLs)
+	if err != nil {
+		log.Errorf("failed to reopen query job urls: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	res := apischema.RecrawlQueryJobResponse{
+		QueryJobID: queryJobID.String(),
+		URLs:       urls,
+	}
+
+	return lambdaresponses.Respond200(res)
+}
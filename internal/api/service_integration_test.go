@@ -35,6 +35,7 @@ func Test_CreateQueryJob(t *testing.T) {
 		expectedResponseStatusCode int
 		dbrepository               *dbrepository.Repository
 		snsClient                  api.SNSClient
+		zenserpMonthlyBudget       int
 	}{
 		{
 			name:                       "returns 500 when dbrepository is nil",
@@ -75,6 +76,26 @@ func Test_CreateQueryJob(t *testing.T) {
 			},
 			expectedResponseStatusCode: 200,
 		},
+		{
+			name:                 "returns 429 when the monthly zenserp budget would be exceeded",
+			dbrepository:         dbRepository,
+			snsClient:            &mockSnsClient{},
+			zenserpMonthlyBudget: 3,
+			request: events.APIGatewayProxyRequest{
+				Body: `{"keyword": "hello world"}`,
+			},
+			expectedResponseStatusCode: 429,
+		},
+		{
+			name:                 "returns 200 and creates a query job for a user within budget",
+			dbrepository:         dbRepository,
+			snsClient:            &mockSnsClient{},
+			zenserpMonthlyBudget: 100,
+			request: events.APIGatewayProxyRequest{
+				Body: `{"keyword": "hello world", "user_id": "jane"}`,
+			},
+			expectedResponseStatusCode: 200,
+		},
 	}
 
 	for _, tt := range tests {
@@ -82,7 +103,7 @@ func Test_CreateQueryJob(t *testing.T) {
 			testRepo.CleanDB()
 
 			ctx := context.Background()
-			service := api.NewService(tt.dbrepository, tt.snsClient, nil)
+			service := api.NewService(tt.dbrepository, tt.snsClient, nil, tt.zenserpMonthlyBudget, 0)
 			resp, _ := service.CreateQueryJob(ctx, tt.request)
 			require.Equal(t, tt.expectedResponseStatusCode, resp.StatusCode)
 
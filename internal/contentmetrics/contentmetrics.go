@@ -0,0 +1,228 @@
+package contentmetrics
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/serpanalysis"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/jponc/competitive-analysis/pkg/webscraper"
+)
+
+const (
+	// topPosition is the worst position of the pages the recommended ranges are computed from
+	topPosition = 10
+	// minTopPages is the number of top pages needed to compute the recommended ranges from, otherwise every
+	// page is used
+	minTopPages = 3
+)
+
+// sentenceEndRegexp matches the punctuation ending a sentence, along with the whitespace following it
+var sentenceEndRegexp = regexp.MustCompile(`[.!?]+(\s+|$)`)
+
+// metrics lists the summarized metrics, in the order they're returned
+var metrics = []struct {
+	name  string
+	value func(m types.ContentMetrics) float64
+}{
+	{"word_count", func(m types.ContentMetrics) float64 { return float64(m.WordCount) }},
+	{"paragraph_count", func(m types.ContentMetrics) float64 { return float64(m.ParagraphCount) }},
+	{"avg_sentence_length", func(m types.ContentMetrics) float64 { return m.AvgSentenceLength }},
+	{"flesch_reading_ease", func(m types.ContentMetrics) float64 { return m.FleschReadingEase }},
+	{"heading_count", func(m types.ContentMetrics) float64 { return float64(m.HeadingCount) }},
+	{"image_count", func(m types.ContentMetrics) float64 { return float64(m.ImageCount) }},
+	{"internal_link_count", func(m types.ContentMetrics) float64 { return float64(m.InternalLinkCount) }},
+	{"external_link_count", func(m types.ContentMetrics) float64 { return float64(m.ExternalLinkCount) }},
+	{"list_count", func(m types.ContentMetrics) float64 { return float64(m.ListCount) }},
+	{"table_count", func(m types.ContentMetrics) float64 { return float64(m.TableCount) }},
+}
+
+// Compute returns the content metrics of the page scraped from the url. The text and structure metrics cover
+// the main content of the page while the link counts cover all of its links, navigation included.
+func Compute(pageURL string, res *webscraper.ScrapeResult) types.ContentMetrics {
+	wordCount, sentenceCount, syllableCount := 0, 0, 0
+
+	for _, section := range res.MainSections {
+		for _, sentence := range sentenceEndRegexp.Split(section, -1) {
+			sentenceWords := words(sentence)
+			if len(sentenceWords) == 0 {
+				continue
+			}
+
+			sentenceCount++
+			wordCount += len(sentenceWords)
+			for _, word := range sentenceWords {
+				syllableCount += syllables(word)
+			}
+		}
+	}
+
+	internalLinks, externalLinks := countLinks(pageURL, res.Links)
+
+	contentMetrics := types.ContentMetrics{
+		WordCount:         wordCount,
+		ParagraphCount:    res.Structure.Paragraphs,
+		HeadingCount:      res.Structure.Headings,
+		ImageCount:        res.Structure.Images,
+		InternalLinkCount: internalLinks,
+		ExternalLinkCount: externalLinks,
+		ListCount:         res.Structure.Lists,
+		TableCount:        res.Structure.Tables,
+	}
+
+	if sentenceCount > 0 {
+		wordsPerSentence := float64(wordCount) / float64(sentenceCount)
+		syllablesPerWord := float64(syllableCount) / float64(wordCount)
+
+		contentMetrics.AvgSentenceLength = round(wordsPerSentence)
+		contentMetrics.FleschReadingEase = round(206.835 - 1.015*wordsPerSentence - 84.6*syllablesPerWord)
+	}
+
+	return contentMetrics
+}
+
+// Summarize returns the distribution of every metric over the pages crawled by a query job. The recommended
+// range of a metric is the interquartile range of the pages ranking in the top 10, or of every page when too
+// few of them do.
+func Summarize(queryJobID uuid.UUID, pages []types.URLContentMetrics) *types.ContentMetricsSummary {
+	summary := &types.ContentMetricsSummary{
+		QueryJobID: queryJobID,
+		PageCount:  len(pages),
+		Metrics:    []types.ContentMetricDistribution{},
+	}
+
+	if len(pages) == 0 {
+		return summary
+	}
+
+	topPages := []types.URLContentMetrics{}
+	for _, page := range pages {
+		if page.BestPosition <= topPosition {
+			topPages = append(topPages, page)
+		}
+	}
+
+	if len(topPages) < minTopPages {
+		topPages = pages
+	}
+
+	summary.TopPageCount = len(topPages)
+
+	for _, metric := range metrics {
+		values := sortedValues(pages, metric.value)
+		topValues := sortedValues(topPages, metric.value)
+
+		summary.Metrics = append(summary.Metrics, types.ContentMetricDistribution{
+			Metric:         metric.name,
+			Min:            values[0],
+			P25:            percentile(values, 0.25),
+			Median:         percentile(values, 0.5),
+			P75:            percentile(values, 0.75),
+			Max:            values[len(values)-1],
+			RecommendedMin: percentile(topValues, 0.25),
+			RecommendedMax: percentile(topValues, 0.75),
+		})
+	}
+
+	return summary
+}
+
+// words returns the words of the text, leaving out the tokens without letters nor digits
+func words(text string) []string {
+	result := []string{}
+	for _, token := range strings.Fields(text) {
+		if strings.IndexFunc(token, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			result = append(result, token)
+		}
+	}
+
+	return result
+}
+
+// syllables estimates the syllables of an english word from its groups of vowels, a final silent "e" isn't
+// counted
+func syllables(word string) int {
+	word = strings.ToLower(strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }))
+
+	count := 0
+	inVowelGroup := false
+	for _, r := range word {
+		isVowel := strings.ContainsRune("aeiouy", r)
+		if isVowel && !inVowelGroup {
+			count++
+		}
+		inVowelGroup = isVowel
+	}
+
+	if count > 1 && strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") {
+		count--
+	}
+
+	if count == 0 {
+		return 1
+	}
+
+	return count
+}
+
+// countLinks counts the links to the domain of the page and to other domains, links which aren't to web pages
+// like mailto: aren't counted
+func countLinks(pageURL string, links []webscraper.Link) (int, int) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return 0, 0
+	}
+
+	pageDomain := serpanalysis.Domain(pageURL)
+
+	internal, external := 0, 0
+	for _, link := range links {
+		u, err := url.Parse(strings.TrimSpace(link.LinkURL))
+		if err != nil {
+			continue
+		}
+
+		resolved := base.ResolveReference(u)
+		if resolved.Scheme != "http" && resolved.Scheme != "https" {
+			continue
+		}
+
+		if serpanalysis.Domain(resolved.String()) == pageDomain {
+			internal++
+		} else {
+			external++
+		}
+	}
+
+	return internal, external
+}
+
+func sortedValues(pages []types.URLContentMetrics, value func(m types.ContentMetrics) float64) []float64 {
+	values := make([]float64, len(pages))
+	for i, page := range pages {
+		values[i] = value(page.ContentMetrics)
+	}
+
+	sort.Float64s(values)
+
+	return values
+}
+
+// percentile returns the nearest rank percentile of the sorted values
+func percentile(values []float64, p float64) float64 {
+	rank := int(math.Ceil(p*float64(len(values)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	return values[rank]
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,121 @@
+package contentmetrics
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/jponc/competitive-analysis/pkg/webscraper"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Compute(t *testing.T) {
+	res := &webscraper.ScrapeResult{
+		MainSections: []string{
+			"Running Shoes",
+			"The cat sat on the mat. It was happy!",
+			"Why do runners love these shoes?",
+		},
+		Structure: webscraper.ContentStructure{Paragraphs: 2, Headings: 1, Images: 3, Lists: 1, Tables: 1},
+		Links: []webscraper.Link{
+			{Text: "Home", LinkURL: "/"},
+			{Text: "Guide", LinkURL: "https://www.example.com/guide"},
+			{Text: "Next", LinkURL: "page-2"},
+			{Text: "Brand", LinkURL: "https://brand.com/shoes"},
+			{Text: "Email", LinkURL: "mailto:hello@example.com"},
+		},
+	}
+
+	metrics := Compute("https://example.com/blog/shoes", res)
+
+	require.Equal(t, 17, metrics.WordCount)
+	require.Equal(t, 2, metrics.ParagraphCount)
+	require.Equal(t, 4.25, metrics.AvgSentenceLength)
+	require.Equal(t, 102.99, metrics.FleschReadingEase)
+	require.Equal(t, 1, metrics.HeadingCount)
+	require.Equal(t, 3, metrics.ImageCount)
+	require.Equal(t, 3, metrics.InternalLinkCount)
+	require.Equal(t, 1, metrics.ExternalLinkCount)
+	require.Equal(t, 1, metrics.ListCount)
+	require.Equal(t, 1, metrics.TableCount)
+}
+
+func Test_ComputeEmpty(t *testing.T) {
+	metrics := Compute("https://example.com/", &webscraper.ScrapeResult{MainSections: []string{"—"}})
+
+	require.Equal(t, 0, metrics.WordCount)
+	require.Equal(t, 0.0, metrics.AvgSentenceLength)
+	require.Equal(t, 0.0, metrics.FleschReadingEase)
+}
+
+func Test_syllables(t *testing.T) {
+	tests := map[string]int{
+		"cat":        1,
+		"the":        1,
+		"make":       1,
+		"table":      2,
+		"happy":      2,
+		"beautiful,": 3,
+		"2022":       1,
+	}
+
+	for word, expected := range tests {
+		require.Equal(t, expected, syllables(word), word)
+	}
+}
+
+func Test_Summarize(t *testing.T) {
+	queryJobID := uuid.Must(uuid.NewV4())
+
+	pages := []types.URLContentMetrics{
+		{URL: "https://a.com/", BestPosition: 1, ContentMetrics: types.ContentMetrics{WordCount: 2000, ImageCount: 8}},
+		{URL: "https://b.com/", BestPosition: 3, ContentMetrics: types.ContentMetrics{WordCount: 1500, ImageCount: 6}},
+		{URL: "https://c.com/", BestPosition: 5, ContentMetrics: types.ContentMetrics{WordCount: 1800, ImageCount: 4}},
+		{URL: "https://d.com/", BestPosition: 9, ContentMetrics: types.ContentMetrics{WordCount: 1200, ImageCount: 2}},
+		{URL: "https://e.com/", BestPosition: 15, ContentMetrics: types.ContentMetrics{WordCount: 300, ImageCount: 0}},
+		{URL: "https://f.com/", BestPosition: 22, ContentMetrics: types.ContentMetrics{WordCount: 500, ImageCount: 1}},
+	}
+
+	summary := Summarize(queryJobID, pages)
+
+	require.Equal(t, queryJobID, summary.QueryJobID)
+	require.Equal(t, 6, summary.PageCount)
+	require.Equal(t, 4, summary.TopPageCount)
+	require.Len(t, summary.Metrics, 10)
+
+	wordCount := summary.Metrics[0]
+	require.Equal(t, "word_count", wordCount.Metric)
+	require.Equal(t, 300.0, wordCount.Min)
+	require.Equal(t, 500.0, wordCount.P25)
+	require.Equal(t, 1200.0, wordCount.Median)
+	require.Equal(t, 1800.0, wordCount.P75)
+	require.Equal(t, 2000.0, wordCount.Max)
+	require.Equal(t, 1200.0, wordCount.RecommendedMin)
+	require.Equal(t, 1800.0, wordCount.RecommendedMax)
+
+	imageCount := summary.Metrics[5]
+	require.Equal(t, "image_count", imageCount.Metric)
+	require.Equal(t, 2.0, imageCount.RecommendedMin)
+	require.Equal(t, 6.0, imageCount.RecommendedMax)
+}
+
+func Test_SummarizeFewTopPages(t *testing.T) {
+	pages := []types.URLContentMetrics{
+		{URL: "https://a.com/", BestPosition: 2, ContentMetrics: types.ContentMetrics{WordCount: 1000}},
+		{URL: "https://b.com/", BestPosition: 14, ContentMetrics: types.ContentMetrics{WordCount: 400}},
+		{URL: "https://c.com/", BestPosition: 18, ContentMetrics: types.ContentMetrics{WordCount: 600}},
+	}
+
+	summary := Summarize(uuid.Must(uuid.NewV4()), pages)
+
+	require.Equal(t, 3, summary.TopPageCount)
+	require.Equal(t, 400.0, summary.Metrics[0].RecommendedMin)
+	require.Equal(t, 1000.0, summary.Metrics[0].RecommendedMax)
+}
+
+func Test_SummarizeEmpty(t *testing.T) {
+	summary := Summarize(uuid.Must(uuid.NewV4()), []types.URLContentMetrics{})
+
+	require.Equal(t, 0, summary.PageCount)
+	require.Empty(t, summary.Metrics)
+}
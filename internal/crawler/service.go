@@ -3,28 +3,57 @@ package crawler
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/gofrs/uuid"
 	"github.com/jponc/competitive-analysis/api/eventschema"
+	"github.com/jponc/competitive-analysis/internal/contentmetrics"
+	"github.com/jponc/competitive-analysis/internal/keywordusage"
+	"github.com/jponc/competitive-analysis/internal/pagechanges"
+	"github.com/jponc/competitive-analysis/internal/queryjobs"
 	"github.com/jponc/competitive-analysis/internal/repository/dbrepository"
+	"github.com/jponc/competitive-analysis/internal/types"
 	"github.com/jponc/competitive-analysis/pkg/sns"
 	"github.com/jponc/competitive-analysis/pkg/webscraper"
 
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	// maxScrapeAttempts caps the attempts to scrape a url failing with a retryable error
+	maxScrapeAttempts     = 3
+	scrapeRetryBaseDelay  = time.Minute
+	maxScrapeRetriesQueue = 500
+	robotsCacheTTL        = 24 * time.Hour
+	// maxCrawlDelay caps the crawl delay asked by robots.txt so a page can be fetched within the lambda timeout
+	maxCrawlDelay = 10 * time.Second
+	// maxDomainWait caps the time waited for a fetch slot of a domain, the fetch is retried later past it
+	maxDomainWait = time.Minute
+)
+
 type Service struct {
 	webscraperClient *webscraper.Client
 	repository       *dbrepository.Repository
 	snsClient        *sns.Client
+	pageCacheTTL     time.Duration
+	domainDelay      time.Duration
 }
 
-func NewService(webscraperClient *webscraper.Client, repository *dbrepository.Repository, snsClient *sns.Client) *Service {
+// NewService instantiates the crawler service. A pageCacheTTL of 0 disables reusing pages scraped for other
+// query jobs. Fetches of the same domain are spaced by domainDelay, or the crawl delay of its robots.txt
+// when it's longer.
+func NewService(webscraperClient *webscraper.Client, repository *dbrepository.Repository, snsClient *sns.Client, pageCacheTTL, domainDelay time.Duration) *Service {
 	s := &Service{
 		webscraperClient: webscraperClient,
 		repository:       repository,
 		snsClient:        snsClient,
+		pageCacheTTL:     pageCacheTTL,
+		domainDelay:      domainDelay,
 	}
 
 	return s
@@ -56,18 +85,32 @@ func (s *Service) WebScraperParseQueryJobURL(ctx context.Context, snsEvent event
 		log.Fatalf("unable to unarmarshal message: %v", err)
 	}
 
-	url := msg.URL
-
-	log.Infof("Processing URL: %s", url)
+	log.Infof("Processing URL: %s", msg.URL)
 
 	queryJobID, err := uuid.FromString(msg.QueryJobID)
 	if err != nil {
 		log.Fatalf("unable to convert query job id string to UUID: %v", err)
 	}
 
+	err = s.parseQueryJobURL(ctx, queryJobID, msg)
+	if err != nil {
+		log.Errorf("failed to process query items for query job (%s) with url (%s): %v", queryJobID.String(), msg.URL, err)
+		s.failQueryJob(ctx, queryJobID, err.Error())
+	}
+
+	if err := s.repository.Close(); err != nil {
+		log.Fatalf("can't close DB connection")
+	}
+}
+
+// parseQueryJobURL processes the query items of the query job with the url, and reports it's done unless
+// the scrape is retried later
+func (s *Service) parseQueryJobURL(ctx context.Context, queryJobID uuid.UUID, msg eventschema.ParseQueryJobURLMessage) error {
+	url := msg.URL
+
 	queryItems, err := s.repository.GetQueryItemsFromUrl(ctx, queryJobID, url)
 	if err != nil {
-		log.Fatalf("unable to get query item id's: %v", err)
+		return fmt.Errorf("unable to get query item id's: %w", err)
 	}
 
 	var queryItemIDs []uuid.UUID
@@ -75,30 +118,19 @@ func (s *Service) WebScraperParseQueryJobURL(ctx context.Context, snsEvent event
 		queryItemIDs = append(queryItemIDs, queryItem.ID)
 	}
 
-	// Run scraping
-	res, err := s.webscraperClient.Scrape(ctx, url)
-
-	if err == nil {
-		// Create links
-		for _, queryItemID := range queryItemIDs {
-			for _, link := range res.Links {
-				err = s.repository.CreateQueryLink(ctx, queryItemID, link.Text, link.LinkURL)
-				if err != nil {
-					log.Infof("unable to create link: %v", err)
-				}
-			}
-		}
-
-		// Store Body
-		err = s.repository.SetQueryItemsProcessedWithBodyAndTitle(ctx, queryJobID, queryItemIDs, res.Body, res.Title)
-	} else {
-		// don't panic if there's a URL that can't be processed , just continue
-		log.Errorf("unable to request cleaned HTML with URL (%s) from webscraper: %v", url, err)
-		err = s.repository.SetQueryItemsErrorProcessing(ctx, queryJobID, url)
+	attempt := msg.Attempt
+	if attempt < 1 {
+		attempt = 1
 	}
 
+	retrying, err := s.processURL(ctx, queryJobID, url, queryItemIDs, attempt, msg.SkipPageCache)
 	if err != nil {
-		log.Fatalf("failed to process query items for query job (%s) with url (%s): %v", queryJobID.String(), url, err)
+		return fmt.Errorf("failed to process url (%s): %w", url, err)
+	}
+
+	// the query items stay unprocessed until the retry
+	if retrying {
+		return nil
 	}
 
 	// Publish DoneProcessingQueryJobURL message
@@ -109,15 +141,13 @@ func (s *Service) WebScraperParseQueryJobURL(ctx context.Context, snsEvent event
 
 	err = s.snsClient.Publish(ctx, eventschema.DoneProcessingQueryJobURL, doneMsg)
 	if err != nil {
-		log.Fatalf("failed to publish SNS: %v", err)
+		return fmt.Errorf("failed to publish SNS: %w", err)
 	}
 
 	// Send request to textrazor to extract the content
 	log.Infof("Done processing (%s), url: (%s)", queryJobID.String(), url)
 
-	if err := s.repository.Close(); err != nil {
-		log.Fatalf("can't close DB connection")
-	}
+	return nil
 }
 
 func (s *Service) CheckCompletedQueryJobs(ctx context.Context, snsEvent events.SNSEvent) {
@@ -125,6 +155,10 @@ func (s *Service) CheckCompletedQueryJobs(ctx context.Context, snsEvent events.S
 		log.Fatalf("repository not defined")
 	}
 
+	if s.snsClient == nil {
+		log.Fatalf("snsClient not defined")
+	}
+
 	if err := s.repository.Connect(); err != nil {
 		log.Fatalf("can't connect to DB")
 	}
@@ -143,26 +177,457 @@ func (s *Service) CheckCompletedQueryJobs(ctx context.Context, snsEvent events.S
 		log.Fatalf("unable to convert query job id string to UUID: %v", err)
 	}
 
+	err = s.checkCompletedQueryJob(ctx, queryJobID)
+	if err != nil {
+		log.Errorf("failed to check completion of query job (%s): %v", queryJobID.String(), err)
+		s.failQueryJob(ctx, queryJobID, err.Error())
+	}
+
+	if err := s.repository.Close(); err != nil {
+		log.Fatalf("can't close DB connection")
+	}
+}
+
+// checkCompletedQueryJob marks the query job as complete once all of its query items are processed
+func (s *Service) checkCompletedQueryJob(ctx context.Context, queryJobID uuid.UUID) error {
 	// Get unprocessed query items count
 	unprocessedCount, err := s.repository.GetUnprocessedQueryItemsCount(ctx, queryJobID)
 	if err != nil {
-		log.Fatalf("unable to get unprocessed query items count for query job (%s): %v", queryJobID.String(), err)
+		return fmt.Errorf("unable to get unprocessed query items count: %w", err)
 	}
 
 	if unprocessedCount > 0 {
 		log.Infof("%s query job still has %d remaining unprocessed query items", queryJobID.String(), unprocessedCount)
-		return
+		return nil
 	}
 
 	// mark as complete if there are 0 unprocessed query items
-	err = s.repository.MarkQueryJobAsComplete(ctx, queryJobID)
+	marked, err := s.repository.MarkQueryJobAsComplete(ctx, queryJobID)
+	if err != nil {
+		return fmt.Errorf("query job cannot be marked as complete: %w", err)
+	}
+
+	// another message already completed this query job, or it's being recrawled which doesn't complete it again
+	if !marked {
+		recrawled, err := s.repository.MarkQueryJobRecrawlAsComplete(ctx, queryJobID)
+		if err != nil {
+			return fmt.Errorf("query job recrawl cannot be marked as complete: %w", err)
+		}
+
+		if recrawled {
+			log.Infof("Marked recrawl of query job %s as complete", queryJobID.String())
+		} else {
+			log.Infof("%s query job is already complete", queryJobID.String())
+		}
+
+		return nil
+	}
+
+	// Publish QueryJobCompleted message
+	completedMsg := eventschema.QueryJobCompletedMessage{
+		QueryJobID: queryJobID.String(),
+	}
+
+	err = s.snsClient.Publish(ctx, eventschema.QueryJobCompleted, completedMsg)
 	if err != nil {
-		log.Fatalf("%s query job cannot be marked as complete: %v", queryJobID.String(), err)
+		return fmt.Errorf("failed to publish SNS: %w", err)
 	}
 
+	queryjobs.PublishWebhookEvent(ctx, s.snsClient, types.WebhookEventQueryJobCompleted, queryJobID)
+
 	log.Infof("Marked query job %s as complete", queryJobID.String())
 
+	return nil
+}
+
+// RetryScrapes queues the scrapes of urls due to be retried, either after a transient failure or because
+// they were recrawled
+func (s *Service) RetryScrapes(ctx context.Context) {
+	if s.repository == nil {
+		log.Fatalf("repository not defined")
+	}
+
+	if s.snsClient == nil {
+		log.Fatalf("snsClient not defined")
+	}
+
+	if err := s.repository.Connect(); err != nil {
+		log.Fatalf("can't connect to DB")
+	}
+
+	retries, err := s.repository.GetDueScrapeRetries(ctx, maxScrapeRetriesQueue)
+	if err != nil {
+		log.Fatalf("unable to get due scrape retries: %v", err)
+	}
+
+	for _, retry := range *retries {
+		msg := eventschema.ParseQueryJobURLMessage{
+			QueryJobID:    retry.QueryJobID.String(),
+			URL:           retry.URL,
+			Attempt:       retry.Attempt,
+			SkipPageCache: retry.SkipPageCache,
+		}
+
+		err = s.snsClient.Publish(ctx, eventschema.ParseQueryJobURL, msg)
+		if err != nil {
+			log.Fatalf("failed to publish SNS: %v", err)
+		}
+
+		err = s.repository.DeleteScrapeRetry(ctx, retry)
+		if err != nil {
+			log.Fatalf("unable to delete scrape retry: %v", err)
+		}
+	}
+
+	log.Infof("Queued %d scrape retries", len(*retries))
+
 	if err := s.repository.Close(); err != nil {
 		log.Fatalf("can't close DB connection")
 	}
 }
+
+// processURL links the query items to the page of the url, reusing the page when it was scraped within the
+// page cache TTL. It returns whether the scrape failed and is retried later.
+func (s *Service) processURL(ctx context.Context, queryJobID uuid.UUID, url string, queryItemIDs []uuid.UUID, attempt int, skipPageCache bool) (bool, error) {
+	pageURL := webscraper.NormalizeURL(url)
+
+	if s.pageCacheTTL > 0 && !skipPageCache {
+		page, err := s.repository.GetFreshPage(ctx, pageURL, time.Now().Add(-s.pageCacheTTL))
+		if err != nil {
+			return false, err
+		}
+
+		if page != nil {
+			log.Infof("Reusing page of url (%s) scraped at %s", url, page.ScrapedAt)
+			s.recordFetch(ctx, queryJobID, url, attempt, true, webscraper.Diagnostics{})
+
+			mainContent, err := s.repository.GetPageMainContent(ctx, page.ID)
+			if err != nil {
+				log.Errorf("unable to get main content of url (%s) to record keyword usage: %v", url, err)
+			} else {
+				s.recordKeywordUsage(ctx, queryJobID, queryItemIDs, keywordusage.Page{
+					URL:         url,
+					Title:       page.Title,
+					Description: page.Description,
+					H1:          page.H1,
+					MainContent: mainContent,
+				})
+			}
+
+			return false, s.repository.SetQueryItemsProcessedWithPage(ctx, queryJobID, queryItemIDs, page.ID, page.Title)
+		}
+	}
+
+	robots, err := s.getRobots(ctx, url)
+	if err != nil {
+		// the site is unreachable, don't crawl it
+		log.Errorf("unable to get robots.txt of url (%s): %v", url, err)
+		return s.failURL(ctx, queryJobID, url, attempt, err)
+	}
+
+	if !robots.Allowed(url) {
+		log.Infof("url (%s) is disallowed by robots.txt", url)
+		s.recordFetch(ctx, queryJobID, url, attempt, false, webscraper.Diagnostics{FailureCategory: webscraper.FailureBlockedByRobots})
+
+		return false, s.repository.SetQueryItemsBlocked(ctx, queryJobID, url)
+	}
+
+	wait, err := s.waitForDomain(ctx, url, robots.CrawlDelay())
+	if err != nil {
+		return false, err
+	}
+
+	// the domain is busy for longer than this invocation should wait, fetch the url once its slot is due
+	if wait > 0 {
+		log.Infof("Deferring url (%s) by %s until its domain fetch slot", url, wait)
+		return true, s.repository.ScheduleScrapeRetry(ctx, queryJobID, url, attempt, skipPageCache, wait)
+	}
+
+	// Run scraping
+	res, err := s.webscraperClient.Scrape(ctx, url)
+	if err != nil {
+		// don't panic if there's a URL that can't be processed , just continue
+		log.Errorf("unable to request cleaned HTML with URL (%s) from webscraper: %v", url, err)
+		return s.failURL(ctx, queryJobID, url, attempt, err)
+	}
+
+	if s.webscraperClient.NeedsRendering(res) {
+		res = s.render(ctx, url, robots, res)
+	}
+
+	s.recordFetch(ctx, queryJobID, url, attempt, false, res.Diagnostics)
+
+	// Keep track of content changes between crawls
+	s.recordPageSnapshot(ctx, queryJobID, url, res)
+
+	links := make([]types.Link, len(res.Links))
+	for i, link := range res.Links {
+		links[i] = types.Link{Text: link.Text, URL: link.LinkURL}
+	}
+
+	// Store body and links
+	pageID, err := s.repository.SavePage(ctx, pageURL, res.Title, res.Description, res.Body, res.MainContent, res.H1, links)
+	if err != nil {
+		return false, err
+	}
+
+	s.recordContentMetrics(ctx, pageID, url, res)
+	s.recordKeywordUsage(ctx, queryJobID, queryItemIDs, keywordusage.Page{
+		URL:         url,
+		Title:       res.Title,
+		Description: res.Description,
+		H1:          res.H1,
+		MainContent: res.MainContent,
+	})
+
+	return false, s.repository.SetQueryItemsProcessedWithPage(ctx, queryJobID, queryItemIDs, pageID, res.Title)
+}
+
+// render returns the page of the url rendered with its scripts, or its static scrape when rendering fails or
+// doesn't get more text. The url is already allowed by robots.txt, rendering fetches it again so it waits for
+// another fetch slot of the domain.
+func (s *Service) render(ctx context.Context, url string, robots *webscraper.Robots, static *webscraper.ScrapeResult) *webscraper.ScrapeResult {
+	wait, err := s.waitForDomain(ctx, url, robots.CrawlDelay())
+	if err != nil {
+		log.Errorf("unable to wait to render url (%s), keeping its static HTML: %v", url, err)
+		return static
+	}
+
+	if wait > 0 {
+		log.Infof("Domain of url (%s) is busy for %s, keeping its static HTML", url, wait)
+		return static
+	}
+
+	rendered, err := s.webscraperClient.Render(ctx, url)
+	if err != nil {
+		// the static page is better than nothing
+		log.Errorf("unable to render url (%s), keeping its static HTML: %v", url, err)
+		return static
+	}
+
+	if len(rendered.Body) <= len(static.Body) {
+		return static
+	}
+
+	return rendered
+}
+
+// failURL schedules another attempt to scrape the url when the failure is transient and attempts remain,
+// otherwise the query items of the url are marked as errored. It returns whether the url is retried.
+func (s *Service) failURL(ctx context.Context, queryJobID uuid.UUID, url string, attempt int, err error) (bool, error) {
+	diagnostics := failureDiagnostics(err)
+	s.recordFetch(ctx, queryJobID, url, attempt, false, diagnostics)
+
+	if !isRetryable(diagnostics.FailureCategory) || attempt >= maxScrapeAttempts {
+		return false, s.repository.SetQueryItemsErrorProcessing(ctx, queryJobID, url)
+	}
+
+	delay := scrapeRetryBaseDelay << (attempt - 1)
+	log.Infof("Retrying url (%s) in %s after attempt %d of %d failed with %s", url, delay, attempt, maxScrapeAttempts, diagnostics.FailureCategory)
+
+	return true, s.repository.ScheduleScrapeRetry(ctx, queryJobID, url, attempt+1, false, delay)
+}
+
+// isRetryable returns whether a fetch failing with the category may succeed later
+func isRetryable(failureCategory string) bool {
+	switch failureCategory {
+	case webscraper.FailureTimeout, webscraper.FailureServerError, webscraper.FailureRateLimited, webscraper.FailureRobotsUnreachable:
+		return true
+	default:
+		return false
+	}
+}
+
+// getRobots returns the robots.txt rules of the site of the url, fetched within the robots cache TTL. Failing
+// to use the cache doesn't fail the crawl.
+func (s *Service) getRobots(ctx context.Context, url string) (*webscraper.Robots, error) {
+	origin := webscraper.Origin(url)
+
+	content, err := s.repository.GetRobotsTxt(ctx, origin, time.Now().Add(-robotsCacheTTL))
+	if err != nil {
+		log.Errorf("unable to get cached robots.txt of (%s): %v", origin, err)
+	}
+
+	if content == nil {
+		c, err := s.webscraperClient.FetchRobots(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.repository.SaveRobotsTxt(ctx, origin, c)
+		if err != nil {
+			log.Errorf("unable to cache robots.txt of (%s): %v", origin, err)
+		}
+
+		content = &c
+	}
+
+	return webscraper.ParseRobots(*content, s.webscraperClient.UserAgent()), nil
+}
+
+// waitForDomain waits for the next fetch slot of the domain of the url, which is shared by every concurrent
+// crawler through the database. It returns the wait left when the slot is more than maxDomainWait away.
+func (s *Service) waitForDomain(ctx context.Context, rawURL string, crawlDelay time.Duration) (time.Duration, error) {
+	delay := s.domainDelay
+	if crawlDelay > delay {
+		delay = crawlDelay
+	}
+	if delay > maxCrawlDelay {
+		delay = maxCrawlDelay
+	}
+
+	if delay <= 0 {
+		return 0, nil
+	}
+
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return 0, nil
+	}
+
+	wait, err := s.repository.ReserveDomainFetch(ctx, strings.ToLower(u.Hostname()), delay)
+	if err != nil {
+		return 0, err
+	}
+
+	return sleepForDomain(ctx, rawURL, wait)
+}
+
+// sleepForDomain sleeps for the wait of a domain fetch slot, unless it's longer than maxDomainWait in which
+// case it returns the wait right away
+func sleepForDomain(ctx context.Context, rawURL string, wait time.Duration) (time.Duration, error) {
+	if wait > maxDomainWait {
+		return wait, nil
+	}
+
+	if wait > 0 {
+		log.Infof("Waiting %s before fetching url (%s)", wait, rawURL)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	return 0, nil
+}
+
+// recordFetch stores the diagnostics of the fetch of the url, or that its page was reused from the page cache.
+// Failing to do so doesn't fail the crawl.
+func (s *Service) recordFetch(ctx context.Context, queryJobID uuid.UUID, url string, attempt int, cached bool, diagnostics webscraper.Diagnostics) {
+	diagnostic := types.FetchDiagnostic{
+		QueryJobID:     queryJobID,
+		URL:            url,
+		Attempt:        attempt,
+		Cached:         cached,
+		FinalURL:       diagnostics.FinalURL,
+		Redirects:      diagnostics.Redirects,
+		ResponseTimeMs: int(diagnostics.ResponseTime.Milliseconds()),
+		ContentType:    diagnostics.ContentType,
+		Rendered:       diagnostics.Rendered,
+	}
+
+	if diagnostics.StatusCode != 0 {
+		diagnostic.StatusCode = &diagnostics.StatusCode
+
+		// the length is unknown when the body wasn't read and the server didn't send it
+		if diagnostics.ContentLength >= 0 {
+			diagnostic.ContentLength = &diagnostics.ContentLength
+		}
+	}
+
+	if diagnostics.Error != "" {
+		diagnostic.Error = &diagnostics.Error
+	}
+
+	if diagnostics.FailureCategory != "" {
+		diagnostic.FailureCategory = &diagnostics.FailureCategory
+	}
+
+	if diagnostic.Redirects == nil {
+		diagnostic.Redirects = []string{}
+	}
+
+	err := s.repository.SaveFetchDiagnostic(ctx, diagnostic)
+	if err != nil {
+		log.Errorf("unable to record fetch diagnostic of url (%s): %v", url, err)
+	}
+}
+
+// recordContentMetrics stores the content metrics of the scraped page. Failing to do so doesn't fail the crawl.
+func (s *Service) recordContentMetrics(ctx context.Context, pageID uuid.UUID, url string, res *webscraper.ScrapeResult) {
+	// relative links are relative to the page the redirects led to
+	pageURL := res.Diagnostics.FinalURL
+	if pageURL == "" {
+		pageURL = url
+	}
+
+	metrics := contentmetrics.Compute(pageURL, res)
+	metrics.PageID = pageID
+
+	err := s.repository.SaveContentMetrics(ctx, metrics)
+	if err != nil {
+		log.Errorf("unable to record content metrics of url (%s): %v", url, err)
+	}
+}
+
+// recordKeywordUsage stores how the page of the query items uses the keyword of the query job. Failing to do so
+// doesn't fail the crawl.
+func (s *Service) recordKeywordUsage(ctx context.Context, queryJobID uuid.UUID, queryItemIDs []uuid.UUID, page keywordusage.Page) {
+	queryJob, err := s.repository.GetQueryJob(ctx, queryJobID)
+	if err != nil {
+		log.Errorf("unable to get query job (%s) to record keyword usage: %v", queryJobID, err)
+		return
+	}
+
+	usage := keywordusage.Analyze(queryJob.Keyword, page)
+
+	err = s.repository.SaveKeywordUsage(ctx, queryItemIDs, usage)
+	if err != nil {
+		log.Errorf("unable to record keyword usage of url (%s): %v", page.URL, err)
+	}
+}
+
+// failureDiagnostics returns the diagnostics of a failed fetch
+func failureDiagnostics(err error) webscraper.Diagnostics {
+	var scrapeErr *webscraper.ScrapeError
+	if errors.As(err, &scrapeErr) {
+		return scrapeErr.Diagnostics
+	}
+
+	return webscraper.Diagnostics{
+		Error:           err.Error(),
+		FailureCategory: webscraper.FailureUnknown,
+	}
+}
+
+// recordPageSnapshot stores a new snapshot of the page when its content changed since the last crawl.
+// Failing to do so doesn't fail the crawl.
+func (s *Service) recordPageSnapshot(ctx context.Context, queryJobID uuid.UUID, url string, res *webscraper.ScrapeResult) {
+	content, contentHash := pagechanges.Normalize(res.MainSections)
+
+	latest, err := s.repository.GetLatestPageSnapshot(ctx, url)
+	if err != nil {
+		log.Errorf("unable to get latest page snapshot of url (%s): %v", url, err)
+		return
+	}
+
+	if latest != nil && latest.ContentHash == contentHash && latest.Title == res.Title {
+		err = s.repository.TouchPageSnapshot(ctx, latest.ID)
+	} else {
+		err = s.repository.CreatePageSnapshot(ctx, queryJobID, url, contentHash, res.Title, content, pagechanges.WordCount(content))
+	}
+
+	if err != nil {
+		log.Errorf("unable to record page snapshot of url (%s): %v", url, err)
+	}
+}
+
+// failQueryJob marks the query job as failed and notifies the webhook endpoints, unless it already completed or failed
+func (s *Service) failQueryJob(ctx context.Context, queryJobID uuid.UUID, reason string) {
+	err := queryjobs.Fail(ctx, s.repository, s.snsClient, queryJobID, reason)
+	if err != nil {
+		log.Fatalf("unable to fail query job: %v", err)
+	}
+}
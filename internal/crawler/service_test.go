@@ -0,0 +1,41 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SleepForDomainDefersLongWaits(t *testing.T) {
+	wait := maxDomainWait + time.Second
+
+	start := time.Now()
+	left, err := sleepForDomain(context.Background(), "https://example.com/page", wait)
+
+	require.NoError(t, err)
+	require.Equal(t, wait, left)
+	require.True(t, time.Since(start) < time.Second)
+}
+
+func Test_SleepForDomainSleepsShortWaits(t *testing.T) {
+	wait := 20 * time.Millisecond
+
+	start := time.Now()
+	left, err := sleepForDomain(context.Background(), "https://example.com/page", wait)
+
+	require.NoError(t, err)
+	require.Zero(t, left)
+	require.True(t, time.Since(start) >= wait)
+}
+
+func Test_SleepForDomainStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	left, err := sleepForDomain(ctx, "https://example.com/page", maxDomainWait)
+
+	require.Equal(t, context.Canceled, err)
+	require.Zero(t, left)
+}
@@ -0,0 +1,135 @@
+package crawlhealth
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/types"
+)
+
+// slowestURLsCount is the number of slowest urls returned in a summary
+const slowestURLsCount = 5
+
+// Summarize returns the crawl health of a query job from the crawl status of its urls and the diagnostics of
+// their last fetch. Response times only cover the urls that were fetched rather than reused from the page cache.
+func Summarize(queryJobID uuid.UUID, statuses []types.ExportURLStatus, diagnostics []types.FetchDiagnostic) *types.CrawlHealth {
+	health := &types.CrawlHealth{
+		QueryJobID:        queryJobID,
+		URLCount:          len(statuses),
+		StatusCodes:       []types.CrawlHealthCount{},
+		FailureCategories: []types.CrawlHealthCount{},
+		ContentTypes:      []types.CrawlHealthCount{},
+		SlowestURLs:       []types.CrawlHealthURL{},
+	}
+
+	for _, status := range statuses {
+		switch status.CrawlStatus {
+		case "processed":
+			health.Processed++
+		case "error":
+			health.Errored++
+		case "blocked":
+			health.Blocked++
+		default:
+			health.Pending++
+		}
+	}
+
+	if done := health.Processed + health.Errored + health.Blocked; done > 0 {
+		health.SuccessRate = math.Round(float64(health.Processed)/float64(done)*10000) / 10000
+	}
+
+	statusCodes := map[string]int{}
+	failureCategories := map[string]int{}
+	contentTypes := map[string]int{}
+	fetched := []types.CrawlHealthURL{}
+
+	for _, d := range diagnostics {
+		if d.Cached {
+			health.Cached++
+			continue
+		}
+
+		if len(d.Redirects) > 0 {
+			health.Redirected++
+		}
+
+		if d.Rendered {
+			health.Rendered++
+		}
+
+		if d.StatusCode != nil {
+			statusCodes[strconv.Itoa(*d.StatusCode)]++
+		}
+
+		if d.FailureCategory != nil {
+			failureCategories[*d.FailureCategory]++
+		}
+
+		if mediaType := strings.ToLower(strings.TrimSpace(strings.Split(d.ContentType, ";")[0])); mediaType != "" {
+			contentTypes[mediaType]++
+		}
+
+		if d.ResponseTimeMs > 0 {
+			fetched = append(fetched, types.CrawlHealthURL{URL: d.URL, ResponseTimeMs: d.ResponseTimeMs})
+		}
+	}
+
+	health.StatusCodes = sortedCounts(statusCodes)
+	health.FailureCategories = sortedCounts(failureCategories)
+	health.ContentTypes = sortedCounts(contentTypes)
+
+	if len(fetched) > 0 {
+		sort.SliceStable(fetched, func(i, j int) bool {
+			if fetched[i].ResponseTimeMs != fetched[j].ResponseTimeMs {
+				return fetched[i].ResponseTimeMs > fetched[j].ResponseTimeMs
+			}
+			return fetched[i].URL < fetched[j].URL
+		})
+
+		total := 0
+		for _, f := range fetched {
+			total += f.ResponseTimeMs
+		}
+
+		health.AvgResponseTimeMs = total / len(fetched)
+		health.MedianResponseTimeMs = percentile(fetched, 0.5)
+		health.P95ResponseTimeMs = percentile(fetched, 0.95)
+
+		if len(fetched) > slowestURLsCount {
+			fetched = fetched[:slowestURLsCount]
+		}
+		health.SlowestURLs = fetched
+	}
+
+	return health
+}
+
+// percentile returns the nearest rank percentile of the response times sorted slowest first
+func percentile(fetched []types.CrawlHealthURL, p float64) int {
+	rank := int(math.Ceil(p*float64(len(fetched)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	return fetched[len(fetched)-1-rank].ResponseTimeMs
+}
+
+func sortedCounts(counts map[string]int) []types.CrawlHealthCount {
+	sorted := []types.CrawlHealthCount{}
+	for value, count := range counts {
+		sorted = append(sorted, types.CrawlHealthCount{Value: value, Count: count})
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Value < sorted[j].Value
+	})
+
+	return sorted
+}
@@ -0,0 +1,72 @@
+package crawlhealth
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func stringPtr(v string) *string {
+	return &v
+}
+
+func Test_Summarize(t *testing.T) {
+	queryJobID := uuid.Must(uuid.NewV4())
+
+	statuses := []types.ExportURLStatus{
+		{URL: "https://a.com/", CrawlStatus: "processed"},
+		{URL: "https://b.com/", CrawlStatus: "processed"},
+		{URL: "https://c.com/", CrawlStatus: "processed"},
+		{URL: "https://d.com/", CrawlStatus: "error"},
+		{URL: "https://e.com/", CrawlStatus: "blocked"},
+		{URL: "https://f.com/", CrawlStatus: "pending"},
+	}
+
+	diagnostics := []types.FetchDiagnostic{
+		{URL: "https://a.com/", StatusCode: intPtr(200), ResponseTimeMs: 100, ContentType: "text/html; charset=utf-8"},
+		{URL: "https://b.com/", StatusCode: intPtr(200), ResponseTimeMs: 300, ContentType: "text/html", Redirects: []string{"http://b.com/"}, Rendered: true},
+		{URL: "https://c.com/", Cached: true},
+		{URL: "https://d.com/", ResponseTimeMs: 60000, FailureCategory: stringPtr("timeout"), Error: stringPtr("deadline exceeded")},
+		{URL: "https://e.com/", FailureCategory: stringPtr("robots_blocked")},
+	}
+
+	health := Summarize(queryJobID, statuses, diagnostics)
+
+	require.Equal(t, queryJobID, health.QueryJobID)
+	require.Equal(t, 6, health.URLCount)
+	require.Equal(t, 3, health.Processed)
+	require.Equal(t, 1, health.Errored)
+	require.Equal(t, 1, health.Blocked)
+	require.Equal(t, 1, health.Pending)
+	require.Equal(t, 1, health.Cached)
+	require.Equal(t, 1, health.Redirected)
+	require.Equal(t, 1, health.Rendered)
+	require.Equal(t, 0.6, health.SuccessRate)
+
+	require.Equal(t, 20133, health.AvgResponseTimeMs)
+	require.Equal(t, 300, health.MedianResponseTimeMs)
+	require.Equal(t, 60000, health.P95ResponseTimeMs)
+
+	require.Equal(t, []types.CrawlHealthCount{{Value: "200", Count: 2}}, health.StatusCodes)
+	require.Equal(t, []types.CrawlHealthCount{{Value: "robots_blocked", Count: 1}, {Value: "timeout", Count: 1}}, health.FailureCategories)
+	require.Equal(t, []types.CrawlHealthCount{{Value: "text/html", Count: 2}}, health.ContentTypes)
+	require.Equal(t, []types.CrawlHealthURL{
+		{URL: "https://d.com/", ResponseTimeMs: 60000},
+		{URL: "https://b.com/", ResponseTimeMs: 300},
+		{URL: "https://a.com/", ResponseTimeMs: 100},
+	}, health.SlowestURLs)
+}
+
+func Test_SummarizeEmpty(t *testing.T) {
+	health := Summarize(uuid.Nil, []types.ExportURLStatus{}, []types.FetchDiagnostic{})
+
+	require.Equal(t, 0.0, health.SuccessRate)
+	require.Empty(t, health.SlowestURLs)
+	require.NotNil(t, health.StatusCodes)
+}
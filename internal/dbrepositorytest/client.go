@@ -36,8 +36,12 @@ func (r *TestRepo) CleanDB() {
 	ctx := context.Background()
 
 	r.pgClient.Connect()
-	r.pgClient.ExecContext(ctx, `DELETE FROM link`)
+	r.pgClient.ExecContext(ctx, `DELETE FROM watchlist`)
+	r.pgClient.ExecContext(ctx, `DELETE FROM webhook_endpoint`)
+	r.pgClient.ExecContext(ctx, `DELETE FROM zenserp_usage`)
 	r.pgClient.ExecContext(ctx, `DELETE FROM query_item`)
+	r.pgClient.ExecContext(ctx, `DELETE FROM page_link`)
+	r.pgClient.ExecContext(ctx, `DELETE FROM page`)
 	r.pgClient.ExecContext(ctx, `DELETE FROM query_location`)
 	r.pgClient.ExecContext(ctx, `DELETE FROM query_job`)
 	r.pgClient.Close()
@@ -0,0 +1,319 @@
+package exports
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/reports"
+	"github.com/jponc/competitive-analysis/internal/repository/dbrepository"
+	"github.com/jponc/competitive-analysis/internal/serpanalysis"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/jponc/competitive-analysis/pkg/lambdaresponses"
+	"github.com/jponc/competitive-analysis/pkg/s3"
+	"github.com/jponc/competitive-analysis/pkg/spreadsheet"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// schemaVersion is bumped whenever the columns of a dataset change
+	schemaVersion  = "1"
+	downloadExpiry = 15 * time.Minute
+)
+
+const (
+	ReportFormatHTML = "html"
+	ReportFormatPDF  = "pdf"
+)
+
+const (
+	DatasetPositionHits = "position_hits"
+	DatasetRankings     = "rankings"
+	DatasetURLs         = "urls"
+)
+
+type dataset struct {
+	columns []string
+	write   func(ctx context.Context, repository *dbrepository.Repository, queryJobID uuid.UUID, w spreadsheet.Writer) error
+}
+
+var datasets = map[string]dataset{
+	DatasetPositionHits: {
+		columns: []string{"url", "avg_position", "location_hits_count"},
+		write: func(ctx context.Context, repository *dbrepository.Repository, queryJobID uuid.UUID, w spreadsheet.Writer) error {
+			return repository.StreamQueryJobPositionHits(ctx, queryJobID, func(positionHit types.QueryJobPositionHit) error {
+				return w.WriteRow([]interface{}{positionHit.URL, float64(positionHit.AvgPosition), positionHit.LocationHitsCount})
+			})
+		},
+	},
+	DatasetRankings: {
+		columns: []string{"query_location_id", "device", "country", "location", "cached", "position", "url", "title"},
+		write: func(ctx context.Context, repository *dbrepository.Repository, queryJobID uuid.UUID, w spreadsheet.Writer) error {
+			return repository.StreamQueryJobRankings(ctx, queryJobID, func(ranking types.ExportRanking) error {
+				return w.WriteRow([]interface{}{
+					ranking.QueryLocationID.String(),
+					ranking.Device,
+					ranking.Country,
+					ranking.Location,
+					ranking.Cached,
+					ranking.Position,
+					ranking.URL,
+					ranking.Title,
+				})
+			})
+		},
+	},
+	DatasetURLs: {
+		columns: []string{"url", "title", "crawl_status", "processed_at", "link_count"},
+		write: func(ctx context.Context, repository *dbrepository.Repository, queryJobID uuid.UUID, w spreadsheet.Writer) error {
+			return repository.StreamQueryJobURLStatuses(ctx, queryJobID, func(urlStatus types.ExportURLStatus) error {
+				var processedAt interface{}
+				if urlStatus.ProcessedAt != nil {
+					processedAt = *urlStatus.ProcessedAt
+				}
+
+				return w.WriteRow([]interface{}{urlStatus.URL, urlStatus.Title, urlStatus.CrawlStatus, processedAt, urlStatus.LinkCount})
+			})
+		},
+	},
+}
+
+type Service struct {
+	repository *dbrepository.Repository
+	s3Client   *s3.Client
+}
+
+// NewService instantiates the exports service
+func NewService(repository *dbrepository.Repository, s3Client *s3.Client) *Service {
+	s := &Service{
+		repository: repository,
+		s3Client:   s3Client,
+	}
+
+	return s
+}
+
+// ExportQueryJob exports a dataset of the query job as CSV or XLSX. Rows are streamed from the database
+// into the export file on S3 and the response redirects to a short lived download URL. The columns of the
+// dataset are listed in the X-Export-Columns header.
+func (s *Service) ExportQueryJob(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.repository == nil {
+		log.Errorf("repository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	if s.s3Client == nil {
+		log.Errorf("s3Client not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	id, found := request.PathParameters["id"]
+	if !found {
+		log.Fatalf("failed to get id path parameter")
+	}
+
+	queryJobID := uuid.FromStringOrNil(id)
+
+	datasetName := request.QueryStringParameters["dataset"]
+	ds, found := datasets[datasetName]
+	if !found {
+		return lambdaresponses.Respond400(fmt.Errorf("dataset must be one of %s, %s or %s", DatasetPositionHits, DatasetRankings, DatasetURLs))
+	}
+
+	format := spreadsheet.FormatCSV
+	if v, found := request.QueryStringParameters["format"]; found {
+		format = v
+	}
+
+	if format != spreadsheet.FormatCSV && format != spreadsheet.FormatXLSX {
+		return lambdaresponses.Respond400(fmt.Errorf("format must be %s or %s", spreadsheet.FormatCSV, spreadsheet.FormatXLSX))
+	}
+
+	err := s.repository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.repository.Close()
+
+	queryJobs, err := s.repository.GetQueryJobsByIDs(ctx, []uuid.UUID{queryJobID})
+	if err != nil {
+		log.Errorf("failed to get query job: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	if len(*queryJobs) == 0 {
+		return lambdaresponses.Respond404(fmt.Errorf("query job not found"))
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", datasetName, queryJobID.String(), format)
+	key := fmt.Sprintf("exports/%s/%d-%s", queryJobID.String(), time.Now().Unix(), filename)
+
+	// Rows are written into the pipe while the upload reads from it
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeExport(ctx, s.repository, queryJobID, ds, format, datasetName, pw))
+	}()
+
+	err = s.s3Client.Upload(ctx, key, spreadsheet.ContentType(format), fmt.Sprintf(`attachment; filename="%s"`, filename), pr)
+	if err != nil {
+		pr.CloseWithError(err)
+		log.Errorf("failed to export %s of query job (%s): %v", datasetName, queryJobID.String(), err)
+		return lambdaresponses.Respond500()
+	}
+
+	url, err := s.s3Client.PresignGetURL(key, downloadExpiry)
+	if err != nil {
+		log.Errorf("failed to presign export url: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	res, err := lambdaresponses.Respond302(url)
+	res.Headers["X-Export-Dataset"] = datasetName
+	res.Headers["X-Export-Format"] = format
+	res.Headers["X-Export-Columns"] = strings.Join(ds.columns, ",")
+	res.Headers["X-Export-Schema-Version"] = schemaVersion
+	res.Headers["Access-Control-Expose-Headers"] = "X-Export-Dataset, X-Export-Format, X-Export-Columns, X-Export-Schema-Version"
+
+	return res, err
+}
+
+// GetQueryJobReport renders the keyword report of a completed query job as a standalone HTML page or a
+// PDF document, the response redirects to a short lived download URL
+func (s *Service) GetQueryJobReport(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if s.repository == nil {
+		log.Errorf("repository not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	if s.s3Client == nil {
+		log.Errorf("s3Client not defined")
+		return lambdaresponses.Respond500()
+	}
+
+	id, found := request.PathParameters["id"]
+	if !found {
+		log.Fatalf("failed to get id path parameter")
+	}
+
+	queryJobID := uuid.FromStringOrNil(id)
+
+	format := ReportFormatHTML
+	if v, found := request.QueryStringParameters["format"]; found {
+		format = v
+	}
+
+	if format != ReportFormatHTML && format != ReportFormatPDF {
+		return lambdaresponses.Respond400(fmt.Errorf("format must be %s or %s", ReportFormatHTML, ReportFormatPDF))
+	}
+
+	contentScope := types.ContentScopeMain
+	if v, found := request.QueryStringParameters["content"]; found {
+		contentScope = v
+	}
+
+	if contentScope != types.ContentScopeMain && contentScope != types.ContentScopeFull {
+		return lambdaresponses.Respond400(fmt.Errorf("content must be %s or %s", types.ContentScopeMain, types.ContentScopeFull))
+	}
+
+	err := s.repository.Connect()
+	if err != nil {
+		log.Errorf("error connecting to repository db: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	defer s.repository.Close()
+
+	queryJobs, err := s.repository.GetQueryJobsByIDs(ctx, []uuid.UUID{queryJobID})
+	if err != nil {
+		log.Errorf("failed to get query job: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	if len(*queryJobs) == 0 {
+		return lambdaresponses.Respond404(fmt.Errorf("query job not found"))
+	}
+
+	queryJob := (*queryJobs)[0]
+	if queryJob.CompletedAt == nil {
+		return lambdaresponses.Respond400(fmt.Errorf("query job is not completed yet"))
+	}
+
+	rankings, err := s.repository.GetQueryJobLocationRankings(ctx, queryJobID)
+	if err != nil {
+		log.Errorf("failed to get query job location rankings: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	contents, err := s.repository.GetQueryJobURLContents(ctx, queryJobID, contentScope == types.ContentScopeFull)
+	if err != nil {
+		log.Errorf("failed to get query job url contents: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	report := serpanalysis.KeywordReport(queryJob, *rankings, *contents)
+
+	render := reports.RenderHTML
+	contentType := "text/html; charset=utf-8"
+	if format == ReportFormatPDF {
+		render = reports.RenderPDF
+		contentType = "application/pdf"
+	}
+
+	filename := fmt.Sprintf("report-%s.%s", queryJobID.String(), format)
+	key := fmt.Sprintf("reports/%s/%d-%s", queryJobID.String(), time.Now().Unix(), filename)
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(render(pw, report))
+	}()
+
+	err = s.s3Client.Upload(ctx, key, contentType, fmt.Sprintf(`inline; filename="%s"`, filename), pr)
+	if err != nil {
+		pr.CloseWithError(err)
+		log.Errorf("failed to render %s report of query job (%s): %v", format, queryJobID.String(), err)
+		return lambdaresponses.Respond500()
+	}
+
+	url, err := s.s3Client.PresignGetURL(key, downloadExpiry)
+	if err != nil {
+		log.Errorf("failed to presign report url: %v", err)
+		return lambdaresponses.Respond500()
+	}
+
+	return lambdaresponses.Respond302(url)
+}
+
+func writeExport(ctx context.Context, repository *dbrepository.Repository, queryJobID uuid.UUID, ds dataset, format, sheetName string, out io.Writer) error {
+	var w spreadsheet.Writer
+	if format == spreadsheet.FormatXLSX {
+		xw, err := spreadsheet.NewXLSXWriter(out, sheetName)
+		if err != nil {
+			return err
+		}
+		w = xw
+	} else {
+		w = spreadsheet.NewCSVWriter(out)
+	}
+
+	header := make([]interface{}, len(ds.columns))
+	for i, column := range ds.columns {
+		header[i] = column
+	}
+
+	if err := w.WriteRow(header); err != nil {
+		return err
+	}
+
+	if err := ds.write(ctx, repository, queryJobID, w); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
@@ -0,0 +1,321 @@
+package keywordusage
+
+import (
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/types"
+)
+
+// introductionLength is the number of words of the main content making its introduction
+const introductionLength = 100
+
+// stopWords aren't counted as partial matches of a keyword, unless it's only made of them
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true, "for": true,
+	"from": true, "how": true, "in": true, "is": true, "it": true, "of": true, "on": true, "or": true, "the": true,
+	"to": true, "vs": true, "what": true, "with": true,
+}
+
+// placements lists the summarized keyword placements, in the order they're returned
+var placements = []struct {
+	name    string
+	present func(u types.KeywordUsage) bool
+}{
+	{"title", func(u types.KeywordUsage) bool { return u.InTitle }},
+	{"h1", func(u types.KeywordUsage) bool { return u.InH1 }},
+	{"url", func(u types.KeywordUsage) bool { return u.InURL }},
+	{"description", func(u types.KeywordUsage) bool { return u.InDescription }},
+	{"first_100_words", func(u types.KeywordUsage) bool { return u.InFirst100Words }},
+}
+
+// frequencies lists the summarized keyword frequency metrics, in the order they're returned
+var frequencies = []struct {
+	name  string
+	value func(u types.KeywordUsage) float64
+}{
+	{"exact_match_count", func(u types.KeywordUsage) float64 { return float64(u.ExactMatchCount) }},
+	{"partial_match_count", func(u types.KeywordUsage) float64 { return float64(u.PartialMatchCount) }},
+	{"density", func(u types.KeywordUsage) float64 { return u.Density }},
+}
+
+// Page is the content of a page the keyword usage is analysed on
+type Page struct {
+	URL         string
+	Title       string
+	Description string
+	H1          []string
+	MainContent string
+}
+
+// Analyze returns how the page uses the keyword. Texts are compared word by word, ignoring case and
+// punctuation: an exact match is the words of the keyword in order, a partial match is one of its words
+// outside of an exact match.
+func Analyze(keyword string, page Page) types.KeywordUsage {
+	keywordWords := words(keyword)
+	if len(keywordWords) == 0 {
+		return types.KeywordUsage{}
+	}
+
+	contentWords := words(page.MainContent)
+
+	introduction := contentWords
+	if len(introduction) > introductionLength {
+		introduction = introduction[:introductionLength]
+	}
+
+	inH1 := false
+	for _, h1 := range page.H1 {
+		if exactMatches(words(h1), keywordWords) > 0 {
+			inH1 = true
+			break
+		}
+	}
+
+	exactMatchCount := exactMatches(contentWords, keywordWords)
+
+	usage := types.KeywordUsage{
+		InTitle:           exactMatches(words(page.Title), keywordWords) > 0,
+		InH1:              inH1,
+		InURL:             exactMatches(words(slug(page.URL)), keywordWords) > 0,
+		InDescription:     exactMatches(words(page.Description), keywordWords) > 0,
+		InFirst100Words:   exactMatches(introduction, keywordWords) > 0,
+		ExactMatchCount:   exactMatchCount,
+		PartialMatchCount: partialMatches(contentWords, keywordWords),
+		WordCount:         len(contentWords),
+	}
+
+	if len(contentWords) > 0 {
+		usage.Density = round(float64(exactMatchCount*len(keywordWords)) / float64(len(contentWords)) * 100)
+	}
+
+	return usage
+}
+
+// Summarize compares the average position of the query items by keyword placement, and correlates the keyword
+// frequencies of their pages with their position
+func Summarize(queryJobID uuid.UUID, keyword string, usages []types.QueryItemKeywordUsage) *types.KeywordUsageSummary {
+	summary := &types.KeywordUsageSummary{
+		QueryJobID:     queryJobID,
+		Keyword:        keyword,
+		QueryItemCount: len(usages),
+		Placements:     []types.KeywordPlacement{},
+		Frequencies:    []types.KeywordFrequency{},
+	}
+
+	if len(usages) == 0 {
+		return summary
+	}
+
+	positions := make([]float64, len(usages))
+	for i, usage := range usages {
+		positions[i] = float64(usage.Position)
+	}
+
+	for _, placement := range placements {
+		present, absent := []float64{}, []float64{}
+		for i, usage := range usages {
+			if placement.present(usage.KeywordUsage) {
+				present = append(present, positions[i])
+			} else {
+				absent = append(absent, positions[i])
+			}
+		}
+
+		summary.Placements = append(summary.Placements, types.KeywordPlacement{
+			Placement:          placement.name,
+			PresentCount:       len(present),
+			PresentRate:        round(float64(len(present)) / float64(len(usages))),
+			AvgPositionPresent: average(present),
+			AvgPositionAbsent:  average(absent),
+		})
+	}
+
+	for _, frequency := range frequencies {
+		values := make([]float64, len(usages))
+		sum := 0.0
+		for i, usage := range usages {
+			values[i] = frequency.value(usage.KeywordUsage)
+			sum += values[i]
+		}
+
+		summary.Frequencies = append(summary.Frequencies, types.KeywordFrequency{
+			Metric:      frequency.name,
+			Average:     round(sum / float64(len(values))),
+			Median:      median(values),
+			Correlation: rankCorrelation(values, positions),
+		})
+	}
+
+	return summary
+}
+
+// words returns the lowercased words of the text, split on anything but letters and digits
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// slug returns the path of the url, where its keywords are
+func slug(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return u.Path
+}
+
+// exactMatches counts the non overlapping occurrences of the keyword words, in order, in the text words
+func exactMatches(textWords, keywordWords []string) int {
+	count := 0
+	for i := 0; i+len(keywordWords) <= len(textWords); {
+		if matchesAt(textWords, keywordWords, i) {
+			count++
+			i += len(keywordWords)
+		} else {
+			i++
+		}
+	}
+
+	return count
+}
+
+// partialMatches counts the occurrences of the keyword words outside of the exact matches of the keyword
+func partialMatches(textWords, keywordWords []string) int {
+	terms := map[string]bool{}
+	for _, word := range keywordWords {
+		if !stopWords[word] {
+			terms[word] = true
+		}
+	}
+
+	if len(terms) == 0 {
+		for _, word := range keywordWords {
+			terms[word] = true
+		}
+	}
+
+	count := 0
+	for i := 0; i < len(textWords); {
+		if matchesAt(textWords, keywordWords, i) {
+			i += len(keywordWords)
+			continue
+		}
+
+		if terms[textWords[i]] {
+			count++
+		}
+		i++
+	}
+
+	return count
+}
+
+func matchesAt(textWords, keywordWords []string, i int) bool {
+	if i+len(keywordWords) > len(textWords) {
+		return false
+	}
+
+	for j, word := range keywordWords {
+		if textWords[i+j] != word {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rankCorrelation returns the Spearman rank correlation of the values with the positions, nil when there are
+// too few values or either doesn't vary
+func rankCorrelation(values, positions []float64) *float64 {
+	if len(values) < 3 {
+		return nil
+	}
+
+	valueRanks := ranks(values)
+	positionRanks := ranks(positions)
+
+	meanRank := float64(len(values)+1) / 2
+
+	covariance, valueVariance, positionVariance := 0.0, 0.0, 0.0
+	for i := range values {
+		dv := valueRanks[i] - meanRank
+		dp := positionRanks[i] - meanRank
+
+		covariance += dv * dp
+		valueVariance += dv * dv
+		positionVariance += dp * dp
+	}
+
+	if valueVariance == 0 || positionVariance == 0 {
+		return nil
+	}
+
+	correlation := round(covariance / math.Sqrt(valueVariance*positionVariance))
+
+	return &correlation
+}
+
+// ranks returns the rank of every value, starting at 1, tied values sharing their average rank
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+
+	result := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && values[order[end]] == values[order[start]] {
+			end++
+		}
+
+		rank := float64(start+end+1) / 2
+		for _, i := range order[start:end] {
+			result[i] = rank
+		}
+
+		start = end
+	}
+
+	return result
+}
+
+func average(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+
+	avg := round(sum / float64(len(values)))
+
+	return &avg
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return round((sorted[middle-1] + sorted[middle]) / 2)
+	}
+
+	return sorted[middle]
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,107 @@
+package keywordusage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Analyze(t *testing.T) {
+	page := Page{
+		URL:         "https://example.com/blog/best-running-shoes-2022?ref=home",
+		Title:       "The 10 Best Running Shoes | Example",
+		Description: "Our pick of shoes for running.",
+		H1:          []string{"Reviews", "Best running shoes"},
+		MainContent: "Best running shoes. Choosing RUNNING SHOES is hard, so we tested every shoe. Running every day needs good shoes.",
+	}
+
+	usage := Analyze("Running Shoes", page)
+
+	require.True(t, usage.InTitle)
+	require.True(t, usage.InH1)
+	require.True(t, usage.InURL)
+	require.False(t, usage.InDescription)
+	require.True(t, usage.InFirst100Words)
+	require.Equal(t, 2, usage.ExactMatchCount)
+	require.Equal(t, 2, usage.PartialMatchCount)
+	require.Equal(t, 19, usage.WordCount)
+	require.Equal(t, 21.05, usage.Density)
+}
+
+func Test_AnalyzeIntroduction(t *testing.T) {
+	page := Page{
+		URL:         "https://example.com/",
+		MainContent: strings.Repeat("filler ", 100) + "trail running shoes",
+	}
+
+	usage := Analyze("running shoes", page)
+
+	require.False(t, usage.InFirst100Words)
+	require.False(t, usage.InURL)
+	require.Equal(t, 1, usage.ExactMatchCount)
+}
+
+func Test_AnalyzeStopWords(t *testing.T) {
+	page := Page{MainContent: "How to run a marathon: run slowly, and learn how to run downhill."}
+
+	usage := Analyze("how to run", page)
+
+	require.Equal(t, 2, usage.ExactMatchCount)
+	require.Equal(t, 1, usage.PartialMatchCount)
+}
+
+func Test_Summarize(t *testing.T) {
+	queryJobID := uuid.Must(uuid.NewV4())
+
+	usages := []types.QueryItemKeywordUsage{
+		{URL: "https://a.com/", Position: 1, KeywordUsage: types.KeywordUsage{InTitle: true, ExactMatchCount: 5, PartialMatchCount: 10}},
+		{URL: "https://b.com/", Position: 2, KeywordUsage: types.KeywordUsage{InTitle: true, ExactMatchCount: 3, PartialMatchCount: 8}},
+		{URL: "https://c.com/", Position: 3, KeywordUsage: types.KeywordUsage{ExactMatchCount: 4, PartialMatchCount: 2}},
+		{URL: "https://d.com/", Position: 5, KeywordUsage: types.KeywordUsage{ExactMatchCount: 0, PartialMatchCount: 1}},
+	}
+
+	summary := Summarize(queryJobID, "running shoes", usages)
+
+	require.Equal(t, queryJobID, summary.QueryJobID)
+	require.Equal(t, "running shoes", summary.Keyword)
+	require.Equal(t, 4, summary.QueryItemCount)
+	require.Len(t, summary.Placements, 5)
+	require.Len(t, summary.Frequencies, 3)
+
+	title := summary.Placements[0]
+	require.Equal(t, "title", title.Placement)
+	require.Equal(t, 2, title.PresentCount)
+	require.Equal(t, 0.5, title.PresentRate)
+	require.Equal(t, 1.5, *title.AvgPositionPresent)
+	require.Equal(t, 4.0, *title.AvgPositionAbsent)
+
+	h1 := summary.Placements[1]
+	require.Equal(t, "h1", h1.Placement)
+	require.Equal(t, 0, h1.PresentCount)
+	require.Nil(t, h1.AvgPositionPresent)
+	require.Equal(t, 2.75, *h1.AvgPositionAbsent)
+
+	exact := summary.Frequencies[0]
+	require.Equal(t, "exact_match_count", exact.Metric)
+	require.Equal(t, 3.0, exact.Average)
+	require.Equal(t, 3.5, exact.Median)
+	require.Equal(t, -0.8, *exact.Correlation)
+
+	partial := summary.Frequencies[1]
+	require.Equal(t, -1.0, *partial.Correlation)
+
+	// the density doesn't vary
+	density := summary.Frequencies[2]
+	require.Nil(t, density.Correlation)
+}
+
+func Test_SummarizeEmpty(t *testing.T) {
+	summary := Summarize(uuid.Must(uuid.NewV4()), "running shoes", []types.QueryItemKeywordUsage{})
+
+	require.Equal(t, 0, summary.QueryItemCount)
+	require.Empty(t, summary.Placements)
+	require.Empty(t, summary.Frequencies)
+}
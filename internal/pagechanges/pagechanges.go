@@ -0,0 +1,103 @@
+package pagechanges
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/jponc/competitive-analysis/internal/types"
+)
+
+const (
+	// maxSampleSections is the number of added and removed sections returned in a change summary
+	maxSampleSections = 10
+	// maxSampleSectionLength is the number of characters kept of each returned section
+	maxSampleSectionLength = 200
+)
+
+// Normalize joins the sections of a scraped page into the text we store and compare between crawls,
+// one section per line, and returns it with its content hash.
+func Normalize(sections []string) (string, string) {
+	lines := []string{}
+	for _, section := range sections {
+		line := strings.Join(strings.Fields(section), " ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	content := strings.Join(lines, "\n")
+	hash := sha256.Sum256([]byte(content))
+
+	return content, hex.EncodeToString(hash[:])
+}
+
+// WordCount returns the number of words of the normalized content
+func WordCount(content string) int {
+	return len(strings.Fields(content))
+}
+
+// Summarize describes how a page changed from the previous snapshot to the current one
+func Summarize(previous, current types.PageSnapshot) types.PageChange {
+	previousSections := sectionSet(previous.Content)
+	currentSections := sectionSet(current.Content)
+
+	change := types.PageChange{
+		URL:               current.URL,
+		PreviousSeenAt:    previous.LastSeenAt,
+		ChangedAt:         current.FirstSeenAt,
+		PreviousTitle:     previous.Title,
+		Title:             current.Title,
+		TitleChanged:      previous.Title != current.Title,
+		PreviousWordCount: previous.WordCount,
+		WordCount:         current.WordCount,
+		WordCountDelta:    current.WordCount - previous.WordCount,
+		AddedSections:     []string{},
+		RemovedSections:   []string{},
+	}
+
+	for _, section := range splitSections(current.Content) {
+		if !previousSections[section] {
+			change.AddedSectionsCount++
+			change.AddedSections = appendSample(change.AddedSections, section)
+		}
+	}
+
+	for _, section := range splitSections(previous.Content) {
+		if !currentSections[section] {
+			change.RemovedSectionsCount++
+			change.RemovedSections = appendSample(change.RemovedSections, section)
+		}
+	}
+
+	return change
+}
+
+func splitSections(content string) []string {
+	if content == "" {
+		return []string{}
+	}
+
+	return strings.Split(content, "\n")
+}
+
+func sectionSet(content string) map[string]bool {
+	set := map[string]bool{}
+	for _, section := range splitSections(content) {
+		set[section] = true
+	}
+
+	return set
+}
+
+func appendSample(samples []string, section string) []string {
+	if len(samples) >= maxSampleSections {
+		return samples
+	}
+
+	if runes := []rune(section); len(runes) > maxSampleSectionLength {
+		section = string(runes[:maxSampleSectionLength]) + "…"
+	}
+
+	return append(samples, section)
+}
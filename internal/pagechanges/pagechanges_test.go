@@ -0,0 +1,42 @@
+package pagechanges_test
+
+import (
+	"testing"
+
+	"github.com/jponc/competitive-analysis/internal/pagechanges"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Normalize(t *testing.T) {
+	content, hash := pagechanges.Normalize([]string{"  Hello   world ", "", "Second\tline"})
+	require.Equal(t, "Hello world\nSecond line", content)
+
+	_, sameHash := pagechanges.Normalize([]string{"Hello world", "Second line"})
+	require.Equal(t, hash, sameHash)
+
+	_, otherHash := pagechanges.Normalize([]string{"Second line", "Hello world"})
+	require.NotEqual(t, hash, otherHash)
+
+	require.Equal(t, 4, pagechanges.WordCount(content))
+}
+
+func Test_Summarize(t *testing.T) {
+	previousContent, _ := pagechanges.Normalize([]string{"Intro", "Old pricing section", "Footer"})
+	currentContent, _ := pagechanges.Normalize([]string{"Intro", "New pricing section with more words", "FAQ", "Footer"})
+
+	previous := types.PageSnapshot{URL: "https://a.com", Title: "Pricing", Content: previousContent, WordCount: pagechanges.WordCount(previousContent)}
+	current := types.PageSnapshot{URL: "https://a.com", Title: "Pricing 2022", Content: currentContent, WordCount: pagechanges.WordCount(currentContent)}
+
+	change := pagechanges.Summarize(previous, current)
+
+	require.Equal(t, "https://a.com", change.URL)
+	require.True(t, change.TitleChanged)
+	require.Equal(t, 5, change.PreviousWordCount)
+	require.Equal(t, 9, change.WordCount)
+	require.Equal(t, 4, change.WordCountDelta)
+	require.Equal(t, 2, change.AddedSectionsCount)
+	require.Equal(t, []string{"New pricing section with more words", "FAQ"}, change.AddedSections)
+	require.Equal(t, 1, change.RemovedSectionsCount)
+	require.Equal(t, []string{"Old pricing section"}, change.RemovedSections)
+}
@@ -0,0 +1,49 @@
+package queryjobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/api/eventschema"
+	"github.com/jponc/competitive-analysis/internal/repository/dbrepository"
+	"github.com/jponc/competitive-analysis/internal/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Publisher publishes messages to SNS topics
+type Publisher interface {
+	Publish(ctx context.Context, topic string, message interface{}) error
+}
+
+// Fail marks the query job as failed with the reason and notifies the webhook endpoints, unless it already
+// completed or failed
+func Fail(ctx context.Context, repository *dbrepository.Repository, publisher Publisher, queryJobID uuid.UUID, reason string) error {
+	marked, err := repository.MarkQueryJobAsFailed(ctx, queryJobID, reason)
+	if err != nil {
+		return fmt.Errorf("%s query job cannot be marked as failed: %w", queryJobID.String(), err)
+	}
+
+	if marked {
+		PublishWebhookEvent(ctx, publisher, types.WebhookEventQueryJobFailed, queryJobID)
+	}
+
+	return nil
+}
+
+// PublishWebhookEvent notifies the webhook endpoints subscribed to the event type. Failing to do so doesn't
+// fail the processing of the query job.
+func PublishWebhookEvent(ctx context.Context, publisher Publisher, eventType string, queryJobID uuid.UUID) {
+	msg := eventschema.WebhookEventMessage{
+		EventType:  eventType,
+		QueryJobID: queryJobID.String(),
+		OccurredAt: time.Now(),
+	}
+
+	err := publisher.Publish(ctx, eventschema.WebhookEvent, msg)
+	if err != nil {
+		log.Errorf("failed to publish %s webhook event for query job (%s): %v", eventType, queryJobID.String(), err)
+	}
+}
@@ -0,0 +1,41 @@
+package reports
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+
+	"github.com/jponc/competitive-analysis/internal/types"
+)
+
+//go:embed templates/report.html
+var reportTemplate string
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"maxBucket": maxBucket,
+	"percent":   percent,
+}).Parse(reportTemplate))
+
+// RenderHTML writes the report as a standalone HTML page, styles are inlined so it can be shared as a single file
+func RenderHTML(w io.Writer, report *types.KeywordReport) error {
+	return htmlTemplate.Execute(w, report)
+}
+
+func maxBucket(buckets []types.ReportBucket) int {
+	max := 0
+	for _, bucket := range buckets {
+		if bucket.Count > max {
+			max = bucket.Count
+		}
+	}
+
+	return max
+}
+
+func percent(count, max int) int {
+	if max == 0 {
+		return 0
+	}
+
+	return count * 100 / max
+}
@@ -0,0 +1,150 @@
+package reports
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	pdfMargin     = 15.0
+	pdfPageWidth  = 210.0 - 2*pdfMargin
+	pdfLineHeight = 6.0
+)
+
+// RenderPDF writes the report as an A4 PDF document
+func RenderPDF(w io.Writer, report *types.KeywordReport) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+
+	// the core fonts only cover cp1252
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(pdfPageWidth, 10, tr(report.QueryJob.Keyword), "", 1, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(102, 102, 102)
+	meta := fmt.Sprintf("%d locations - Generated %s", len(report.Locations), report.GeneratedAt.Format("2 Jan 2006 15:04 MST"))
+	if report.QueryJob.CompletedAt != nil {
+		meta = fmt.Sprintf("Completed %s - %s", report.QueryJob.CompletedAt.Format("2 Jan 2006 15:04 MST"), meta)
+	}
+	if report.QueryJob.TargetDomain != nil {
+		meta = fmt.Sprintf("%s - Tracking %s", meta, *report.QueryJob.TargetDomain)
+	}
+	pdf.CellFormat(pdfPageWidth, pdfLineHeight, tr(meta), "", 1, "L", false, 0, "")
+	pdf.SetTextColor(34, 34, 34)
+
+	// Top competitors
+	pdfHeading(pdf, "Top competitors")
+	widths := []float64{80, 25, 25, 25, 25}
+	pdfRow(pdf, tr, widths, []string{"Domain", "Best", "Avg", "Locations", "URLs"}, true)
+	for _, competitor := range report.TopCompetitors {
+		pdf.SetFont("Helvetica", "", 9)
+		if competitor.IsTarget {
+			pdf.SetFont("Helvetica", "B", 9)
+		}
+
+		pdfRow(pdf, tr, widths, []string{
+			competitor.Domain,
+			fmt.Sprintf("%d", competitor.BestPosition),
+			fmt.Sprintf("%.2f", competitor.AvgPosition),
+			fmt.Sprintf("%d", competitor.LocationCount),
+			fmt.Sprintf("%d", competitor.URLCount),
+		}, false)
+	}
+
+	// Rankings per location
+	pdfHeading(pdf, "Rankings per location")
+	widths = []float64{12, 88, 80}
+	for _, location := range report.Locations {
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(pdfPageWidth, pdfLineHeight+1, tr(fmt.Sprintf("%s (%s)", location.Location, location.Device)), "", 1, "L", false, 0, "")
+
+		pdf.SetFont("Helvetica", "", 8)
+		for _, ranking := range location.Rankings {
+			pdfRow(pdf, tr, widths, []string{fmt.Sprintf("%d", ranking.Position), ranking.Title, ranking.URL}, false)
+		}
+		pdf.Ln(2)
+	}
+
+	// Body length distribution
+	pdfHeading(pdf, "Body length of crawled pages")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.CellFormat(pdfPageWidth, pdfLineHeight, fmt.Sprintf("%d pages crawled, median of %d characters", report.CrawledPages, report.MedianBodyLength), "", 1, "L", false, 0, "")
+
+	max := maxBucket(report.BodyLengths)
+	barWidth := pdfPageWidth - 30 - 15
+	pdf.SetFillColor(74, 123, 208)
+	for _, bucket := range report.BodyLengths {
+		pdf.CellFormat(30, pdfLineHeight, bucket.Label, "", 0, "L", false, 0, "")
+
+		x, y := pdf.GetXY()
+		if bucket.Count > 0 {
+			pdf.Rect(x, y+1, barWidth*float64(bucket.Count)/float64(max), pdfLineHeight-2, "F")
+		}
+
+		pdf.SetX(x + barWidth)
+		pdf.CellFormat(15, pdfLineHeight, fmt.Sprintf("%d", bucket.Count), "", 1, "R", false, 0, "")
+	}
+
+	// Title words
+	pdfHeading(pdf, "Most common title words")
+	pdf.SetFont("Helvetica", "", 9)
+	widths = []float64{60, 20}
+	for _, wordCount := range report.TitleWords {
+		pdfRow(pdf, tr, widths, []string{wordCount.Word, fmt.Sprintf("%d", wordCount.Count)}, false)
+	}
+
+	return pdf.Output(w)
+}
+
+func pdfHeading(pdf *gofpdf.Fpdf, text string) {
+	pdf.Ln(6)
+	pdf.SetFont("Helvetica", "B", 13)
+	pdf.CellFormat(pdfPageWidth, 8, text, "B", 1, "L", false, 0, "")
+	pdf.Ln(2)
+}
+
+// pdfRow writes a table row, narrow columns past the first one hold numbers and are right aligned. Texts too
+// wide for their column are shortened.
+func pdfRow(pdf *gofpdf.Fpdf, tr func(string) string, widths []float64, texts []string, header bool) {
+	if header {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(247, 247, 247)
+	}
+
+	for i, text := range texts {
+		align := "L"
+		if i > 0 && widths[i] <= 25 {
+			align = "R"
+		}
+
+		pdf.CellFormat(widths[i], pdfLineHeight, fit(pdf, tr(text), widths[i]-2), "B", 0, align, header, 0, "")
+	}
+
+	pdf.Ln(-1)
+
+	if header {
+		pdf.SetFont("Helvetica", "", 9)
+	}
+}
+
+// fit shortens the text with an ellipsis until it fits the width
+func fit(pdf *gofpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+
+	return string(runes) + "..."
+}
@@ -0,0 +1,64 @@
+package reports
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func testReport() *types.KeywordReport {
+	completedAt := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+	targetDomain := "example.com"
+
+	return &types.KeywordReport{
+		QueryJob: types.QueryJob{
+			Keyword:      "crème brûlée <recipe>",
+			CompletedAt:  &completedAt,
+			TargetDomain: &targetDomain,
+		},
+		GeneratedAt: completedAt,
+		TopCompetitors: []types.ReportCompetitor{
+			{Domain: "a.com", BestPosition: 1, AvgPosition: 1.5, LocationCount: 2, URLCount: 1},
+			{Domain: "example.com", BestPosition: 2, AvgPosition: 2.5, LocationCount: 2, URLCount: 2, IsTarget: true},
+		},
+		Locations: []types.ReportLocation{
+			{Device: "desktop", Location: "Sydney,New South Wales,Australia", Rankings: []types.ReportRanking{
+				{Position: 1, URL: "https://a.com/recipe", Title: "Crème brûlée recipe"},
+				{Position: 2, URL: "https://example.com/" + string(bytes.Repeat([]byte("long-path/"), 30)), Title: "Example"},
+			}},
+		},
+		CrawledPages:     2,
+		MedianBodyLength: 3000,
+		BodyLengths: []types.ReportBucket{
+			{Label: "< 1k", Count: 0},
+			{Label: "2.5k - 5k", Count: 2},
+		},
+		TitleWords: []types.ReportWordCount{{Word: "recipe", Count: 2}},
+	}
+}
+
+func Test_RenderHTML(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, RenderHTML(&b, testReport()))
+
+	html := b.String()
+	require.Contains(t, html, "<h1>crème brûlée &lt;recipe&gt;</h1>")
+	require.Contains(t, html, `<tr class="target"><td>example.com</td>`)
+	require.Contains(t, html, "Sydney,New South Wales,Australia")
+	require.Contains(t, html, `style="width: 100%"`)
+	require.Contains(t, html, "recipe &times; 2")
+}
+
+func Test_RenderPDF(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, RenderPDF(&b, testReport()))
+	require.True(t, bytes.HasPrefix(b.Bytes(), []byte("%PDF-")))
+}
+
+func Test_RenderPDFEmptyReport(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, RenderPDF(&b, &types.KeywordReport{BodyLengths: []types.ReportBucket{{Label: "< 1k"}}}))
+}
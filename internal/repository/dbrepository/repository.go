@@ -2,15 +2,48 @@ package dbrepository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"html"
 	"strings"
+	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/jponc/competitive-analysis/internal/types"
 	"github.com/jponc/competitive-analysis/pkg/postgres"
 	"github.com/lib/pq"
 )
 
+// queryJobPositionHitsQuery returns the URLs ranking in at least 3 locations of a query job
+const queryJobPositionHitsQuery = `
+	SELECT AVG(position)::numeric(10,2) as avg_position, url, count(*) as location_hits_count
+	FROM query_item
+	WHERE query_job_id = $1
+	GROUP BY query_job_id, url
+	HAVING count(*) >= 3
+	ORDER BY AVG(position) ASC
+`
+
+// queryItemColumns lists the columns scanned into types.QueryItem, the body and main content are read from the
+// page the query item was crawled into. Pages scraped before main contents were extracted fall back to the body.
+const queryItemColumns = `
+	id, query_job_id, query_location_id, position, title, url, processed_at, created_at, error_processing, blocked, page_id,
+	(SELECT body FROM page WHERE page.id = query_item.page_id) AS body,
+	(SELECT COALESCE(main_content, body) FROM page WHERE page.id = query_item.page_id) AS main_content
+`
+
+// searchBodyMaxLength caps the characters of a body indexed for search, tsvectors are limited to 1MB
+const searchBodyMaxLength = 200000
+
+// snippetStartSel and snippetStopSel delimit the matches in search snippets, they are private use characters
+// stripped from the page text beforehand
+const (
+	snippetStartSel = "\ue000"
+	snippetStopSel  = "\ue001"
+)
+
 type Repository struct {
 	dbClient *postgres.Client
 }
@@ -35,24 +68,102 @@ func (r *Repository) Close() error {
 	return r.dbClient.Close()
 }
 
-func (r *Repository) CreateQueryJob(ctx context.Context, keyword string) (uuid.UUID, error) {
+// ErrZenserpBudgetExceeded is returned when reserving the searches of a query job would go over the monthly
+// Zenserp budget
+var ErrZenserpBudgetExceeded = errors.New("monthly zenserp budget exceeded")
+
+// CreateQueryJob creates the query job and its query locations, and reserves the Zenserp searches of the
+// locations without results cached since cachedSince, all within one transaction. Reservations are serialised
+// so concurrent query jobs can't overspend the budget together: ErrZenserpBudgetExceeded is returned when the
+// searches reserved since budgetSince would go over monthlyBudget, a monthlyBudget of 0 disables the limit.
+func (r *Repository) CreateQueryJob(ctx context.Context, job types.NewQueryJob, cachedSince, budgetSince time.Time, monthlyBudget int) (uuid.UUID, error) {
 	if r.dbClient == nil {
 		return uuid.Nil, fmt.Errorf("dbClient not initialised")
 	}
 
 	var id uuid.UUID
 
-	err := r.dbClient.GetContext(
-		ctx,
-		&id,
-		`
-			INSERT INTO query_job (keyword)
-			VALUES ($1)
-			RETURNING id
-		`,
-		keyword)
+	err := r.dbClient.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		// held until the transaction ends, reading the usage still works meanwhile
+		_, err := tx.ExecContext(ctx, `LOCK TABLE zenserp_usage IN EXCLUSIVE MODE`)
+		if err != nil {
+			return fmt.Errorf("failed to lock zenserp usage: %w", err)
+		}
+
+		cacheKeys := make([]string, len(job.Locations))
+		for i, location := range job.Locations {
+			cacheKeys[i] = location.CacheKey
+		}
+
+		var requestCount int
+		err = tx.GetContext(
+			ctx,
+			&requestCount,
+			`
+				SELECT count(*)
+				FROM unnest($1::text[]) AS k(cache_key)
+				WHERE NOT EXISTS (
+					SELECT 1 FROM serp_cache sc WHERE sc.cache_key = k.cache_key AND sc.created_at >= $2
+				)
+			`, pq.Array(cacheKeys), cachedSince,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to count uncached searches: %w", err)
+		}
+
+		if monthlyBudget > 0 {
+			var used int
+			err = tx.GetContext(ctx, &used, `SELECT COALESCE(SUM(request_count), 0) FROM zenserp_usage WHERE created_at >= $1`, budgetSince)
+			if err != nil {
+				return fmt.Errorf("failed to get zenserp usage count: %w", err)
+			}
+
+			if used+requestCount > monthlyBudget {
+				return fmt.Errorf("%w: %d of %d searches used, %d more requested", ErrZenserpBudgetExceeded, used, monthlyBudget, requestCount)
+			}
+		}
+
+		err = tx.GetContext(
+			ctx,
+			&id,
+			`
+				INSERT INTO query_job (keyword, user_id, device_comparison, target_domain)
+				VALUES ($1, $2, $3, $4)
+				RETURNING id
+			`,
+			job.Keyword, job.UserID, job.DeviceComparison, job.TargetDomain)
+		if err != nil {
+			return fmt.Errorf("failed to insert query job: %w", err)
+		}
+
+		for _, location := range job.Locations {
+			_, err = tx.ExecContext(
+				ctx,
+				`
+					INSERT INTO query_location (query_job_id, device, search_engine, num, country, location)
+					VALUES ($1, $2, $3, $4, $5, $6)
+				`,
+				id, location.Device, location.SearchEngine, location.Num, location.Country, location.Location)
+			if err != nil {
+				return fmt.Errorf("failed to insert query location: %w", err)
+			}
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`
+				INSERT INTO zenserp_usage (query_job_id, user_id, request_count)
+				VALUES ($1, $2, $3)
+			`, id, job.UserID, requestCount,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to reserve zenserp usage: %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to insert query job: %v", err)
+		return uuid.Nil, err
 	}
 
 	return id, nil
@@ -81,29 +192,6 @@ func (r *Repository) GetQueryJob(ctx context.Context, id uuid.UUID) (*types.Quer
 	return &queryJob, nil
 }
 
-func (r *Repository) CreateQueryLocation(ctx context.Context, queryJobID, device, searchEngine, num, country, location string) (uuid.UUID, error) {
-	if r.dbClient == nil {
-		return uuid.Nil, fmt.Errorf("dbClient not initialised")
-	}
-
-	var id uuid.UUID
-
-	err := r.dbClient.GetContext(
-		ctx,
-		&id,
-		`
-			INSERT INTO query_location (query_job_id, device, search_engine, num, country, location)
-			VALUES ($1, $2, $3, $4, $5, $6)
-			RETURNING id
-		`,
-		queryJobID, device, searchEngine, num, country, location)
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to insert query location: %v", err)
-	}
-
-	return id, nil
-}
-
 func (r *Repository) GetQueryLocations(ctx context.Context, queryJobID uuid.UUID) (*[]types.QueryLocation, error) {
 	if r.dbClient == nil {
 		return nil, fmt.Errorf("dbClient not initialised")
@@ -114,7 +202,7 @@ func (r *Repository) GetQueryLocations(ctx context.Context, queryJobID uuid.UUID
 	err := r.dbClient.SelectContext(
 		ctx,
 		&queryLocations,
-		`SELECT * FROM query_location WHERE query_job_id = $1`, queryJobID,
+		`SELECT * FROM query_location WHERE query_job_id = $1 ORDER BY device, location`, queryJobID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get query locations: %w", err)
@@ -216,7 +304,7 @@ func (r *Repository) GetQueryItem(ctx context.Context, id uuid.UUID) (*types.Que
 		ctx,
 		&queryItem,
 		`
-			SELECT * FROM query_item where id = $1
+			SELECT `+queryItemColumns+` FROM query_item where id = $1
 		`, id,
 	)
 	if err != nil {
@@ -237,7 +325,7 @@ func (r *Repository) GetQueryItemUsingJobIDAndUrl(ctx context.Context, queryJobI
 		ctx,
 		&queryItem,
 		`
-			SELECT *
+			SELECT `+queryItemColumns+`
 			FROM query_item
 			WHERE query_job_id = $1 AND url = $2
 			LIMIT 1
@@ -270,6 +358,99 @@ func (r *Repository) SetQueryItemsErrorProcessing(ctx context.Context, queryJobI
 	return nil
 }
 
+// SetQueryItemsBlocked marks the query items of the url as processed without crawling them, as robots.txt
+// disallows it
+func (r *Repository) SetQueryItemsBlocked(ctx context.Context, queryJobID uuid.UUID, url string) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			UPDATE query_item
+			SET processed_at = now(), error_processing = false, blocked = true
+			WHERE query_job_id = $1 and url = $2
+		`, queryJobID, url,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update query item blocked: %w", err)
+	}
+
+	return nil
+}
+
+// GetRobotsTxt returns the robots.txt content of the origin fetched since the given time, or nil if there's none
+func (r *Repository) GetRobotsTxt(ctx context.Context, origin string, since time.Time) (*string, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	var content string
+
+	err := r.dbClient.GetContext(
+		ctx,
+		&content,
+		`SELECT content FROM robots_txt WHERE origin = $1 AND fetched_at >= $2`, origin, since,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get robots.txt: %w", err)
+	}
+
+	return &content, nil
+}
+
+func (r *Repository) SaveRobotsTxt(ctx context.Context, origin, content string) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			INSERT INTO robots_txt (origin, content)
+			VALUES ($1, $2)
+			ON CONFLICT (origin) DO UPDATE
+			SET content = EXCLUDED.content, fetched_at = now()
+		`, origin, sanitizeText(content),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save robots.txt: %w", err)
+	}
+
+	return nil
+}
+
+// ReserveDomainFetch reserves the next fetch slot of the host, spacing fetches by the delay across every
+// concurrent scraper. It returns how long to wait until the slot.
+func (r *Repository) ReserveDomainFetch(ctx context.Context, host string, delay time.Duration) (time.Duration, error) {
+	if r.dbClient == nil {
+		return 0, fmt.Errorf("dbClient not initialised")
+	}
+
+	var waitMs int64
+
+	err := r.dbClient.GetContext(
+		ctx,
+		&waitMs,
+		`
+			INSERT INTO domain_fetch (host, next_fetch_at)
+			VALUES ($1, now() + $2::float8 * interval '1 second')
+			ON CONFLICT (host) DO UPDATE
+			SET next_fetch_at = GREATEST(domain_fetch.next_fetch_at, now()) + $2::float8 * interval '1 second'
+			RETURNING (GREATEST(extract(epoch FROM next_fetch_at - now()) - $2::float8, 0) * 1000)::bigint
+		`, host, delay.Seconds(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve domain fetch: %w", err)
+	}
+
+	return time.Duration(waitMs) * time.Millisecond, nil
+}
+
 func (r *Repository) GetQueryItemsFromUrl(ctx context.Context, queryJobID uuid.UUID, url string) (*[]types.QueryItem, error) {
 	if r.dbClient == nil {
 		return nil, fmt.Errorf("dbClient not initialised")
@@ -281,7 +462,7 @@ func (r *Repository) GetQueryItemsFromUrl(ctx context.Context, queryJobID uuid.U
 		ctx,
 		&queryItems,
 		`
-			SELECT *
+			SELECT `+queryItemColumns+`
 			FROM query_item
 			WHERE query_job_id = $1 AND url = $2
 		`, queryJobID, url,
@@ -293,46 +474,128 @@ func (r *Repository) GetQueryItemsFromUrl(ctx context.Context, queryJobID uuid.U
 	return &queryItems, nil
 }
 
-func (r *Repository) SetQueryItemsProcessedWithBodyAndTitle(ctx context.Context, queryJobID uuid.UUID, queryItemIDs []uuid.UUID, body string, title string) error {
+// SetQueryItemsProcessedWithPage marks the query items as crawled into the page, along with the page title
+func (r *Repository) SetQueryItemsProcessedWithPage(ctx context.Context, queryJobID uuid.UUID, queryItemIDs []uuid.UUID, pageID uuid.UUID, title string) error {
 	if r.dbClient == nil {
 		return fmt.Errorf("dbClient not initialised")
 	}
 
-	// Remove non UTF8
-	body = strings.ToValidUTF8(body, "")
-
 	_, err := r.dbClient.ExecContext(
 		ctx,
 		`
 			UPDATE query_item
-			SET processed_at = now(), error_processing = false, body = $3, title = $4
+			SET processed_at = now(), error_processing = false, page_id = $3, title = $4
 			WHERE query_job_id = $1 and id = any($2)
-		`, queryJobID, pq.Array(queryItemIDs), body, title,
+		`, queryJobID, pq.Array(queryItemIDs), pageID, title,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to update query item error processing true with body: %w", err)
+		return fmt.Errorf("failed to update query items processed with page: %w", err)
 	}
 
 	return nil
 }
 
-func (r *Repository) CreateQueryLink(ctx context.Context, queryItemID uuid.UUID, text, url string) error {
+// GetFreshPage returns the latest page snapshot stored for the normalized url if it was scraped since the given
+// time, or nil if there's none. Pages without extracted headings aren't returned, the keyword usage of the
+// query items crawled into them would be missing the ones in headings.
+func (r *Repository) GetFreshPage(ctx context.Context, url string, since time.Time) (*types.Page, error) {
 	if r.dbClient == nil {
-		return fmt.Errorf("dbClient not initialised")
+		return nil, fmt.Errorf("dbClient not initialised")
 	}
 
-	_, err := r.dbClient.ExecContext(
+	page := types.Page{}
+
+	err := r.dbClient.GetContext(
 		ctx,
+		&page,
 		`
-			INSERT INTO link (query_item_id, text, url)
-			VALUES ($1, $2, $3)
-		`, queryItemID, text, url,
+			SELECT id, url, title, description, h1, scraped_at, created_at
+			FROM page
+			WHERE url = $1 AND scraped_at >= $2 AND h1 IS NOT NULL
+			ORDER BY scraped_at DESC
+			LIMIT 1
+		`, url, since,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create link: %w, %s, %s", err, text, url)
+		return nil, fmt.Errorf("failed to get fresh page: %w", err)
 	}
 
-	return nil
+	return &page, nil
+}
+
+// SavePage stores a snapshot of the scraped content and links of the normalized url. Snapshots are never
+// updated, so query items keep the content they were crawled with when the url is scraped again. The links are
+// copied in bulk within the same transaction.
+func (r *Repository) SavePage(ctx context.Context, url, title, description, body, mainContent string, h1 []string, links []types.Link) (uuid.UUID, error) {
+	if r.dbClient == nil {
+		return uuid.Nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	var id uuid.UUID
+
+	err := r.dbClient.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		err := tx.GetContext(
+			ctx,
+			&id,
+			`
+				INSERT INTO page (url, title, description, body, main_content, h1, search_vector)
+				VALUES ($1, $2, $3, $4, $5, $7, setweight(to_tsvector('english', $2), 'A') || setweight(to_tsvector('english', left($4, $6)), 'B'))
+				RETURNING id
+			`, url, sanitizeText(title), sanitizeText(description), sanitizeText(body), sanitizeText(mainContent), searchBodyMaxLength, pq.Array(sanitizeTexts(h1)),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save page: %w", err)
+		}
+
+		stmt, err := tx.PrepareContext(ctx, pq.CopyIn("page_link", "page_id", "text", "url"))
+		if err != nil {
+			return fmt.Errorf("failed to prepare page links copy: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, link := range links {
+			_, err = stmt.ExecContext(ctx, id, sanitizeText(link.Text), sanitizeText(link.URL))
+			if err != nil {
+				return fmt.Errorf("failed to copy page link: %w", err)
+			}
+		}
+
+		// flush the buffered rows
+		_, err = stmt.ExecContext(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to copy page links: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return id, nil
+}
+
+// highlightSnippet HTML escapes the snippet and wraps the matches ts_headline delimited with sentinels in <mark>
+// tags, so markup in the page text can't get through unescaped
+func highlightSnippet(snippet string) string {
+	return strings.NewReplacer(snippetStartSel, "<mark>", snippetStopSel, "</mark>").Replace(html.EscapeString(snippet))
+}
+
+// sanitizeText removes the invalid UTF8 and NUL characters Postgres rejects in text columns
+func sanitizeText(text string) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(text, ""), "\x00", "")
+}
+
+func sanitizeTexts(texts []string) []string {
+	sanitized := make([]string, len(texts))
+	for i, text := range texts {
+		sanitized[i] = sanitizeText(text)
+	}
+
+	return sanitized
 }
 
 func (r *Repository) GetUnprocessedQueryItemsCount(ctx context.Context, queryJobID uuid.UUID) (int, error) {
@@ -358,24 +621,83 @@ func (r *Repository) GetUnprocessedQueryItemsCount(ctx context.Context, queryJob
 	return count, nil
 }
 
-func (r *Repository) MarkQueryJobAsComplete(ctx context.Context, queryJobID uuid.UUID) error {
+// MarkQueryJobAsComplete marks the query job as complete, returns false if it was already complete
+func (r *Repository) MarkQueryJobAsComplete(ctx context.Context, queryJobID uuid.UUID) (bool, error) {
 	if r.dbClient == nil {
-		return fmt.Errorf("dbClient not initialised")
+		return false, fmt.Errorf("dbClient not initialised")
 	}
 
-	_, err := r.dbClient.ExecContext(
+	res, err := r.dbClient.ExecContext(
 		ctx,
 		`
 			UPDATE query_job
 			SET completed_at = now()
-			WHERE id = $1
+			WHERE id = $1 AND completed_at IS NULL
 		`, queryJobID,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to mark query job (%s) as complete: %w", queryJobID.String(), err)
+		return false, fmt.Errorf("failed to mark query job (%s) as complete: %w", queryJobID.String(), err)
 	}
 
-	return nil
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get marked query job (%s) rows affected: %w", queryJobID.String(), err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// MarkQueryJobRecrawlAsComplete marks the recrawl of the query job as complete unless there's none in progress,
+// returning whether it was marked
+func (r *Repository) MarkQueryJobRecrawlAsComplete(ctx context.Context, queryJobID uuid.UUID) (bool, error) {
+	if r.dbClient == nil {
+		return false, fmt.Errorf("dbClient not initialised")
+	}
+
+	res, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			UPDATE query_job
+			SET recrawl_completed_at = now()
+			WHERE id = $1 AND recrawl_started_at IS NOT NULL AND recrawl_completed_at IS NULL
+		`, queryJobID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark query job (%s) recrawl as complete: %w", queryJobID.String(), err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get marked query job (%s) recrawl rows affected: %w", queryJobID.String(), err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// MarkQueryJobAsFailed marks the query job as failed unless it already completed or failed, returning whether it was marked
+func (r *Repository) MarkQueryJobAsFailed(ctx context.Context, queryJobID uuid.UUID, reason string) (bool, error) {
+	if r.dbClient == nil {
+		return false, fmt.Errorf("dbClient not initialised")
+	}
+
+	res, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			UPDATE query_job
+			SET failed_at = now(), failure_reason = $2
+			WHERE id = $1 AND completed_at IS NULL AND failed_at IS NULL
+		`, queryJobID, reason,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark query job (%s) as failed: %w", queryJobID.String(), err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get failed query job (%s) rows affected: %w", queryJobID.String(), err)
+	}
+
+	return rowsAffected > 0, nil
 }
 
 func (r *Repository) GetQueryJobs(ctx context.Context) (*[]types.QueryJob, error) {
@@ -407,14 +729,7 @@ func (r *Repository) GetQueryJobPositionHits(ctx context.Context, queryJobID uui
 	err := r.dbClient.SelectContext(
 		ctx,
 		&positionHits,
-		`
-			SELECT AVG(position)::numeric(10,2) as avg_position, url, count(*) as location_hits_count
-			FROM query_item
-			WHERE query_job_id = $1
-			GROUP BY query_job_id, url
-			HAVING count(*) >= 3
-			ORDER BY AVG(position) ASC
-		`,
+		queryJobPositionHitsQuery,
 		queryJobID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get query job position hits: %v", err)
@@ -434,9 +749,10 @@ func (r *Repository) GetQueryItemLinks(ctx context.Context, queryItemID uuid.UUI
 		ctx,
 		&links,
 		`
-			SELECT text, url
-			FROM link
-			WHERE query_item_id = $1
+			SELECT pl.text, pl.url
+			FROM query_item qi
+			INNER JOIN page_link pl ON pl.page_id = qi.page_id
+			WHERE qi.id = $1
 		`,
 		queryItemID)
 	if err != nil {
@@ -458,3 +774,1333 @@ func (r *Repository) DeleteQueryJob(ctx context.Context, queryJobID uuid.UUID) e
 
 	return nil
 }
+
+// SettleZenserpUsage replaces the searches reserved for the query job when it was created by the ones it
+// actually made, which differ when cached results expired or got cached meanwhile
+func (r *Repository) SettleZenserpUsage(ctx context.Context, queryJobID uuid.UUID, userID *string, requestCount int) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			WITH settled AS (
+				UPDATE zenserp_usage
+				SET request_count = $3
+				WHERE query_job_id = $1
+				RETURNING id
+			), usage AS (
+				INSERT INTO zenserp_usage (query_job_id, user_id, request_count)
+				SELECT $1, $2, $3
+				WHERE NOT EXISTS (SELECT 1 FROM settled)
+			)
+			UPDATE query_job
+			SET serp_request_count = $3
+			WHERE id = $1
+		`, queryJobID, userID, requestCount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to settle zenserp usage for query job (%s): %w", queryJobID.String(), err)
+	}
+
+	return nil
+}
+
+func (r *Repository) GetZenserpUsageCountSince(ctx context.Context, since time.Time) (int, error) {
+	if r.dbClient == nil {
+		return 0, fmt.Errorf("dbClient not initialised")
+	}
+
+	var count int
+
+	err := r.dbClient.GetContext(
+		ctx,
+		&count,
+		`
+			SELECT COALESCE(SUM(request_count), 0)
+			FROM zenserp_usage
+			WHERE created_at >= $1
+		`, since,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get zenserp usage count: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *Repository) GetZenserpUsageByUserSince(ctx context.Context, since time.Time) (*[]types.ZenserpUserUsage, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	usages := []types.ZenserpUserUsage{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&usages,
+		`
+			SELECT user_id, SUM(request_count) as request_count
+			FROM zenserp_usage
+			WHERE created_at >= $1
+			GROUP BY user_id
+			ORDER BY SUM(request_count) DESC
+		`, since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get zenserp usage by user: %w", err)
+	}
+
+	return &usages, nil
+}
+
+// GetSerpCacheHit returns the most recent query location stored for the cache key since the given time,
+// or nil if there's none.
+func (r *Repository) GetSerpCacheHit(ctx context.Context, cacheKey string, since time.Time) (*types.QueryLocation, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	queryLocation := types.QueryLocation{}
+
+	err := r.dbClient.GetContext(
+		ctx,
+		&queryLocation,
+		`
+			SELECT ql.*
+			FROM serp_cache sc
+			INNER JOIN query_location ql ON ql.id = sc.query_location_id
+			WHERE sc.cache_key = $1 AND sc.created_at >= $2
+			ORDER BY sc.created_at DESC
+			LIMIT 1
+		`, cacheKey, since,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get serp cache hit: %w", err)
+	}
+
+	return &queryLocation, nil
+}
+
+func (r *Repository) CreateSerpCacheEntry(ctx context.Context, cacheKey string, queryLocationID uuid.UUID) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			INSERT INTO serp_cache (cache_key, query_location_id)
+			VALUES ($1, $2)
+		`, cacheKey, queryLocationID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create serp cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// CopyCachedQueryItems copies the query items of the source query location into the query location
+// of the query job, and marks that query location as cached.
+func (r *Repository) CopyCachedQueryItems(ctx context.Context, queryJobID, queryLocationID, sourceQueryLocationID uuid.UUID) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			WITH cached_location AS (
+				UPDATE query_location
+				SET cached = true
+				WHERE id = $2
+			)
+			INSERT INTO query_item (query_job_id, query_location_id, position, url, title)
+			SELECT $1, $2, position, url, title
+			FROM query_item
+			WHERE query_location_id = $3
+		`, queryJobID, queryLocationID, sourceQueryLocationID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to copy cached query items from query location (%s): %w", sourceQueryLocationID.String(), err)
+	}
+
+	return nil
+}
+
+func (r *Repository) GetQueryJobURLs(ctx context.Context, queryJobID uuid.UUID) ([]string, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	urls := []string{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&urls,
+		`
+			SELECT DISTINCT url
+			FROM query_item
+			WHERE query_job_id = $1
+		`, queryJobID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query job urls: %w", err)
+	}
+
+	return urls, nil
+}
+
+func (r *Repository) GetQueryJobLocationRankings(ctx context.Context, queryJobID uuid.UUID) (*[]types.LocationRanking, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	rankings := []types.LocationRanking{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&rankings,
+		`
+			SELECT qi.query_job_id, qi.query_location_id, ql.device, ql.location, qi.position, qi.url, qi.title
+			FROM query_item qi
+			INNER JOIN query_location ql ON ql.id = qi.query_location_id
+			WHERE qi.query_job_id = $1
+			ORDER BY ql.location, ql.device, qi.position
+		`, queryJobID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query job location rankings: %w", err)
+	}
+
+	return &rankings, nil
+}
+
+func (r *Repository) GetQueryJobsByIDs(ctx context.Context, queryJobIDs []uuid.UUID) (*[]types.QueryJob, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	queryJobs := []types.QueryJob{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&queryJobs,
+		`SELECT * FROM query_job WHERE id = any($1) ORDER BY created_at`, pq.Array(queryJobIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query jobs: %w", err)
+	}
+
+	return &queryJobs, nil
+}
+
+func (r *Repository) GetLocationRankingsForQueryJobs(ctx context.Context, queryJobIDs []uuid.UUID) (*[]types.LocationRanking, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	rankings := []types.LocationRanking{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&rankings,
+		`
+			SELECT qi.query_job_id, qi.query_location_id, ql.device, ql.location, qi.position, qi.url, qi.title
+			FROM query_item qi
+			INNER JOIN query_location ql ON ql.id = qi.query_location_id
+			WHERE qi.query_job_id = any($1)
+			ORDER BY qi.query_job_id, ql.location, ql.device, qi.position
+		`, pq.Array(queryJobIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get location rankings of query jobs: %w", err)
+	}
+
+	return &rankings, nil
+}
+
+// GetQueryJobURLContents returns the title and body length of every successfully crawled url of the query job.
+// The length is the one of the main content of the pages unless the full body is asked.
+func (r *Repository) GetQueryJobURLContents(ctx context.Context, queryJobID uuid.UUID, fullBody bool) (*[]types.URLContent, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	contents := []types.URLContent{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&contents,
+		`
+			SELECT DISTINCT ON (qi.url) qi.url, qi.title,
+				length(CASE WHEN $2 THEN p.body ELSE COALESCE(p.main_content, p.body) END) as body_length
+			FROM query_item qi
+			INNER JOIN page p ON p.id = qi.page_id
+			WHERE qi.query_job_id = $1 AND qi.error_processing = false
+			ORDER BY qi.url
+		`, queryJobID, fullBody,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query job url contents: %w", err)
+	}
+
+	return &contents, nil
+}
+
+// GetLatestPageSnapshot returns the most recent snapshot of the url, or nil if it was never crawled before
+func (r *Repository) GetLatestPageSnapshot(ctx context.Context, url string) (*types.PageSnapshot, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	pageSnapshot := types.PageSnapshot{}
+
+	err := r.dbClient.GetContext(
+		ctx,
+		&pageSnapshot,
+		`
+			SELECT *
+			FROM page_snapshot
+			WHERE url = $1
+			ORDER BY first_seen_at DESC
+			LIMIT 1
+		`, url,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest page snapshot: %w", err)
+	}
+
+	return &pageSnapshot, nil
+}
+
+func (r *Repository) CreatePageSnapshot(ctx context.Context, queryJobID uuid.UUID, url, contentHash, title, content string, wordCount int) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			INSERT INTO page_snapshot (query_job_id, url, content_hash, title, content, word_count)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, queryJobID, url, contentHash, title, content, wordCount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create page snapshot: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) TouchPageSnapshot(ctx context.Context, id uuid.UUID) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			UPDATE page_snapshot
+			SET last_seen_at = now()
+			WHERE id = $1
+		`, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update page snapshot last seen at: %w", err)
+	}
+
+	return nil
+}
+
+// GetChangedPageSnapshots returns the latest two snapshots of the urls whose content changed since the given time,
+// most recently changed first. When queryJobID is set, only urls ranking in that query job are returned.
+func (r *Repository) GetChangedPageSnapshots(ctx context.Context, since time.Time, queryJobID *uuid.UUID, limit int) (*[]types.PageSnapshot, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	pageSnapshots := []types.PageSnapshot{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&pageSnapshots,
+		`
+			WITH changed_url AS (
+				SELECT url, MAX(first_seen_at) as changed_at
+				FROM page_snapshot
+				WHERE $2::uuid IS NULL OR url IN (SELECT url FROM query_item WHERE query_job_id = $2)
+				GROUP BY url
+				HAVING COUNT(*) > 1 AND MAX(first_seen_at) >= $1
+				ORDER BY MAX(first_seen_at) DESC
+				LIMIT $3
+			)
+			SELECT id, url, query_job_id, content_hash, title, content, word_count, first_seen_at, last_seen_at
+			FROM (
+				SELECT ps.*, cu.changed_at, row_number() OVER (PARTITION BY ps.url ORDER BY ps.first_seen_at DESC) as rank
+				FROM page_snapshot ps
+				INNER JOIN changed_url cu ON cu.url = ps.url
+			) ranked
+			WHERE rank <= 2
+			ORDER BY changed_at DESC, url, first_seen_at DESC
+		`, since, queryJobID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get changed page snapshots: %w", err)
+	}
+
+	return &pageSnapshots, nil
+}
+
+func (r *Repository) CreateWatchlist(ctx context.Context, name string, domains []string, webhookURL *string) (uuid.UUID, error) {
+	if r.dbClient == nil {
+		return uuid.Nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	var id uuid.UUID
+
+	err := r.dbClient.GetContext(
+		ctx,
+		&id,
+		`
+			INSERT INTO watchlist (name, domains, webhook_url)
+			VALUES ($1, $2, $3)
+			RETURNING id
+		`, name, pq.Array(domains), webhookURL,
+	)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to insert watchlist: %w", err)
+	}
+
+	return id, nil
+}
+
+func (r *Repository) GetWatchlist(ctx context.Context, id uuid.UUID) (*types.Watchlist, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	watchlist := types.Watchlist{}
+
+	err := r.dbClient.GetContext(
+		ctx,
+		&watchlist,
+		`SELECT * FROM watchlist WHERE id = $1`, id,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchlist: %w", err)
+	}
+
+	return &watchlist, nil
+}
+
+func (r *Repository) GetWatchlists(ctx context.Context) (*[]types.Watchlist, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	watchlists := []types.Watchlist{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&watchlists,
+		`SELECT * FROM watchlist ORDER BY created_at DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchlists: %w", err)
+	}
+
+	return &watchlists, nil
+}
+
+func (r *Repository) DeleteWatchlist(ctx context.Context, id uuid.UUID) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(ctx, `DELETE FROM watchlist WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete watchlist: %w", err)
+	}
+
+	return nil
+}
+
+// GetLatestCompletedQueryJobs returns the last perKeyword completed query jobs of every keyword, ordered by
+// completion time. Keywords are compared case and whitespace insensitive.
+func (r *Repository) GetLatestCompletedQueryJobs(ctx context.Context, perKeyword int) (*[]types.QueryJob, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	queryJobs := []types.QueryJob{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&queryJobs,
+		`
+			SELECT qj.*
+			FROM query_job qj
+			INNER JOIN (
+				SELECT id, row_number() OVER (
+					PARTITION BY lower(regexp_replace(trim(keyword), '\s+', ' ', 'g'))
+					ORDER BY completed_at DESC
+				) AS rank
+				FROM query_job
+				WHERE completed_at IS NOT NULL
+			) latest ON latest.id = qj.id
+			WHERE latest.rank <= $1
+			ORDER BY qj.completed_at
+		`, perKeyword,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest completed query jobs: %w", err)
+	}
+
+	return &queryJobs, nil
+}
+
+// GetPreviousCompletedQueryJob returns the query job for the same keyword completed before the given one,
+// or nil if there's none. Keywords are compared case and whitespace insensitive.
+func (r *Repository) GetPreviousCompletedQueryJob(ctx context.Context, queryJob types.QueryJob) (*types.QueryJob, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	previous := types.QueryJob{}
+
+	err := r.dbClient.GetContext(
+		ctx,
+		&previous,
+		`
+			SELECT *
+			FROM query_job
+			WHERE lower(regexp_replace(trim(keyword), '\s+', ' ', 'g')) = lower(regexp_replace(trim($1), '\s+', ' ', 'g'))
+			AND completed_at IS NOT NULL AND completed_at < $2 AND id <> $3
+			ORDER BY completed_at DESC
+			LIMIT 1
+		`, queryJob.Keyword, queryJob.CompletedAt, queryJob.ID,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previous completed query job: %w", err)
+	}
+
+	return &previous, nil
+}
+
+// GetDomainBestPositions returns the best position of each domain (or its subdomains) in every given query job
+func (r *Repository) GetDomainBestPositions(ctx context.Context, queryJobIDs []uuid.UUID, domains []string) (*[]types.DomainBestPosition, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	positions := []types.DomainBestPosition{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&positions,
+		`
+			SELECT qi.query_job_id, d.domain, MIN(qi.position) as best_position
+			FROM query_item qi
+			CROSS JOIN LATERAL (
+				SELECT regexp_replace(lower(substring(qi.url from '^[a-zA-Z]+://([^/:?#]+)')), '^www\.', '') as host
+			) h
+			INNER JOIN unnest($2::text[]) as d(domain) ON h.host = d.domain OR right(h.host, length(d.domain) + 1) = '.' || d.domain
+			WHERE qi.query_job_id = any($1)
+			GROUP BY qi.query_job_id, d.domain
+		`, pq.Array(queryJobIDs), pq.Array(domains),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domain best positions: %w", err)
+	}
+
+	return &positions, nil
+}
+
+// CreateWatchlistAlert stores the alert and returns whether it's new, an alert already stored for the same query
+// job is skipped
+func (r *Repository) CreateWatchlistAlert(ctx context.Context, alert types.WatchlistAlert) (bool, error) {
+	if r.dbClient == nil {
+		return false, fmt.Errorf("dbClient not initialised")
+	}
+
+	res, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			INSERT INTO watchlist_alert (watchlist_id, query_job_id, keyword, domain, alert_type, position, previous_position)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (watchlist_id, query_job_id, domain, alert_type) DO NOTHING
+		`, alert.WatchlistID, alert.QueryJobID, alert.Keyword, alert.Domain, alert.AlertType, alert.Position, alert.PreviousPosition,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to create watchlist alert: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get created watchlist alert rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *Repository) GetWatchlistAlerts(ctx context.Context, watchlistID uuid.UUID, limit int) (*[]types.WatchlistAlert, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	alerts := []types.WatchlistAlert{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&alerts,
+		`
+			SELECT *
+			FROM watchlist_alert
+			WHERE watchlist_id = $1
+			ORDER BY created_at DESC
+			LIMIT $2
+		`, watchlistID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchlist alerts: %w", err)
+	}
+
+	return &alerts, nil
+}
+
+func (r *Repository) SetQueryLocationTargetRanking(ctx context.Context, targetRanking types.TargetRanking) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			UPDATE query_location
+			SET target_position = $2, target_url = $3, target_urls = $4, target_cannibalized = $5
+			WHERE id = $1
+		`, targetRanking.QueryLocationID, targetRanking.Position, targetRanking.URL, pq.Array(targetRanking.URLs), targetRanking.Cannibalized,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set target ranking of query location (%s): %w", targetRanking.QueryLocationID.String(), err)
+	}
+
+	return nil
+}
+
+func (r *Repository) SetQueryJobTargetSummary(ctx context.Context, queryJobID uuid.UUID, summary types.TargetSummary) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			UPDATE query_job
+			SET target_best_position = $2, target_urls = $3, target_cannibalized = $4
+			WHERE id = $1
+		`, queryJobID, summary.BestPosition, pq.Array(summary.URLs), summary.Cannibalized,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set target summary of query job (%s): %w", queryJobID.String(), err)
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateWebhookEndpoint(ctx context.Context, url, secret string, eventTypes []string) (uuid.UUID, error) {
+	if r.dbClient == nil {
+		return uuid.Nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	var id uuid.UUID
+
+	err := r.dbClient.GetContext(
+		ctx,
+		&id,
+		`
+			INSERT INTO webhook_endpoint (url, secret, event_types)
+			VALUES ($1, $2, $3)
+			RETURNING id
+		`, url, secret, pq.Array(eventTypes),
+	)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to insert webhook endpoint: %w", err)
+	}
+
+	return id, nil
+}
+
+func (r *Repository) GetWebhookEndpoint(ctx context.Context, id uuid.UUID) (*types.WebhookEndpoint, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	webhookEndpoint := types.WebhookEndpoint{}
+
+	err := r.dbClient.GetContext(
+		ctx,
+		&webhookEndpoint,
+		`SELECT * FROM webhook_endpoint WHERE id = $1`, id,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+
+	return &webhookEndpoint, nil
+}
+
+func (r *Repository) GetWebhookEndpoints(ctx context.Context) (*[]types.WebhookEndpoint, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	webhookEndpoints := []types.WebhookEndpoint{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&webhookEndpoints,
+		`SELECT * FROM webhook_endpoint ORDER BY created_at DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoints: %w", err)
+	}
+
+	return &webhookEndpoints, nil
+}
+
+// GetWebhookEndpointsForEvent returns the webhook endpoints subscribed to the event type, endpoints without
+// event types are subscribed to every event
+func (r *Repository) GetWebhookEndpointsForEvent(ctx context.Context, eventType string) (*[]types.WebhookEndpoint, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	webhookEndpoints := []types.WebhookEndpoint{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&webhookEndpoints,
+		`
+			SELECT *
+			FROM webhook_endpoint
+			WHERE cardinality(event_types) = 0 OR $1 = any(event_types)
+			ORDER BY created_at
+		`, eventType,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoints for event (%s): %w", eventType, err)
+	}
+
+	return &webhookEndpoints, nil
+}
+
+func (r *Repository) DeleteWebhookEndpoint(ctx context.Context, id uuid.UUID) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(ctx, `DELETE FROM webhook_endpoint WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateWebhookDelivery(ctx context.Context, webhookEndpointID uuid.UUID, eventType string, queryJobID *uuid.UUID, payload string) (*types.WebhookDelivery, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	delivery := types.WebhookDelivery{}
+
+	err := r.dbClient.GetContext(
+		ctx,
+		&delivery,
+		`
+			INSERT INTO webhook_delivery (webhook_endpoint_id, event_type, query_job_id, payload)
+			VALUES ($1, $2, $3, $4)
+			RETURNING *
+		`, webhookEndpointID, eventType, queryJobID, payload,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert webhook delivery: %w", err)
+	}
+
+	return &delivery, nil
+}
+
+func (r *Repository) UpdateWebhookDelivery(ctx context.Context, delivery types.WebhookDelivery) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			UPDATE webhook_delivery
+			SET status = $2, attempts = $3, response_status = $4, error = $5, delivered_at = $6
+			WHERE id = $1
+		`, delivery.ID, delivery.Status, delivery.Attempts, delivery.ResponseStatus, delivery.Error, delivery.DeliveredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery (%s): %w", delivery.ID.String(), err)
+	}
+
+	return nil
+}
+
+func (r *Repository) GetWebhookDeliveries(ctx context.Context, webhookEndpointID uuid.UUID, limit int) (*[]types.WebhookDelivery, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	deliveries := []types.WebhookDelivery{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&deliveries,
+		`
+			SELECT *
+			FROM webhook_delivery
+			WHERE webhook_endpoint_id = $1
+			ORDER BY created_at DESC
+			LIMIT $2
+		`, webhookEndpointID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+
+	return &deliveries, nil
+}
+
+// StreamQueryJobPositionHits calls fn for every position hit of the query job without loading them all in memory
+func (r *Repository) StreamQueryJobPositionHits(ctx context.Context, queryJobID uuid.UUID, fn func(types.QueryJobPositionHit) error) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	rows, err := r.dbClient.QueryxContext(ctx, queryJobPositionHitsQuery, queryJobID)
+	if err != nil {
+		return fmt.Errorf("failed to query query job position hits: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var positionHit types.QueryJobPositionHit
+		if err := rows.StructScan(&positionHit); err != nil {
+			return fmt.Errorf("failed to scan query job position hit: %w", err)
+		}
+
+		if err := fn(positionHit); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// StreamQueryJobRankings calls fn for every query item of the query job along with its location, without
+// loading them all in memory
+func (r *Repository) StreamQueryJobRankings(ctx context.Context, queryJobID uuid.UUID, fn func(types.ExportRanking) error) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	rows, err := r.dbClient.QueryxContext(
+		ctx,
+		`
+			SELECT qi.query_location_id, ql.device, ql.country, ql.location, ql.cached, qi.position, qi.url, qi.title
+			FROM query_item qi
+			INNER JOIN query_location ql ON ql.id = qi.query_location_id
+			WHERE qi.query_job_id = $1
+			ORDER BY ql.device, ql.location, qi.position
+		`, queryJobID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query query job rankings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ranking types.ExportRanking
+		if err := rows.StructScan(&ranking); err != nil {
+			return fmt.Errorf("failed to scan query job ranking: %w", err)
+		}
+
+		if err := fn(ranking); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// StreamQueryJobURLStatuses calls fn with the crawl status and link count of every URL of the query job,
+// without loading them all in memory
+func (r *Repository) StreamQueryJobURLStatuses(ctx context.Context, queryJobID uuid.UUID, fn func(types.ExportURLStatus) error) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	rows, err := r.dbClient.QueryxContext(
+		ctx,
+		`
+			SELECT
+				qi.url,
+				MAX(qi.title) as title,
+				CASE
+					WHEN bool_or(qi.blocked) THEN 'blocked'
+					WHEN bool_or(qi.error_processing) THEN 'error'
+					WHEN bool_and(qi.processed_at IS NOT NULL) THEN 'processed'
+					ELSE 'pending'
+				END as crawl_status,
+				MAX(qi.processed_at) as processed_at,
+				COALESCE(MAX(lc.link_count), 0) as link_count
+			FROM query_item qi
+			LEFT JOIN LATERAL (
+				SELECT count(*) as link_count FROM page_link pl WHERE pl.page_id = qi.page_id
+			) lc ON true
+			WHERE qi.query_job_id = $1
+			GROUP BY qi.url
+			ORDER BY qi.url
+		`, queryJobID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query query job url statuses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var urlStatus types.ExportURLStatus
+		if err := rows.StructScan(&urlStatus); err != nil {
+			return fmt.Errorf("failed to scan query job url status: %w", err)
+		}
+
+		if err := fn(urlStatus); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// SearchQueryItems finds the crawled pages of every query job matching the phrase, best ranked first. Pages
+// ranking in several locations of a job are returned once with their best position.
+func (r *Repository) SearchQueryItems(ctx context.Context, phrase string, limit int) (*[]types.ContentSearchResult, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	results := []types.ContentSearchResult{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&results,
+		`
+			WITH query AS (
+				SELECT phraseto_tsquery('english', $1) AS q
+			), matched_pages AS (
+				SELECT p.id, p.body, ts_rank_cd(p.search_vector, query.q) AS rank
+				FROM page p, query
+				WHERE p.search_vector @@ query.q
+			), matches AS (
+				SELECT DISTINCT ON (qi.query_job_id, qi.url)
+					qi.query_job_id, qi.url, qi.title, mp.body, qi.position AS best_position,
+					count(*) OVER (PARTITION BY qi.query_job_id, qi.url) AS location_count,
+					mp.rank
+				FROM query_item qi
+				INNER JOIN matched_pages mp ON mp.id = qi.page_id
+				ORDER BY qi.query_job_id, qi.url, qi.position
+			), top_matches AS (
+				SELECT *
+				FROM matches
+				ORDER BY rank DESC, best_position, url
+				LIMIT $2
+			)
+			SELECT m.query_job_id, qj.keyword, m.url, m.title, m.best_position, m.location_count, m.rank,
+				ts_headline('english', translate(left(m.body, $3), $4, ''), query.q, $5) AS snippet
+			FROM top_matches m
+			INNER JOIN query_job qj ON qj.id = m.query_job_id
+			CROSS JOIN query
+			ORDER BY m.rank DESC, m.best_position, m.url
+		`, phrase, limit, searchBodyMaxLength,
+		snippetStartSel+snippetStopSel,
+		fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxFragments=2, MinWords=10, MaxWords=30, FragmentDelimiter=" ... "`, snippetStartSel, snippetStopSel),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search query items: %w", err)
+	}
+
+	for i := range results {
+		results[i].Snippet = highlightSnippet(results[i].Snippet)
+	}
+
+	return &results, nil
+}
+
+// SaveFetchDiagnostic stores the diagnostic of the last fetch of the url of the query job
+func (r *Repository) SaveFetchDiagnostic(ctx context.Context, d types.FetchDiagnostic) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			INSERT INTO fetch_diagnostic (
+				query_job_id, url, cached, status_code, final_url, redirects, response_time_ms, content_length,
+				content_type, error, failure_category, attempt, rendered
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			ON CONFLICT (query_job_id, url) DO UPDATE
+			SET cached = EXCLUDED.cached, status_code = EXCLUDED.status_code, final_url = EXCLUDED.final_url,
+				redirects = EXCLUDED.redirects, response_time_ms = EXCLUDED.response_time_ms,
+				content_length = EXCLUDED.content_length, content_type = EXCLUDED.content_type, error = EXCLUDED.error,
+				failure_category = EXCLUDED.failure_category, attempt = EXCLUDED.attempt, rendered = EXCLUDED.rendered,
+				fetched_at = now()
+		`,
+		d.QueryJobID, d.URL, d.Cached, d.StatusCode, d.FinalURL, d.Redirects, d.ResponseTimeMs, d.ContentLength,
+		d.ContentType, d.Error, d.FailureCategory, d.Attempt, d.Rendered,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save fetch diagnostic: %w", err)
+	}
+
+	return nil
+}
+
+// GetFetchDiagnostic returns the diagnostic of the last fetch of the url of the query job, or nil if it wasn't
+// fetched yet
+func (r *Repository) GetFetchDiagnostic(ctx context.Context, queryJobID uuid.UUID, url string) (*types.FetchDiagnostic, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	diagnostic := types.FetchDiagnostic{}
+
+	err := r.dbClient.GetContext(
+		ctx,
+		&diagnostic,
+		`SELECT * FROM fetch_diagnostic WHERE query_job_id = $1 AND url = $2`, queryJobID, url,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fetch diagnostic: %w", err)
+	}
+
+	return &diagnostic, nil
+}
+
+func (r *Repository) GetFetchDiagnostics(ctx context.Context, queryJobID uuid.UUID) (*[]types.FetchDiagnostic, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	diagnostics := []types.FetchDiagnostic{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&diagnostics,
+		`SELECT * FROM fetch_diagnostic WHERE query_job_id = $1 ORDER BY url`, queryJobID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fetch diagnostics: %w", err)
+	}
+
+	return &diagnostics, nil
+}
+
+// ScheduleScrapeRetry schedules the url of the query job to be scraped again after the delay, bypassing the
+// page cache when skipPageCache is set
+func (r *Repository) ScheduleScrapeRetry(ctx context.Context, queryJobID uuid.UUID, url string, attempt int, skipPageCache bool, delay time.Duration) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			INSERT INTO scrape_retry (query_job_id, url, attempt, skip_page_cache, retry_at)
+			VALUES ($1, $2, $3, $4, now() + $5::float8 * interval '1 second')
+			ON CONFLICT (query_job_id, url) DO UPDATE
+			SET attempt = EXCLUDED.attempt, skip_page_cache = EXCLUDED.skip_page_cache, retry_at = EXCLUDED.retry_at
+		`, queryJobID, url, attempt, skipPageCache, delay.Seconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to schedule scrape retry: %w", err)
+	}
+
+	return nil
+}
+
+// GetDueScrapeRetries returns the scrape retries due by now, oldest first
+func (r *Repository) GetDueScrapeRetries(ctx context.Context, limit int) (*[]types.ScrapeRetry, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	retries := []types.ScrapeRetry{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&retries,
+		`SELECT * FROM scrape_retry WHERE retry_at <= now() ORDER BY retry_at LIMIT $1`, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due scrape retries: %w", err)
+	}
+
+	return &retries, nil
+}
+
+// DeleteScrapeRetry deletes the scrape retry once queued, unless it was rescheduled for another attempt since
+func (r *Repository) DeleteScrapeRetry(ctx context.Context, retry types.ScrapeRetry) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(
+		ctx,
+		`DELETE FROM scrape_retry WHERE query_job_id = $1 AND url = $2 AND attempt = $3`,
+		retry.QueryJobID, retry.URL, retry.Attempt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete scrape retry: %w", err)
+	}
+
+	return nil
+}
+
+// ReopenQueryJobURLs marks the urls of the query job as unprocessed again, or all its errored urls when none
+// are given, and schedules scraping them again bypassing the page cache. A completed query job stays completed,
+// the recrawl is tracked separately and marked complete once the urls are processed. It returns the reopened
+// urls.
+func (r *Repository) ReopenQueryJobURLs(ctx context.Context, queryJobID uuid.UUID, urls []string) ([]string, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	if urls == nil {
+		urls = []string{}
+	}
+
+	reopened := []string{}
+
+	err := r.dbClient.WithTransaction(ctx, func(tx *sqlx.Tx) error {
+		err := tx.SelectContext(
+			ctx,
+			&reopened,
+			`
+				WITH reopened AS (
+					UPDATE query_item
+					SET processed_at = NULL, error_processing = false, blocked = false
+					WHERE query_job_id = $1 AND ((cardinality($2::text[]) = 0 AND error_processing) OR url = any($2))
+					RETURNING url
+				)
+				SELECT DISTINCT url FROM reopened ORDER BY url
+			`, queryJobID, pq.Array(urls),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to reopen query items: %w", err)
+		}
+
+		if len(reopened) == 0 {
+			return nil
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`
+				INSERT INTO scrape_retry (query_job_id, url, attempt, skip_page_cache, retry_at)
+				SELECT $1, url, 1, true, now()
+				FROM unnest($2::text[]) AS url
+				ON CONFLICT (query_job_id, url) DO UPDATE
+				SET attempt = 1, skip_page_cache = true, retry_at = now()
+			`, queryJobID, pq.Array(reopened),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to schedule recrawl: %w", err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`
+				UPDATE query_job
+				SET recrawl_started_at = now(), recrawl_completed_at = NULL
+				WHERE id = $1 AND completed_at IS NOT NULL
+			`, queryJobID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to start query job recrawl: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reopened, nil
+}
+
+// SaveContentMetrics stores the content metrics of the page, replacing the ones of its previous scrape
+func (r *Repository) SaveContentMetrics(ctx context.Context, m types.ContentMetrics) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			INSERT INTO content_metric (
+				page_id, word_count, paragraph_count, avg_sentence_length, flesch_reading_ease, heading_count,
+				image_count, internal_link_count, external_link_count, list_count, table_count
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			ON CONFLICT (page_id) DO UPDATE
+			SET word_count = EXCLUDED.word_count, paragraph_count = EXCLUDED.paragraph_count,
+				avg_sentence_length = EXCLUDED.avg_sentence_length, flesch_reading_ease = EXCLUDED.flesch_reading_ease,
+				heading_count = EXCLUDED.heading_count, image_count = EXCLUDED.image_count,
+				internal_link_count = EXCLUDED.internal_link_count, external_link_count = EXCLUDED.external_link_count,
+				list_count = EXCLUDED.list_count, table_count = EXCLUDED.table_count, computed_at = now()
+		`,
+		m.PageID, m.WordCount, m.ParagraphCount, m.AvgSentenceLength, m.FleschReadingEase, m.HeadingCount,
+		m.ImageCount, m.InternalLinkCount, m.ExternalLinkCount, m.ListCount, m.TableCount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save content metrics: %w", err)
+	}
+
+	return nil
+}
+
+// GetQueryJobContentMetrics returns the content metrics of every url crawled by the query job along with its
+// best position, urls which weren't crawled successfully are left out
+func (r *Repository) GetQueryJobContentMetrics(ctx context.Context, queryJobID uuid.UUID) (*[]types.URLContentMetrics, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	metrics := []types.URLContentMetrics{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&metrics,
+		`
+			SELECT DISTINCT ON (qi.url)
+				qi.url, MIN(qi.position) OVER (PARTITION BY qi.url) AS best_position, cm.*
+			FROM query_item qi
+			INNER JOIN content_metric cm ON cm.page_id = qi.page_id
+			WHERE qi.query_job_id = $1 AND qi.processed_at IS NOT NULL AND qi.error_processing = false
+			ORDER BY qi.url, qi.position
+		`, queryJobID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query job content metrics: %w", err)
+	}
+
+	return &metrics, nil
+}
+
+// GetPageMainContent returns the main content of the page, its body when the main content wasn't extracted
+func (r *Repository) GetPageMainContent(ctx context.Context, pageID uuid.UUID) (string, error) {
+	if r.dbClient == nil {
+		return "", fmt.Errorf("dbClient not initialised")
+	}
+
+	var mainContent string
+
+	err := r.dbClient.GetContext(
+		ctx,
+		&mainContent,
+		`SELECT COALESCE(main_content, body) FROM page WHERE id = $1`, pageID,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to get page main content: %w", err)
+	}
+
+	return mainContent, nil
+}
+
+// SaveKeywordUsage stores the keyword usage of the page of the query items, replacing the one of their previous
+// crawl
+func (r *Repository) SaveKeywordUsage(ctx context.Context, queryItemIDs []uuid.UUID, u types.KeywordUsage) error {
+	if r.dbClient == nil {
+		return fmt.Errorf("dbClient not initialised")
+	}
+
+	_, err := r.dbClient.ExecContext(
+		ctx,
+		`
+			INSERT INTO keyword_usage (
+				query_item_id, in_title, in_h1, in_url, in_description, in_first_100_words, exact_match_count,
+				partial_match_count, word_count, density
+			)
+			SELECT id, $2, $3, $4, $5, $6, $7, $8, $9, $10
+			FROM unnest($1::uuid[]) AS id
+			ON CONFLICT (query_item_id) DO UPDATE
+			SET in_title = EXCLUDED.in_title, in_h1 = EXCLUDED.in_h1, in_url = EXCLUDED.in_url,
+				in_description = EXCLUDED.in_description, in_first_100_words = EXCLUDED.in_first_100_words,
+				exact_match_count = EXCLUDED.exact_match_count, partial_match_count = EXCLUDED.partial_match_count,
+				word_count = EXCLUDED.word_count, density = EXCLUDED.density, computed_at = now()
+		`,
+		pq.Array(queryItemIDs), u.InTitle, u.InH1, u.InURL, u.InDescription, u.InFirst100Words, u.ExactMatchCount,
+		u.PartialMatchCount, u.WordCount, u.Density,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save keyword usage: %w", err)
+	}
+
+	return nil
+}
+
+// GetQueryJobKeywordUsage returns the keyword usage of every query item of the query job crawled successfully,
+// along with its position
+func (r *Repository) GetQueryJobKeywordUsage(ctx context.Context, queryJobID uuid.UUID) (*[]types.QueryItemKeywordUsage, error) {
+	if r.dbClient == nil {
+		return nil, fmt.Errorf("dbClient not initialised")
+	}
+
+	usages := []types.QueryItemKeywordUsage{}
+
+	err := r.dbClient.SelectContext(
+		ctx,
+		&usages,
+		`
+			SELECT qi.url, qi.position, ku.query_item_id, ku.in_title, ku.in_h1, ku.in_url, ku.in_description,
+				ku.in_first_100_words, ku.exact_match_count, ku.partial_match_count, ku.word_count, ku.density
+			FROM query_item qi
+			INNER JOIN keyword_usage ku ON ku.query_item_id = qi.id
+			WHERE qi.query_job_id = $1 AND qi.processed_at IS NOT NULL AND qi.error_processing = false
+			ORDER BY qi.position, qi.url
+		`, queryJobID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query job keyword usage: %w", err)
+	}
+
+	return &usages, nil
+}
@@ -4,13 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/gofrs/uuid"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/jponc/competitive-analysis/api/eventschema"
+	"github.com/jponc/competitive-analysis/internal/queryjobs"
 	"github.com/jponc/competitive-analysis/internal/repository/dbrepository"
+	"github.com/jponc/competitive-analysis/internal/serpanalysis"
+	"github.com/jponc/competitive-analysis/internal/types"
 	"github.com/jponc/competitive-analysis/pkg/sns"
 	"github.com/jponc/competitive-analysis/pkg/zenserp"
 )
@@ -19,13 +23,16 @@ type Service struct {
 	zenserpClient *zenserp.Client
 	repository    *dbrepository.Repository
 	snsClient     *sns.Client
+	serpCacheTTL  time.Duration
 }
 
-func NewService(zenserpClient *zenserp.Client, repository *dbrepository.Repository, snsClient *sns.Client) *Service {
+// NewService instantiates the result rankings service. A serpCacheTTL of 0 disables reusing cached SERP results.
+func NewService(zenserpClient *zenserp.Client, repository *dbrepository.Repository, snsClient *sns.Client, serpCacheTTL time.Duration) *Service {
 	s := &Service{
 		zenserpClient: zenserpClient,
 		repository:    repository,
 		snsClient:     snsClient,
+		serpCacheTTL:  serpCacheTTL,
 	}
 
 	return s
@@ -61,46 +68,100 @@ func (s *Service) QueryJobZenserp(ctx context.Context, snsEvent events.SNSEvent)
 		log.Fatalf("unable to convert query job string to UUID: %v", err)
 	}
 
+	err = s.queryJobZenserp(ctx, queryJobID)
+	if err != nil {
+		log.Errorf("failed to query zenserp for query job (%s): %v", queryJobID.String(), err)
+		s.failQueryJob(ctx, queryJobID, err.Error())
+	}
+
+	if err := s.repository.Close(); err != nil {
+		log.Fatalf("can't close DB connection")
+	}
+}
+
+// queryJobZenserp submits the searches of the query job to zenserp, reusing recent results of identical searches
+func (s *Service) queryJobZenserp(ctx context.Context, queryJobID uuid.UUID) error {
 	// Fetch query job
 	queryJob, err := s.repository.GetQueryJob(ctx, queryJobID)
 	if err != nil {
-		log.Fatalf("failed to get query job: %s, %v", queryJobID, err)
+		return fmt.Errorf("failed to get query job: %w", err)
 	}
 
 	// Fetch query locations
 	queryLocations, err := s.repository.GetQueryLocations(ctx, queryJobID)
 	if err != nil {
-		log.Fatalf("failed to get query locations of query job: %s", queryJobID)
+		return fmt.Errorf("failed to get query locations: %w", err)
 	}
 
-	// Convert query locations to a zenserp jobs
+	// Convert query locations to a zenserp jobs, reusing recent results of identical searches
 	var zenserpJobs []zenserp.Job
 	for _, queryLocation := range *queryLocations {
-		zenserpJobs = append(zenserpJobs, zenserp.Job{
-			Query:        queryJob.Keyword,
-			Num:          queryLocation.Num,
-			SearchEngine: queryLocation.SearchEngine,
-			Device:       queryLocation.Device,
-			Country:      queryLocation.Country,
-			Location:     queryLocation.Location,
-		})
+		zenserpJob := zenserpJobOf(queryJob.Keyword, queryLocation)
+
+		if s.serpCacheTTL > 0 {
+			cachedQueryLocation, err := s.repository.GetSerpCacheHit(ctx, zenserpJob.CacheKey(), time.Now().Add(-s.serpCacheTTL))
+			if err != nil {
+				return fmt.Errorf("failed to get serp cache hit: %w", err)
+			}
+
+			if cachedQueryLocation != nil {
+				err = s.repository.CopyCachedQueryItems(ctx, queryJobID, queryLocation.ID, cachedQueryLocation.ID)
+				if err != nil {
+					return fmt.Errorf("failed to copy cached query items: %w", err)
+				}
+
+				log.Infof("reused cached results of query location %s for location: %s", cachedQueryLocation.ID, queryLocation.Location)
+				continue
+			}
+		}
+
+		zenserpJobs = append(zenserpJobs, zenserpJob)
+	}
+
+	// Everything was cached, there's nothing to fetch from zenserp so we go straight to parsing the urls
+	if len(zenserpJobs) == 0 {
+		// Release the searches reserved when the query job was created
+		err = s.repository.SettleZenserpUsage(ctx, queryJobID, queryJob.UserID, 0)
+		if err != nil {
+			return fmt.Errorf("failed to settle zenserp usage: %w", err)
+		}
+
+		err = s.repository.ProcessQueryJob(ctx, queryJobID)
+		if err != nil {
+			return fmt.Errorf("failed to mark query job as processed: %w", err)
+		}
+
+		s.recordTargetRankings(ctx, *queryJob)
+		queryjobs.PublishWebhookEvent(ctx, s.snsClient, types.WebhookEventQueryJobSerpFetched, queryJobID)
+
+		return s.publishQueryJobURLs(ctx, queryJobID)
 	}
 
 	// Create zenserp batch
 	batchResult, err := s.zenserpClient.Batch(ctx, fmt.Sprintf("%s: %s", queryJob.ID, queryJob.Keyword), zenserpJobs)
 	if err != nil {
-		log.Fatalf("failed to create zenserp batch: %s", queryJobID)
+		// No searches were made, release the ones reserved when the query job was created
+		settleErr := s.repository.SettleZenserpUsage(ctx, queryJobID, queryJob.UserID, 0)
+		if settleErr != nil {
+			log.Errorf("failed to settle zenserp usage: %v", settleErr)
+		}
+
+		return fmt.Errorf("failed to create zenserp batch: %w", err)
 	}
 
 	// Set batch ID to query job
 	err = s.repository.SetZenserpBatchToQueryJob(ctx, queryJobID, batchResult.BatchID)
 	if err != nil {
-		log.Fatalf("failed to set zenserp batch ID to query job: %v", err)
+		return fmt.Errorf("failed to set zenserp batch ID to query job: %w", err)
 	}
 
-	if err := s.repository.Close(); err != nil {
-		log.Fatalf("can't close DB connection")
+	// Settle the searches reserved when the query job was created with the ones this batch consumes
+	err = s.repository.SettleZenserpUsage(ctx, queryJobID, queryJob.UserID, len(zenserpJobs))
+	if err != nil {
+		return fmt.Errorf("failed to settle zenserp usage: %w", err)
 	}
+
+	return nil
 }
 
 func (s *Service) ZenserpBatchExtractResults(ctx context.Context, snsEvent events.SNSEvent) {
@@ -134,43 +195,134 @@ func (s *Service) ZenserpBatchExtractResults(ctx context.Context, snsEvent event
 		log.Fatalf("unable to convert query job string to UUID: %v", err)
 	}
 
-	// Get QueryLocations so we can pull the ID later based on location
+	zenserpBatchID := msg.ZenserpBatchID
+
+	err = s.extractBatchResults(ctx, queryJobID, zenserpBatchID)
+	if err != nil {
+		log.Errorf("failed to extract zenserp batch results for query job (%s): %v", queryJobID.String(), err)
+		s.failQueryJob(ctx, queryJobID, err.Error())
+	}
+
+	if err := s.repository.Close(); err != nil {
+		log.Fatalf("can't close DB connection")
+	}
+
+	log.Infof("done creating query items: query job id: %s, batchID: %s", queryJobID, zenserpBatchID)
+}
+
+// extractBatchResults stores the results of the zenserp batch as the query items of the query job
+func (s *Service) extractBatchResults(ctx context.Context, queryJobID uuid.UUID, zenserpBatchID string) error {
+	// Get QueryJob and QueryLocations so we can pull the ID later based on location
+	queryJob, err := s.repository.GetQueryJob(ctx, queryJobID)
+	if err != nil {
+		return fmt.Errorf("unable to get query job: %w", err)
+	}
+
 	queryLocations, err := s.repository.GetQueryLocations(ctx, queryJobID)
 	if err != nil {
-		log.Fatalf("unable to get query locations of %s: %v", queryJobID, err)
+		return fmt.Errorf("unable to get query locations: %w", err)
 	}
 
 	// Get ZenserpBatch
-	zenserpBatchID := msg.ZenserpBatchID
 	batch, err := s.zenserpClient.GetBatch(ctx, zenserpBatchID)
+	if err != nil {
+		return fmt.Errorf("unable to get zenserp batch %s: %w", zenserpBatchID, err)
+	}
 
-	urls := map[string]bool{}
+	// Index the searches submitted in the batch by their parameters, cached query locations already have
+	// their query items
+	submittedQueryLocations := map[string]types.QueryLocation{}
+	for _, queryLocation := range *queryLocations {
+		if queryLocation.Cached {
+			continue
+		}
 
-	// Create QueryItems based on zenserp batch result and query location
+		submittedQueryLocations[zenserpJobOf(queryJob.Keyword, queryLocation).CacheKey()] = queryLocation
+	}
+
+	// Create QueryItems based on zenserp batch result and the query location it was submitted for
 	for _, result := range batch.Results {
-		for _, queryLocation := range *queryLocations {
-			// Found query location, create query items
-			if result.Query.Location == queryLocation.Location {
-				for _, resultItem := range result.ResulItems {
-					if resultItem.URL == "" {
-						// We don't want to process empty URL
-						continue
-					}
-
-					_, err := s.repository.CreateQueryItem(ctx, queryJobID, queryLocation.ID, resultItem.Position, resultItem.URL, resultItem.Title)
-					if err != nil {
-						log.Fatalf("unable to create query item for query job (%s) and query location (%s): %v", queryJobID.String(), queryLocation.ID.String(), err)
-					}
-
-					// Create a set of urls to be processed later
-					urls[resultItem.URL] = true
-				}
+		// Every search of the batch is for the keyword of the query job
+		resultJob := zenserp.Job{
+			Query:        queryJob.Keyword,
+			Num:          result.Query.Num,
+			SearchEngine: result.Query.SearchEngine,
+			Device:       result.Query.Device,
+			Country:      result.Query.Country,
+			Location:     result.Query.Location,
+		}
+		cacheKey := resultJob.CacheKey()
+
+		queryLocation, found := submittedQueryLocations[cacheKey]
+		if !found {
+			log.Warnf("skipping zenserp result not matching any submitted search of query job (%s): %s", queryJobID.String(), cacheKey)
+			continue
+		}
+
+		// A query location only gets the results of one search
+		delete(submittedQueryLocations, cacheKey)
+
+		for _, resultItem := range result.ResulItems {
+			if resultItem.URL == "" {
+				// We don't want to process empty URL
+				continue
+			}
+
+			_, err := s.repository.CreateQueryItem(ctx, queryJobID, queryLocation.ID, resultItem.Position, resultItem.URL, resultItem.Title)
+			if err != nil {
+				return fmt.Errorf("unable to create query item for query location (%s): %w", queryLocation.ID.String(), err)
 			}
 		}
+
+		// Make the stored results available to identical searches
+		err = s.repository.CreateSerpCacheEntry(ctx, cacheKey, queryLocation.ID)
+		if err != nil {
+			return fmt.Errorf("unable to create serp cache entry for query location (%s): %w", queryLocation.ID.String(), err)
+		}
+	}
+
+	for _, queryLocation := range submittedQueryLocations {
+		log.Warnf("no zenserp result for query location (%s) of query job (%s)", queryLocation.ID.String(), queryJobID.String())
+	}
+
+	s.recordTargetRankings(ctx, *queryJob)
+	queryjobs.PublishWebhookEvent(ctx, s.snsClient, types.WebhookEventQueryJobSerpFetched, queryJobID)
+
+	// Publish a message to extract results of every url, including the ones reused from the cache
+	return s.publishQueryJobURLs(ctx, queryJobID)
+}
+
+// publishQueryJobURLs publishes a ParseQueryJobURL message for every unique url of the query job
+func (s *Service) publishQueryJobURLs(ctx context.Context, queryJobID uuid.UUID) error {
+	urls, err := s.repository.GetQueryJobURLs(ctx, queryJobID)
+	if err != nil {
+		return fmt.Errorf("unable to get urls: %w", err)
 	}
 
-	// Iterate all urls generated earlier, then publish a message to extract results
-	for url := range urls {
+	// Nothing to parse, so nothing will ever mark the query job as complete
+	if len(urls) == 0 {
+		marked, err := s.repository.MarkQueryJobAsComplete(ctx, queryJobID)
+		if err != nil {
+			return fmt.Errorf("query job cannot be marked as complete: %w", err)
+		}
+
+		if marked {
+			msg := eventschema.QueryJobCompletedMessage{
+				QueryJobID: queryJobID.String(),
+			}
+
+			err = s.snsClient.Publish(ctx, eventschema.QueryJobCompleted, msg)
+			if err != nil {
+				return fmt.Errorf("failed to publish SNS: %w", err)
+			}
+
+			queryjobs.PublishWebhookEvent(ctx, s.snsClient, types.WebhookEventQueryJobCompleted, queryJobID)
+		}
+
+		return nil
+	}
+
+	for _, url := range urls {
 		msg := eventschema.ParseQueryJobURLMessage{
 			QueryJobID: queryJobID.String(),
 			URL:        url,
@@ -178,15 +330,66 @@ func (s *Service) ZenserpBatchExtractResults(ctx context.Context, snsEvent event
 
 		err = s.snsClient.Publish(ctx, eventschema.ParseQueryJobURL, msg)
 		if err != nil {
-			log.Fatalf("failed to publish SNS: %v", err)
+			return fmt.Errorf("failed to publish SNS: %w", err)
 		}
 
 		log.Infof("Published URL: %s", url)
 	}
 
-	if err := s.repository.Close(); err != nil {
-		log.Fatalf("can't close DB connection")
+	return nil
+}
+
+// recordTargetRankings stores where the target domain of the query job ranks in every location once all of the
+// query items are stored. Failing to do so doesn't fail storing the results.
+func (s *Service) recordTargetRankings(ctx context.Context, queryJob types.QueryJob) {
+	if queryJob.TargetDomain == nil {
+		return
 	}
 
-	log.Infof("done creating query items: query job id: %s, batchID: %s", queryJobID, zenserpBatchID)
+	queryLocations, err := s.repository.GetQueryLocations(ctx, queryJob.ID)
+	if err != nil {
+		log.Errorf("unable to get query locations of %s: %v", queryJob.ID, err)
+		return
+	}
+
+	rankings, err := s.repository.GetQueryJobLocationRankings(ctx, queryJob.ID)
+	if err != nil {
+		log.Errorf("unable to get location rankings of %s: %v", queryJob.ID, err)
+		return
+	}
+
+	targetRankings := serpanalysis.TargetRankings(*queryJob.TargetDomain, *queryLocations, *rankings)
+
+	for _, targetRanking := range targetRankings {
+		err = s.repository.SetQueryLocationTargetRanking(ctx, targetRanking)
+		if err != nil {
+			log.Errorf("unable to record target ranking: %v", err)
+			return
+		}
+	}
+
+	err = s.repository.SetQueryJobTargetSummary(ctx, queryJob.ID, serpanalysis.SummarizeTargetRankings(targetRankings))
+	if err != nil {
+		log.Errorf("unable to record target summary: %v", err)
+	}
+}
+
+// failQueryJob marks the query job as failed and notifies the webhook endpoints, unless it already completed or failed
+func (s *Service) failQueryJob(ctx context.Context, queryJobID uuid.UUID, reason string) {
+	err := queryjobs.Fail(ctx, s.repository, s.snsClient, queryJobID, reason)
+	if err != nil {
+		log.Fatalf("unable to fail query job: %v", err)
+	}
+}
+
+// zenserpJobOf returns the zenserp search of the keyword at the query location
+func zenserpJobOf(keyword string, queryLocation types.QueryLocation) zenserp.Job {
+	return zenserp.Job{
+		Query:        keyword,
+		Num:          queryLocation.Num,
+		SearchEngine: queryLocation.SearchEngine,
+		Device:       queryLocation.Device,
+		Country:      queryLocation.Country,
+		Location:     queryLocation.Location,
+	}
 }
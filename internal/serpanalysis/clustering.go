@@ -0,0 +1,186 @@
+package serpanalysis
+
+import (
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/types"
+)
+
+// TopURLs returns the top n URLs of a query job, ordered by their average position across the query
+// locations they rank in. URLs ranking in more locations win ties.
+func TopURLs(rankings []types.LocationRanking, n int) []string {
+	positions := urlPositions(rankings)
+
+	urls := make([]string, 0, len(positions))
+	for url := range positions {
+		urls = append(urls, url)
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		a, b := mean(positions[urls[i]]), mean(positions[urls[j]])
+		if a != b {
+			return a < b
+		}
+		if len(positions[urls[i]]) != len(positions[urls[j]]) {
+			return len(positions[urls[i]]) > len(positions[urls[j]])
+		}
+		return urls[i] < urls[j]
+	})
+
+	if len(urls) > n {
+		urls = urls[:n]
+	}
+
+	return urls
+}
+
+// ClusterKeywords groups the keywords of query jobs whose top n URLs share at least minSharedURLs URLs.
+// Keywords are linked pairwise and every connected group forms a cluster, so two keywords can end up in
+// the same cluster through a third one. The primary keyword of a cluster is the one sharing the most URLs
+// with the other keywords of the cluster.
+func ClusterKeywords(queryJobs []types.QueryJob, rankings []types.LocationRanking, n, minSharedURLs int) *types.KeywordClustering {
+	jobRankings := map[uuid.UUID][]types.LocationRanking{}
+	for _, ranking := range rankings {
+		jobRankings[ranking.QueryJobID] = append(jobRankings[ranking.QueryJobID], ranking)
+	}
+
+	topURLs := make([]map[string]bool, len(queryJobs))
+	for i, queryJob := range queryJobs {
+		topURLs[i] = map[string]bool{}
+		for _, url := range TopURLs(jobRankings[queryJob.ID], n) {
+			topURLs[i][url] = true
+		}
+	}
+
+	clustering := &types.KeywordClustering{
+		TopN:          n,
+		MinSharedURLs: minSharedURLs,
+		Clusters:      []types.KeywordCluster{},
+		KeywordPairs:  []types.KeywordPairOverlap{},
+	}
+
+	// Link every pair of keywords sharing enough URLs
+	parents := make([]int, len(queryJobs))
+	for i := range parents {
+		parents[i] = i
+	}
+
+	sharedCounts := make([]int, len(queryJobs))
+
+	for i := 0; i < len(queryJobs); i++ {
+		for j := i + 1; j < len(queryJobs); j++ {
+			shared := 0
+			for url := range topURLs[i] {
+				if topURLs[j][url] {
+					shared++
+				}
+			}
+
+			if shared == 0 {
+				continue
+			}
+
+			clustering.KeywordPairs = append(clustering.KeywordPairs, types.KeywordPairOverlap{
+				KeywordA:       queryJobs[i].Keyword,
+				KeywordB:       queryJobs[j].Keyword,
+				SharedURLCount: shared,
+			})
+
+			if shared >= minSharedURLs {
+				union(parents, i, j)
+				sharedCounts[i] += shared
+				sharedCounts[j] += shared
+			}
+		}
+	}
+
+	// Group keywords by their root
+	groups := map[int][]int{}
+	roots := []int{}
+	for i := range queryJobs {
+		root := find(parents, i)
+		if _, found := groups[root]; !found {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], i)
+	}
+
+	for _, root := range roots {
+		members := groups[root]
+
+		primary := members[0]
+		urlCounts := map[string]int{}
+		cluster := types.KeywordCluster{
+			Keywords:   []types.ClusterKeyword{},
+			SharedURLs: []string{},
+		}
+
+		for _, member := range members {
+			cluster.Keywords = append(cluster.Keywords, types.ClusterKeyword{
+				QueryJobID: queryJobs[member].ID,
+				Keyword:    queryJobs[member].Keyword,
+			})
+
+			if sharedCounts[member] > sharedCounts[primary] ||
+				(sharedCounts[member] == sharedCounts[primary] && len(queryJobs[member].Keyword) < len(queryJobs[primary].Keyword)) {
+				primary = member
+			}
+
+			for url := range topURLs[member] {
+				urlCounts[url]++
+			}
+		}
+
+		cluster.PrimaryKeyword = queryJobs[primary].Keyword
+
+		for url, count := range urlCounts {
+			if count > 1 {
+				cluster.SharedURLs = append(cluster.SharedURLs, url)
+			}
+		}
+
+		sort.Slice(cluster.SharedURLs, func(i, j int) bool {
+			a, b := cluster.SharedURLs[i], cluster.SharedURLs[j]
+			if urlCounts[a] != urlCounts[b] {
+				return urlCounts[a] > urlCounts[b]
+			}
+			return a < b
+		})
+
+		clustering.Clusters = append(clustering.Clusters, cluster)
+	}
+
+	sort.SliceStable(clustering.Clusters, func(i, j int) bool {
+		return len(clustering.Clusters[i].Keywords) > len(clustering.Clusters[j].Keywords)
+	})
+
+	sort.SliceStable(clustering.KeywordPairs, func(i, j int) bool {
+		return clustering.KeywordPairs[i].SharedURLCount > clustering.KeywordPairs[j].SharedURLCount
+	})
+
+	return clustering
+}
+
+func find(parents []int, i int) int {
+	for parents[i] != i {
+		parents[i] = parents[parents[i]]
+		i = parents[i]
+	}
+
+	return i
+}
+
+func union(parents []int, i, j int) {
+	rootI, rootJ := find(parents, i), find(parents, j)
+	if rootI == rootJ {
+		return
+	}
+
+	// Keep the earliest keyword as the root so clusters come out in query job order
+	if rootI < rootJ {
+		parents[rootJ] = rootI
+	} else {
+		parents[rootI] = rootJ
+	}
+}
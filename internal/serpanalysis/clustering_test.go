@@ -0,0 +1,51 @@
+package serpanalysis_test
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/serpanalysis"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ClusterKeywords(t *testing.T) {
+	shoes := types.QueryJob{ID: uuid.Must(uuid.NewV4()), Keyword: "running shoes"}
+	bestShoes := types.QueryJob{ID: uuid.Must(uuid.NewV4()), Keyword: "best running shoes for men"}
+	trainers := types.QueryJob{ID: uuid.Must(uuid.NewV4()), Keyword: "running trainers"}
+	recipes := types.QueryJob{ID: uuid.Must(uuid.NewV4()), Keyword: "pasta recipes"}
+
+	rankingsOf := func(queryJob types.QueryJob, urls ...string) []types.LocationRanking {
+		locationID := uuid.Must(uuid.NewV4())
+		rankings := []types.LocationRanking{}
+		for i, url := range urls {
+			rankings = append(rankings, types.LocationRanking{QueryJobID: queryJob.ID, QueryLocationID: locationID, Position: i + 1, URL: url})
+		}
+		return rankings
+	}
+
+	rankings := []types.LocationRanking{}
+	rankings = append(rankings, rankingsOf(shoes, "a", "b", "c", "d", "z")...)
+	rankings = append(rankings, rankingsOf(bestShoes, "a", "b", "c", "x", "y")...)
+	rankings = append(rankings, rankingsOf(trainers, "b", "c", "d", "w", "a")...)
+	rankings = append(rankings, rankingsOf(recipes, "p", "q", "r", "a", "s")...)
+
+	// only the top 4 URLs count, so "a" at position 5 for running trainers is ignored
+	clustering := serpanalysis.ClusterKeywords([]types.QueryJob{shoes, bestShoes, trainers, recipes}, rankings, 4, 3)
+
+	require.Len(t, clustering.Clusters, 2)
+
+	shoesCluster := clustering.Clusters[0]
+	require.Equal(t, "running shoes", shoesCluster.PrimaryKeyword)
+	require.Equal(t, []types.ClusterKeyword{
+		{QueryJobID: shoes.ID, Keyword: "running shoes"},
+		{QueryJobID: bestShoes.ID, Keyword: "best running shoes for men"},
+		{QueryJobID: trainers.ID, Keyword: "running trainers"},
+	}, shoesCluster.Keywords)
+	require.Equal(t, []string{"b", "c", "a", "d"}, shoesCluster.SharedURLs)
+
+	require.Equal(t, "pasta recipes", clustering.Clusters[1].PrimaryKeyword)
+	require.Len(t, clustering.Clusters[1].Keywords, 1)
+
+	require.Equal(t, types.KeywordPairOverlap{KeywordA: "running shoes", KeywordB: "best running shoes for men", SharedURLCount: 3}, clustering.KeywordPairs[0])
+}
@@ -0,0 +1,139 @@
+package serpanalysis
+
+import (
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/types"
+)
+
+// QueryJobSnapshot holds what is needed of a query job to compare it with another one
+type QueryJobSnapshot struct {
+	QueryJobID     uuid.UUID
+	QueryLocations []types.QueryLocation
+	Rankings       []types.LocationRanking
+	Contents       []types.URLContent
+}
+
+// CompareQueryJobs reports what changed from a query job to another one. Rankings are compared for every
+// location and device both query jobs were fetched for: URLs that entered the other query job, dropped out
+// of it, or moved positions. A positive position change means the URL climbed in the other query job.
+// Content changes list the URLs crawled in both query jobs whose title or body length differ.
+func CompareQueryJobs(queryJob, other QueryJobSnapshot) *types.QueryJobComparison {
+	comparison := &types.QueryJobComparison{
+		QueryJobID:      queryJob.QueryJobID,
+		OtherQueryJobID: other.QueryJobID,
+		Locations:       []types.LocationComparison{},
+		ContentChanges:  []types.URLContentChange{},
+	}
+
+	otherQueryLocations := map[string]types.QueryLocation{}
+	for _, queryLocation := range other.QueryLocations {
+		otherQueryLocations[queryLocation.Device+"|"+queryLocation.Location] = queryLocation
+	}
+
+	for _, queryLocation := range queryJob.QueryLocations {
+		otherQueryLocation, found := otherQueryLocations[queryLocation.Device+"|"+queryLocation.Location]
+		if !found {
+			continue
+		}
+
+		positions := bestPositions(queryJob.Rankings, queryLocation.ID)
+		otherPositions := bestPositions(other.Rankings, otherQueryLocation.ID)
+
+		locationComparison := types.LocationComparison{
+			Location: queryLocation.Location,
+			Device:   queryLocation.Device,
+			Entered:  []types.RankedURL{},
+			Dropped:  []types.RankedURL{},
+			Moved:    []types.URLPositionChange{},
+		}
+
+		for url, position := range positions {
+			otherPosition, found := otherPositions[url]
+			if !found {
+				locationComparison.Dropped = append(locationComparison.Dropped, types.RankedURL{URL: url, Position: position})
+				continue
+			}
+
+			if position != otherPosition {
+				locationComparison.Moved = append(locationComparison.Moved, types.URLPositionChange{
+					URL:            url,
+					Position:       position,
+					OtherPosition:  otherPosition,
+					PositionChange: position - otherPosition,
+				})
+			}
+		}
+
+		for url, otherPosition := range otherPositions {
+			if _, found := positions[url]; !found {
+				locationComparison.Entered = append(locationComparison.Entered, types.RankedURL{URL: url, Position: otherPosition})
+			}
+		}
+
+		sortRankedURLs(locationComparison.Entered)
+		sortRankedURLs(locationComparison.Dropped)
+		sort.Slice(locationComparison.Moved, func(i, j int) bool {
+			return locationComparison.Moved[i].OtherPosition < locationComparison.Moved[j].OtherPosition
+		})
+
+		comparison.Locations = append(comparison.Locations, locationComparison)
+	}
+
+	otherContents := map[string]types.URLContent{}
+	for _, content := range other.Contents {
+		otherContents[content.URL] = content
+	}
+
+	for _, content := range queryJob.Contents {
+		otherContent, found := otherContents[content.URL]
+		if !found {
+			continue
+		}
+
+		titleChanged := content.Title != otherContent.Title
+		if !titleChanged && content.BodyLength == otherContent.BodyLength {
+			continue
+		}
+
+		comparison.ContentChanges = append(comparison.ContentChanges, types.URLContentChange{
+			URL:              content.URL,
+			Title:            content.Title,
+			OtherTitle:       otherContent.Title,
+			TitleChanged:     titleChanged,
+			BodyLength:       content.BodyLength,
+			OtherBodyLength:  otherContent.BodyLength,
+			BodyLengthChange: otherContent.BodyLength - content.BodyLength,
+		})
+	}
+
+	sort.Slice(comparison.ContentChanges, func(i, j int) bool {
+		return comparison.ContentChanges[i].URL < comparison.ContentChanges[j].URL
+	})
+
+	return comparison
+}
+
+// bestPositions returns the best position of every URL in a query location
+func bestPositions(rankings []types.LocationRanking, queryLocationID uuid.UUID) map[string]int {
+	positions := map[string]int{}
+
+	for _, ranking := range rankings {
+		if ranking.QueryLocationID != queryLocationID {
+			continue
+		}
+
+		if position, found := positions[ranking.URL]; !found || ranking.Position < position {
+			positions[ranking.URL] = ranking.Position
+		}
+	}
+
+	return positions
+}
+
+func sortRankedURLs(urls []types.RankedURL) {
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].Position < urls[j].Position
+	})
+}
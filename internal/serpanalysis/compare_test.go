@@ -0,0 +1,65 @@
+package serpanalysis_test
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/serpanalysis"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CompareQueryJobs(t *testing.T) {
+	austin := types.QueryLocation{ID: uuid.Must(uuid.NewV4()), Location: "Austin", Device: "desktop"}
+	kingfield := types.QueryLocation{ID: uuid.Must(uuid.NewV4()), Location: "Kingfield", Device: "desktop"}
+	otherAustin := types.QueryLocation{ID: uuid.Must(uuid.NewV4()), Location: "Austin", Device: "desktop"}
+
+	queryJob := serpanalysis.QueryJobSnapshot{
+		QueryJobID:     uuid.Must(uuid.NewV4()),
+		QueryLocations: []types.QueryLocation{austin, kingfield},
+		Rankings: []types.LocationRanking{
+			{QueryLocationID: austin.ID, Position: 1, URL: "https://a.com"},
+			{QueryLocationID: austin.ID, Position: 2, URL: "https://b.com"},
+			{QueryLocationID: austin.ID, Position: 3, URL: "https://c.com"},
+			{QueryLocationID: kingfield.ID, Position: 1, URL: "https://a.com"},
+		},
+		Contents: []types.URLContent{
+			{URL: "https://a.com", Title: "A", BodyLength: 100},
+			{URL: "https://b.com", Title: "B", BodyLength: 200},
+			{URL: "https://c.com", Title: "C", BodyLength: 300},
+		},
+	}
+
+	other := serpanalysis.QueryJobSnapshot{
+		QueryJobID:     uuid.Must(uuid.NewV4()),
+		QueryLocations: []types.QueryLocation{otherAustin},
+		Rankings: []types.LocationRanking{
+			{QueryLocationID: otherAustin.ID, Position: 1, URL: "https://b.com"},
+			{QueryLocationID: otherAustin.ID, Position: 2, URL: "https://a.com"},
+			{QueryLocationID: otherAustin.ID, Position: 3, URL: "https://d.com"},
+		},
+		Contents: []types.URLContent{
+			{URL: "https://a.com", Title: "A", BodyLength: 100},
+			{URL: "https://b.com", Title: "B rewritten", BodyLength: 250},
+			{URL: "https://d.com", Title: "D", BodyLength: 400},
+		},
+	}
+
+	comparison := serpanalysis.CompareQueryJobs(queryJob, other)
+
+	require.Len(t, comparison.Locations, 1)
+	require.Equal(t, types.LocationComparison{
+		Location: "Austin",
+		Device:   "desktop",
+		Entered:  []types.RankedURL{{URL: "https://d.com", Position: 3}},
+		Dropped:  []types.RankedURL{{URL: "https://c.com", Position: 3}},
+		Moved: []types.URLPositionChange{
+			{URL: "https://b.com", Position: 2, OtherPosition: 1, PositionChange: 1},
+			{URL: "https://a.com", Position: 1, OtherPosition: 2, PositionChange: -1},
+		},
+	}, comparison.Locations[0])
+
+	require.Equal(t, []types.URLContentChange{
+		{URL: "https://b.com", Title: "B", OtherTitle: "B rewritten", TitleChanged: true, BodyLength: 200, OtherBodyLength: 250, BodyLengthChange: 50},
+	}, comparison.ContentChanges)
+}
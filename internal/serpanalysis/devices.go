@@ -0,0 +1,99 @@
+package serpanalysis
+
+import (
+	"math"
+	"sort"
+
+	"github.com/jponc/competitive-analysis/internal/types"
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+)
+
+// CompareDevices compares the desktop and mobile rankings of a query job. Positions of a URL are averaged
+// across locations per device, shared URLs are sorted by the largest position change first.
+func CompareDevices(rankings []types.LocationRanking) *types.DeviceComparison {
+	desktop := urlPositions(filterDevice(rankings, DeviceDesktop))
+	mobile := urlPositions(filterDevice(rankings, DeviceMobile))
+
+	comparison := &types.DeviceComparison{
+		DesktopURLCount: len(desktop),
+		MobileURLCount:  len(mobile),
+		SharedURLs:      []types.DeviceURLComparison{},
+		DesktopOnlyURLs: []types.DeviceURLPosition{},
+		MobileOnlyURLs:  []types.DeviceURLPosition{},
+	}
+
+	for url, desktopPositions := range desktop {
+		mobilePositions, found := mobile[url]
+		if !found {
+			comparison.DesktopOnlyURLs = append(comparison.DesktopOnlyURLs, types.DeviceURLPosition{
+				URL:               url,
+				AvgPosition:       round(mean(desktopPositions)),
+				LocationHitsCount: len(desktopPositions),
+			})
+			continue
+		}
+
+		desktopAvg := mean(desktopPositions)
+		mobileAvg := mean(mobilePositions)
+
+		comparison.SharedURLs = append(comparison.SharedURLs, types.DeviceURLComparison{
+			URL:                url,
+			DesktopAvgPosition: round(desktopAvg),
+			MobileAvgPosition:  round(mobileAvg),
+			PositionDelta:      round(mobileAvg - desktopAvg),
+		})
+	}
+
+	for url, mobilePositions := range mobile {
+		if _, found := desktop[url]; !found {
+			comparison.MobileOnlyURLs = append(comparison.MobileOnlyURLs, types.DeviceURLPosition{
+				URL:               url,
+				AvgPosition:       round(mean(mobilePositions)),
+				LocationHitsCount: len(mobilePositions),
+			})
+		}
+	}
+
+	comparison.SharedURLCount = len(comparison.SharedURLs)
+
+	union := len(desktop) + len(mobile) - comparison.SharedURLCount
+	if union > 0 {
+		comparison.Overlap = round(float64(comparison.SharedURLCount) / float64(union))
+	}
+
+	sort.Slice(comparison.SharedURLs, func(i, j int) bool {
+		a, b := comparison.SharedURLs[i], comparison.SharedURLs[j]
+		if math.Abs(a.PositionDelta) != math.Abs(b.PositionDelta) {
+			return math.Abs(a.PositionDelta) > math.Abs(b.PositionDelta)
+		}
+		return a.DesktopAvgPosition < b.DesktopAvgPosition
+	})
+	sortByAvgPosition(comparison.DesktopOnlyURLs)
+	sortByAvgPosition(comparison.MobileOnlyURLs)
+
+	return comparison
+}
+
+func filterDevice(rankings []types.LocationRanking, device string) []types.LocationRanking {
+	filtered := []types.LocationRanking{}
+	for _, ranking := range rankings {
+		if ranking.Device == device {
+			filtered = append(filtered, ranking)
+		}
+	}
+
+	return filtered
+}
+
+func sortByAvgPosition(positions []types.DeviceURLPosition) {
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].AvgPosition != positions[j].AvgPosition {
+			return positions[i].AvgPosition < positions[j].AvgPosition
+		}
+		return positions[i].URL < positions[j].URL
+	})
+}
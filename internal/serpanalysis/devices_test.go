@@ -0,0 +1,50 @@
+package serpanalysis_test
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/serpanalysis"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CompareDevices(t *testing.T) {
+	desktopA := uuid.Must(uuid.NewV4())
+	desktopB := uuid.Must(uuid.NewV4())
+	mobileA := uuid.Must(uuid.NewV4())
+	mobileB := uuid.Must(uuid.NewV4())
+
+	rankings := []types.LocationRanking{
+		{QueryLocationID: desktopA, Device: "desktop", Position: 1, URL: "https://a.com"},
+		{QueryLocationID: desktopA, Device: "desktop", Position: 2, URL: "https://b.com"},
+		{QueryLocationID: desktopA, Device: "desktop", Position: 3, URL: "https://desktop-only.com"},
+		{QueryLocationID: desktopB, Device: "desktop", Position: 1, URL: "https://a.com"},
+		{QueryLocationID: desktopB, Device: "desktop", Position: 4, URL: "https://b.com"},
+		{QueryLocationID: mobileA, Device: "mobile", Position: 5, URL: "https://a.com"},
+		{QueryLocationID: mobileA, Device: "mobile", Position: 3, URL: "https://b.com"},
+		{QueryLocationID: mobileB, Device: "mobile", Position: 2, URL: "https://mobile-only.com"},
+		{QueryLocationID: mobileB, Device: "mobile", Position: 6, URL: "https://mobile-only.com"},
+	}
+
+	comparison := serpanalysis.CompareDevices(rankings)
+
+	require.Equal(t, 3, comparison.DesktopURLCount)
+	require.Equal(t, 3, comparison.MobileURLCount)
+	require.Equal(t, 2, comparison.SharedURLCount)
+	require.Equal(t, 0.5, comparison.Overlap)
+
+	require.Equal(t, []types.DeviceURLComparison{
+		{URL: "https://a.com", DesktopAvgPosition: 1, MobileAvgPosition: 5, PositionDelta: 4},
+		{URL: "https://b.com", DesktopAvgPosition: 3, MobileAvgPosition: 3, PositionDelta: 0},
+	}, comparison.SharedURLs)
+
+	require.Equal(t, []types.DeviceURLPosition{
+		{URL: "https://desktop-only.com", AvgPosition: 3, LocationHitsCount: 1},
+	}, comparison.DesktopOnlyURLs)
+
+	// duplicate URLs within a location only count their best position
+	require.Equal(t, []types.DeviceURLPosition{
+		{URL: "https://mobile-only.com", AvgPosition: 2, LocationHitsCount: 1},
+	}, comparison.MobileOnlyURLs)
+}
@@ -0,0 +1,38 @@
+package serpanalysis
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Domain returns the host of a URL in lowercase, without port and "www." prefix.
+// It returns an empty string if the URL can't be parsed.
+func Domain(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// NormalizeDomain turns user input like "https://www.Example.com/path" or "example.com" into "example.com"
+func NormalizeDomain(input string) string {
+	input = strings.TrimSpace(input)
+	if !strings.Contains(input, "://") {
+		input = "http://" + input
+	}
+
+	return Domain(input)
+}
+
+// MatchesDomain checks if the host is the domain or one of its subdomains
+func MatchesDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// NormalizeKeyword returns the keyword in lowercase with collapsed whitespace, so successive query jobs
+// for the same keyword can be matched
+func NormalizeKeyword(keyword string) string {
+	return strings.ToLower(strings.Join(strings.Fields(keyword), " "))
+}
@@ -0,0 +1,52 @@
+package serpanalysis
+
+import (
+	"math"
+
+	"github.com/jponc/competitive-analysis/internal/types"
+)
+
+// urlPositions returns the best position of every URL in each query location it ranks in
+func urlPositions(rankings []types.LocationRanking) map[string][]float64 {
+	best := map[string]map[string]int{}
+
+	for _, ranking := range rankings {
+		locations, found := best[ranking.URL]
+		if !found {
+			locations = map[string]int{}
+			best[ranking.URL] = locations
+		}
+
+		locationID := ranking.QueryLocationID.String()
+		if position, found := locations[locationID]; !found || ranking.Position < position {
+			locations[locationID] = ranking.Position
+		}
+	}
+
+	positions := map[string][]float64{}
+	for url, locations := range best {
+		for _, position := range locations {
+			positions[url] = append(positions[url], float64(position))
+		}
+	}
+
+	return positions
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum / float64(len(values))
+}
+
+// round rounds to 2 decimal places, matching the precision of the position hits
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
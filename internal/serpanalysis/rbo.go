@@ -0,0 +1,50 @@
+package serpanalysis
+
+import "math"
+
+// rboPersistence is the probability of a user moving on to the next result, 0.9 puts most of the weight
+// on the first 10 results.
+const rboPersistence = 0.9
+
+// RankBiasedOverlap computes the extrapolated rank-biased overlap of two ranked lists (Webber et al., 2010).
+// It returns 1 for identical rankings and 0 for rankings without a common item, and weights agreement at
+// the top of the lists more than agreement further down. Both lists are evaluated up to the shorter length.
+func RankBiasedOverlap(a, b []string) float64 {
+	depth := len(a)
+	if len(b) < depth {
+		depth = len(b)
+	}
+
+	if depth == 0 {
+		return 0
+	}
+
+	seenA := map[string]bool{}
+	seenB := map[string]bool{}
+	overlap := 0
+	sum := 0.0
+
+	for d := 1; d <= depth; d++ {
+		x, y := a[d-1], b[d-1]
+
+		if x == y {
+			overlap++
+		} else {
+			if seenB[x] {
+				overlap++
+			}
+			if seenA[y] {
+				overlap++
+			}
+		}
+
+		seenA[x] = true
+		seenB[y] = true
+
+		sum += float64(overlap) / float64(d) * math.Pow(rboPersistence, float64(d))
+	}
+
+	agreement := float64(overlap) / float64(depth)
+
+	return agreement*math.Pow(rboPersistence, float64(depth)) + (1-rboPersistence)/rboPersistence*sum
+}
@@ -0,0 +1,207 @@
+package serpanalysis
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/types"
+)
+
+const (
+	reportTopCompetitors = 10
+	reportTopRankings    = 10
+	reportTopTitleWords  = 20
+)
+
+// bodyLengthBuckets are the upper bounds (exclusive) in characters of the body length distribution, pages
+// past the last one fall in a final open ended bucket
+var bodyLengthBuckets = []struct {
+	label string
+	max   int
+}{
+	{"< 1k", 1000},
+	{"1k - 2.5k", 2500},
+	{"2.5k - 5k", 5000},
+	{"5k - 10k", 10000},
+	{"10k - 20k", 20000},
+}
+
+// titleStopWords are left out of the most common title words
+var titleStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "by": true, "for": true,
+	"from": true, "how": true, "in": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+	"the": true, "this": true, "to": true, "vs": true, "what": true, "with": true, "you": true, "your": true,
+}
+
+// KeywordReport summarises a query job for a client facing report: the domains ranking across the most
+// locations, the top rankings of every location, how long the crawled pages are and the words their
+// titles use the most.
+func KeywordReport(queryJob types.QueryJob, rankings []types.LocationRanking, contents []types.URLContent) *types.KeywordReport {
+	report := &types.KeywordReport{
+		QueryJob:       queryJob,
+		GeneratedAt:    time.Now(),
+		TopCompetitors: reportCompetitors(queryJob, rankings),
+		Locations:      []types.ReportLocation{},
+		BodyLengths:    []types.ReportBucket{},
+		TitleWords:     []types.ReportWordCount{},
+	}
+
+	// rankings are ordered by location, device then position
+	locationIndex := map[uuid.UUID]int{}
+	for _, ranking := range rankings {
+		i, found := locationIndex[ranking.QueryLocationID]
+		if !found {
+			i = len(report.Locations)
+			locationIndex[ranking.QueryLocationID] = i
+			report.Locations = append(report.Locations, types.ReportLocation{
+				Device:   ranking.Device,
+				Location: ranking.Location,
+				Rankings: []types.ReportRanking{},
+			})
+		}
+
+		if len(report.Locations[i].Rankings) < reportTopRankings {
+			report.Locations[i].Rankings = append(report.Locations[i].Rankings, types.ReportRanking{
+				Position: ranking.Position,
+				URL:      ranking.URL,
+				Title:    ranking.Title,
+			})
+		}
+	}
+
+	bodyLengths := []int{}
+	for _, content := range contents {
+		if content.BodyLength > 0 {
+			bodyLengths = append(bodyLengths, content.BodyLength)
+		}
+	}
+
+	sort.Ints(bodyLengths)
+	report.CrawledPages = len(bodyLengths)
+	if len(bodyLengths) > 0 {
+		report.MedianBodyLength = bodyLengths[len(bodyLengths)/2]
+	}
+
+	counts := make([]int, len(bodyLengthBuckets)+1)
+	for _, length := range bodyLengths {
+		i := 0
+		for i < len(bodyLengthBuckets) && length >= bodyLengthBuckets[i].max {
+			i++
+		}
+		counts[i]++
+	}
+
+	for i, bucket := range bodyLengthBuckets {
+		report.BodyLengths = append(report.BodyLengths, types.ReportBucket{Label: bucket.label, Count: counts[i]})
+	}
+	report.BodyLengths = append(report.BodyLengths, types.ReportBucket{Label: "20k+", Count: counts[len(bodyLengthBuckets)]})
+
+	report.TitleWords = titleWords(rankings)
+
+	return report
+}
+
+func reportCompetitors(queryJob types.QueryJob, rankings []types.LocationRanking) []types.ReportCompetitor {
+	// best position of every domain per location
+	best := map[string]map[uuid.UUID]int{}
+	urls := map[string]map[string]bool{}
+
+	for _, ranking := range rankings {
+		domain := Domain(ranking.URL)
+		if domain == "" {
+			continue
+		}
+
+		if best[domain] == nil {
+			best[domain] = map[uuid.UUID]int{}
+			urls[domain] = map[string]bool{}
+		}
+
+		if position, found := best[domain][ranking.QueryLocationID]; !found || ranking.Position < position {
+			best[domain][ranking.QueryLocationID] = ranking.Position
+		}
+		urls[domain][ranking.URL] = true
+	}
+
+	competitors := []types.ReportCompetitor{}
+	for domain, locations := range best {
+		positions := []float64{}
+		bestPosition := 0
+		for _, position := range locations {
+			positions = append(positions, float64(position))
+			if bestPosition == 0 || position < bestPosition {
+				bestPosition = position
+			}
+		}
+
+		competitors = append(competitors, types.ReportCompetitor{
+			Domain:        domain,
+			BestPosition:  bestPosition,
+			AvgPosition:   round(mean(positions)),
+			LocationCount: len(locations),
+			URLCount:      len(urls[domain]),
+			IsTarget:      queryJob.TargetDomain != nil && MatchesDomain(domain, *queryJob.TargetDomain),
+		})
+	}
+
+	sort.Slice(competitors, func(i, j int) bool {
+		if competitors[i].LocationCount != competitors[j].LocationCount {
+			return competitors[i].LocationCount > competitors[j].LocationCount
+		}
+		if competitors[i].AvgPosition != competitors[j].AvgPosition {
+			return competitors[i].AvgPosition < competitors[j].AvgPosition
+		}
+		return competitors[i].Domain < competitors[j].Domain
+	})
+
+	if len(competitors) > reportTopCompetitors {
+		competitors = competitors[:reportTopCompetitors]
+	}
+
+	return competitors
+}
+
+// titleWords counts the words used in the titles of the ranking URLs, every URL counted once
+func titleWords(rankings []types.LocationRanking) []types.ReportWordCount {
+	seen := map[string]bool{}
+	counts := map[string]int{}
+
+	for _, ranking := range rankings {
+		if seen[ranking.URL] {
+			continue
+		}
+		seen[ranking.URL] = true
+
+		words := strings.FieldsFunc(strings.ToLower(ranking.Title), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+
+		for _, word := range words {
+			if len([]rune(word)) < 2 || titleStopWords[word] {
+				continue
+			}
+			counts[word]++
+		}
+	}
+
+	wordCounts := []types.ReportWordCount{}
+	for word, count := range counts {
+		wordCounts = append(wordCounts, types.ReportWordCount{Word: word, Count: count})
+	}
+
+	sort.Slice(wordCounts, func(i, j int) bool {
+		if wordCounts[i].Count != wordCounts[j].Count {
+			return wordCounts[i].Count > wordCounts[j].Count
+		}
+		return wordCounts[i].Word < wordCounts[j].Word
+	})
+
+	if len(wordCounts) > reportTopTitleWords {
+		wordCounts = wordCounts[:reportTopTitleWords]
+	}
+
+	return wordCounts
+}
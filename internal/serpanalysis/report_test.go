@@ -0,0 +1,65 @@
+package serpanalysis_test
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/serpanalysis"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_KeywordReport(t *testing.T) {
+	austin := uuid.Must(uuid.NewV4())
+	denver := uuid.Must(uuid.NewV4())
+	target := "example.com"
+
+	queryJob := types.QueryJob{ID: uuid.Must(uuid.NewV4()), Keyword: "running shoes", TargetDomain: &target}
+
+	rankings := []types.LocationRanking{
+		{QueryLocationID: austin, Device: "desktop", Location: "Austin", Position: 1, URL: "https://a.com/1", Title: "Best Running Shoes of 2021"},
+		{QueryLocationID: austin, Device: "desktop", Location: "Austin", Position: 2, URL: "https://www.example.com/shoes", Title: "Running Shoes | Example"},
+		{QueryLocationID: austin, Device: "desktop", Location: "Austin", Position: 3, URL: "https://b.com/1", Title: "Trail shoes for the road"},
+		{QueryLocationID: denver, Device: "desktop", Location: "Denver", Position: 1, URL: "https://example.com/shoes", Title: "Running Shoes | Example"},
+		{QueryLocationID: denver, Device: "desktop", Location: "Denver", Position: 2, URL: "https://a.com/2", Title: "Running shoes sale"},
+	}
+
+	contents := []types.URLContent{
+		{URL: "https://a.com/1", BodyLength: 800},
+		{URL: "https://a.com/2", BodyLength: 3000},
+		{URL: "https://b.com/1", BodyLength: 25000},
+		{URL: "https://example.com/shoes", BodyLength: 0},
+	}
+
+	report := serpanalysis.KeywordReport(queryJob, rankings, contents)
+
+	require.Equal(t, []types.ReportCompetitor{
+		{Domain: "a.com", BestPosition: 1, AvgPosition: 1.5, LocationCount: 2, URLCount: 2},
+		{Domain: "example.com", BestPosition: 1, AvgPosition: 1.5, LocationCount: 2, URLCount: 2, IsTarget: true},
+		{Domain: "b.com", BestPosition: 3, AvgPosition: 3, LocationCount: 1, URLCount: 1},
+	}, report.TopCompetitors)
+
+	require.Len(t, report.Locations, 2)
+	require.Equal(t, "Austin", report.Locations[0].Location)
+	require.Len(t, report.Locations[0].Rankings, 3)
+	require.Equal(t, "Denver", report.Locations[1].Location)
+	require.Equal(t, types.ReportRanking{Position: 1, URL: "https://example.com/shoes", Title: "Running Shoes | Example"}, report.Locations[1].Rankings[0])
+
+	require.Equal(t, 3, report.CrawledPages)
+	require.Equal(t, 3000, report.MedianBodyLength)
+	require.Equal(t, []types.ReportBucket{
+		{Label: "< 1k", Count: 1},
+		{Label: "1k - 2.5k", Count: 0},
+		{Label: "2.5k - 5k", Count: 1},
+		{Label: "5k - 10k", Count: 0},
+		{Label: "10k - 20k", Count: 0},
+		{Label: "20k+", Count: 1},
+	}, report.BodyLengths)
+
+	require.Equal(t, types.ReportWordCount{Word: "shoes", Count: 5}, report.TitleWords[0])
+	require.Equal(t, types.ReportWordCount{Word: "running", Count: 4}, report.TitleWords[1])
+	for _, wordCount := range report.TitleWords {
+		require.NotEqual(t, "of", wordCount.Word)
+		require.NotEqual(t, "the", wordCount.Word)
+	}
+}
@@ -0,0 +1,185 @@
+package serpanalysis
+
+import (
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/types"
+)
+
+// DefaultCTRCurve is the expected click-through rate of the top 10 organic positions, positions past the
+// end of a curve get no clicks
+var DefaultCTRCurve = []float64{0.316, 0.158, 0.11, 0.081, 0.061, 0.045, 0.034, 0.026, 0.02, 0.016}
+
+// ShareOfVoice ranks domains by their share of the expected clicks across the keywords of the query jobs.
+// Each result is worth the CTR of its position, averaged across the locations of a query job and weighted
+// by the search volume of the keyword. Keywords without a search volume weigh 1.
+//
+// Successive query jobs of the same keyword form snapshots ordered by completion, aligned on the latest
+// one. Keywords with fewer query jobs reuse their oldest one in the earlier snapshots so every snapshot
+// covers the same keywords. queryJobs must be ordered by completion time.
+func ShareOfVoice(queryJobs []types.QueryJob, rankings []types.LocationRanking, ctrCurve []float64, searchVolumes map[string]int, limit int) *types.ShareOfVoice {
+	jobVisibility := queryJobVisibility(rankings, ctrCurve)
+
+	volumes := map[string]int{}
+	for keyword, volume := range searchVolumes {
+		volumes[NormalizeKeyword(keyword)] = volume
+	}
+
+	keywordJobs := map[string][]types.QueryJob{}
+	keywords := []string{}
+	for _, queryJob := range queryJobs {
+		keyword := NormalizeKeyword(queryJob.Keyword)
+		if _, found := keywordJobs[keyword]; !found {
+			keywords = append(keywords, keyword)
+		}
+		keywordJobs[keyword] = append(keywordJobs[keyword], queryJob)
+	}
+
+	sort.Strings(keywords)
+
+	snapshots := 0
+	for _, jobs := range keywordJobs {
+		if len(jobs) > snapshots {
+			snapshots = len(jobs)
+		}
+	}
+
+	sov := &types.ShareOfVoice{
+		CTRCurve:  ctrCurve,
+		Snapshots: snapshots,
+		Keywords:  []types.ShareOfVoiceKeyword{},
+		Domains:   []types.DomainShareOfVoice{},
+	}
+
+	for _, keyword := range keywords {
+		jobs := keywordJobs[keyword]
+
+		volume, found := volumes[keyword]
+		if !found {
+			volume = 1
+		}
+
+		queryJobIDs := []uuid.UUID{}
+		for _, queryJob := range jobs {
+			queryJobIDs = append(queryJobIDs, queryJob.ID)
+		}
+
+		sov.Keywords = append(sov.Keywords, types.ShareOfVoiceKeyword{
+			Keyword:      jobs[len(jobs)-1].Keyword,
+			SearchVolume: volume,
+			QueryJobIDs:  queryJobIDs,
+		})
+	}
+
+	// Visibility and share of every domain per snapshot, oldest first
+	visibility := make([]map[string]float64, snapshots)
+	shares := make([]map[string]float64, snapshots)
+
+	for i := 0; i < snapshots; i++ {
+		visibility[i] = map[string]float64{}
+		total := 0.0
+
+		for k, keyword := range keywords {
+			jobs := keywordJobs[keyword]
+
+			j := len(jobs) - snapshots + i
+			if j < 0 {
+				j = 0
+			}
+
+			for domain, v := range jobVisibility[jobs[j].ID] {
+				weighted := v * float64(sov.Keywords[k].SearchVolume)
+				visibility[i][domain] += weighted
+				total += weighted
+			}
+		}
+
+		shares[i] = map[string]float64{}
+		for domain, v := range visibility[i] {
+			if total > 0 {
+				shares[i][domain] = v / total * 100
+			}
+		}
+	}
+
+	if snapshots == 0 {
+		return sov
+	}
+
+	latest := snapshots - 1
+
+	domains := []string{}
+	for domain, v := range visibility[latest] {
+		if v > 0 {
+			domains = append(domains, domain)
+		}
+	}
+
+	sort.Slice(domains, func(i, j int) bool {
+		a, b := visibility[latest][domains[i]], visibility[latest][domains[j]]
+		if a != b {
+			return a > b
+		}
+		return domains[i] < domains[j]
+	})
+
+	if len(domains) > limit {
+		domains = domains[:limit]
+	}
+
+	for _, domain := range domains {
+		entry := types.DomainShareOfVoice{
+			Domain:     domain,
+			Visibility: round(visibility[latest][domain]),
+			Share:      round(shares[latest][domain]),
+			Trend:      []float64{},
+		}
+
+		for i := 0; i < snapshots; i++ {
+			entry.Trend = append(entry.Trend, round(shares[i][domain]))
+		}
+
+		if snapshots > 1 {
+			entry.ShareChange = round(shares[latest][domain] - shares[latest-1][domain])
+		}
+
+		sov.Domains = append(sov.Domains, entry)
+	}
+
+	return sov
+}
+
+// queryJobVisibility returns the expected clicks of every domain per query job, averaged across the query
+// locations of the job
+func queryJobVisibility(rankings []types.LocationRanking, ctrCurve []float64) map[uuid.UUID]map[string]float64 {
+	clicks := map[uuid.UUID]map[string]float64{}
+	locations := map[uuid.UUID]map[uuid.UUID]bool{}
+
+	for _, ranking := range rankings {
+		if locations[ranking.QueryJobID] == nil {
+			locations[ranking.QueryJobID] = map[uuid.UUID]bool{}
+			clicks[ranking.QueryJobID] = map[string]float64{}
+		}
+		locations[ranking.QueryJobID][ranking.QueryLocationID] = true
+
+		if ranking.Position < 1 || ranking.Position > len(ctrCurve) {
+			continue
+		}
+
+		domain := Domain(ranking.URL)
+		if domain == "" {
+			continue
+		}
+
+		clicks[ranking.QueryJobID][domain] += ctrCurve[ranking.Position-1]
+	}
+
+	for queryJobID, domains := range clicks {
+		for domain := range domains {
+			domains[domain] /= float64(len(locations[queryJobID]))
+		}
+	}
+
+	return clicks
+}
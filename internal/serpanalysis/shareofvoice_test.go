@@ -0,0 +1,76 @@
+package serpanalysis_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/serpanalysis"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ShareOfVoice(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-24 * time.Hour)
+
+	a1 := types.QueryJob{ID: uuid.Must(uuid.NewV4()), Keyword: "keyword a", CompletedAt: &earlier}
+	b1 := types.QueryJob{ID: uuid.Must(uuid.NewV4()), Keyword: "keyword b", CompletedAt: &earlier}
+	a2 := types.QueryJob{ID: uuid.Must(uuid.NewV4()), Keyword: "Keyword A", CompletedAt: &now}
+
+	l1 := uuid.Must(uuid.NewV4())
+	l2 := uuid.Must(uuid.NewV4())
+	l3 := uuid.Must(uuid.NewV4())
+	l4 := uuid.Must(uuid.NewV4())
+
+	rankings := []types.LocationRanking{
+		{QueryJobID: a1.ID, QueryLocationID: l1, Position: 1, URL: "https://a.com/1"},
+		{QueryJobID: a1.ID, QueryLocationID: l1, Position: 2, URL: "https://b.com/1"},
+		{QueryJobID: a1.ID, QueryLocationID: l1, Position: 3, URL: "https://c.com/1"},
+		{QueryJobID: a2.ID, QueryLocationID: l2, Position: 1, URL: "https://b.com/1"},
+		{QueryJobID: a2.ID, QueryLocationID: l2, Position: 2, URL: "https://a.com/1"},
+		{QueryJobID: a2.ID, QueryLocationID: l3, Position: 1, URL: "https://b.com/1"},
+		{QueryJobID: a2.ID, QueryLocationID: l3, Position: 2, URL: "https://www.b.com/2"},
+		{QueryJobID: a2.ID, QueryLocationID: l3, Position: 4, URL: "https://d.com/1"},
+		{QueryJobID: b1.ID, QueryLocationID: l4, Position: 1, URL: "https://a.com/2"},
+		{QueryJobID: b1.ID, QueryLocationID: l4, Position: 2, URL: "https://c.com/2"},
+	}
+
+	ctrCurve := []float64{0.5, 0.3, 0.2}
+	searchVolumes := map[string]int{"Keyword  A": 3}
+
+	sov := serpanalysis.ShareOfVoice([]types.QueryJob{a1, b1, a2}, rankings, ctrCurve, searchVolumes, 10)
+
+	require.Equal(t, 2, sov.Snapshots)
+	require.Equal(t, []types.ShareOfVoiceKeyword{
+		{Keyword: "Keyword A", SearchVolume: 3, QueryJobIDs: []uuid.UUID{a1.ID, a2.ID}},
+		{Keyword: "keyword b", SearchVolume: 1, QueryJobIDs: []uuid.UUID{b1.ID}},
+	}, sov.Keywords)
+
+	require.Equal(t, []types.DomainShareOfVoice{
+		{Domain: "b.com", Visibility: 1.95, Share: 60.94, ShareChange: 37.25, Trend: []float64{23.68, 60.94}},
+		{Domain: "a.com", Visibility: 0.95, Share: 29.69, ShareChange: -22.94, Trend: []float64{52.63, 29.69}},
+		{Domain: "c.com", Visibility: 0.3, Share: 9.37, ShareChange: -14.31, Trend: []float64{23.68, 9.37}},
+	}, sov.Domains)
+}
+
+func Test_ShareOfVoiceLimit(t *testing.T) {
+	now := time.Now()
+	queryJob := types.QueryJob{ID: uuid.Must(uuid.NewV4()), Keyword: "keyword", CompletedAt: &now}
+	location := uuid.Must(uuid.NewV4())
+
+	rankings := []types.LocationRanking{
+		{QueryJobID: queryJob.ID, QueryLocationID: location, Position: 1, URL: "https://a.com"},
+		{QueryJobID: queryJob.ID, QueryLocationID: location, Position: 2, URL: "https://b.com"},
+		{QueryJobID: queryJob.ID, QueryLocationID: location, Position: 3, URL: "https://c.com"},
+	}
+
+	sov := serpanalysis.ShareOfVoice([]types.QueryJob{queryJob}, rankings, serpanalysis.DefaultCTRCurve, nil, 2)
+
+	require.Equal(t, 1, sov.Snapshots)
+	require.Len(t, sov.Domains, 2)
+	require.Equal(t, "a.com", sov.Domains[0].Domain)
+	require.Equal(t, "b.com", sov.Domains[1].Domain)
+	require.Equal(t, 0.0, sov.Domains[0].ShareChange)
+	require.Equal(t, []float64{sov.Domains[0].Share}, sov.Domains[0].Trend)
+}
@@ -0,0 +1,103 @@
+package serpanalysis
+
+import (
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/types"
+)
+
+// TargetRankings finds where the target domain (or its subdomains) ranks in every query location. A location
+// where the domain isn't in the results gets a nil position, and it's cannibalized when more than one URL of
+// the domain ranks in it.
+func TargetRankings(targetDomain string, queryLocations []types.QueryLocation, rankings []types.LocationRanking) []types.TargetRanking {
+	locationURLs := map[uuid.UUID][]string{}
+	locationPositions := map[uuid.UUID]int{}
+
+	// rankings are sorted by position so the first match of a location is its best position
+	for _, ranking := range sortedByPosition(rankings) {
+		if !MatchesDomain(Domain(ranking.URL), targetDomain) {
+			continue
+		}
+
+		if _, found := locationPositions[ranking.QueryLocationID]; !found {
+			locationPositions[ranking.QueryLocationID] = ranking.Position
+		}
+
+		if !containsString(locationURLs[ranking.QueryLocationID], ranking.URL) {
+			locationURLs[ranking.QueryLocationID] = append(locationURLs[ranking.QueryLocationID], ranking.URL)
+		}
+	}
+
+	targetRankings := []types.TargetRanking{}
+
+	for _, queryLocation := range queryLocations {
+		targetRanking := types.TargetRanking{
+			QueryLocationID: queryLocation.ID,
+			URLs:            []string{},
+		}
+
+		if position, found := locationPositions[queryLocation.ID]; found {
+			urls := locationURLs[queryLocation.ID]
+
+			targetRanking.Position = &position
+			targetRanking.URL = &urls[0]
+			targetRanking.URLs = urls
+			targetRanking.Cannibalized = len(urls) > 1
+		}
+
+		targetRankings = append(targetRankings, targetRanking)
+	}
+
+	return targetRankings
+}
+
+// SummarizeTargetRankings returns the best position of the target domain across locations and every URL of it
+// that ranks. Multiple ranking URLs, whether in the same location or in different ones, compete for the keyword.
+func SummarizeTargetRankings(targetRankings []types.TargetRanking) types.TargetSummary {
+	summary := types.TargetSummary{
+		URLs: []string{},
+	}
+
+	for _, targetRanking := range targetRankings {
+		if targetRanking.Position == nil {
+			continue
+		}
+
+		if summary.BestPosition == nil || *targetRanking.Position < *summary.BestPosition {
+			position := *targetRanking.Position
+			summary.BestPosition = &position
+		}
+
+		for _, url := range targetRanking.URLs {
+			if !containsString(summary.URLs, url) {
+				summary.URLs = append(summary.URLs, url)
+			}
+		}
+	}
+
+	summary.Cannibalized = len(summary.URLs) > 1
+
+	return summary
+}
+
+func sortedByPosition(rankings []types.LocationRanking) []types.LocationRanking {
+	sorted := make([]types.LocationRanking, len(rankings))
+	copy(sorted, rankings)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Position < sorted[j].Position
+	})
+
+	return sorted
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,67 @@
+package serpanalysis_test
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/serpanalysis"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_TargetRankings(t *testing.T) {
+	austin := uuid.Must(uuid.NewV4())
+	denver := uuid.Must(uuid.NewV4())
+	miami := uuid.Must(uuid.NewV4())
+
+	queryLocations := []types.QueryLocation{
+		{ID: austin, Location: "Austin"},
+		{ID: denver, Location: "Denver"},
+		{ID: miami, Location: "Miami"},
+	}
+
+	rankings := []types.LocationRanking{
+		{QueryLocationID: austin, Position: 1, URL: "https://competitor.com/shoes"},
+		{QueryLocationID: austin, Position: 4, URL: "https://www.example.com/shoes"},
+		{QueryLocationID: denver, Position: 7, URL: "https://example.com/blog/shoes"},
+		{QueryLocationID: denver, Position: 2, URL: "https://shop.example.com/shoes"},
+		{QueryLocationID: denver, Position: 9, URL: "https://notexample.com/shoes"},
+		{QueryLocationID: miami, Position: 1, URL: "https://competitor.com/shoes"},
+	}
+
+	targetRankings := serpanalysis.TargetRankings("example.com", queryLocations, rankings)
+	require.Len(t, targetRankings, 3)
+
+	require.Equal(t, austin, targetRankings[0].QueryLocationID)
+	require.Equal(t, 4, *targetRankings[0].Position)
+	require.Equal(t, "https://www.example.com/shoes", *targetRankings[0].URL)
+	require.Equal(t, []string{"https://www.example.com/shoes"}, targetRankings[0].URLs)
+	require.False(t, targetRankings[0].Cannibalized)
+
+	require.Equal(t, denver, targetRankings[1].QueryLocationID)
+	require.Equal(t, 2, *targetRankings[1].Position)
+	require.Equal(t, "https://shop.example.com/shoes", *targetRankings[1].URL)
+	require.Equal(t, []string{"https://shop.example.com/shoes", "https://example.com/blog/shoes"}, targetRankings[1].URLs)
+	require.True(t, targetRankings[1].Cannibalized)
+
+	require.Equal(t, miami, targetRankings[2].QueryLocationID)
+	require.Nil(t, targetRankings[2].Position)
+	require.Nil(t, targetRankings[2].URL)
+	require.Empty(t, targetRankings[2].URLs)
+	require.False(t, targetRankings[2].Cannibalized)
+
+	summary := serpanalysis.SummarizeTargetRankings(targetRankings)
+	require.Equal(t, 2, *summary.BestPosition)
+	require.Equal(t, []string{"https://www.example.com/shoes", "https://shop.example.com/shoes", "https://example.com/blog/shoes"}, summary.URLs)
+	require.True(t, summary.Cannibalized)
+}
+
+func Test_SummarizeTargetRankingsNotRanking(t *testing.T) {
+	summary := serpanalysis.SummarizeTargetRankings([]types.TargetRanking{
+		{QueryLocationID: uuid.Must(uuid.NewV4()), URLs: []string{}},
+	})
+
+	require.Nil(t, summary.BestPosition)
+	require.Empty(t, summary.URLs)
+	require.False(t, summary.Cannibalized)
+}
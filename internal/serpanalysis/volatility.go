@@ -0,0 +1,150 @@
+package serpanalysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/types"
+)
+
+// LocationVolatility reports how much the rankings of a query job differ between its query locations.
+// Every URL gets position statistics across the query locations it ranks in and the locations it is
+// missing from, and the consistency score is the average rank-biased overlap of every pair of locations.
+func LocationVolatility(queryLocations []types.QueryLocation, rankings []types.LocationRanking) *types.LocationVolatility {
+	labels := LocationLabels(queryLocations)
+
+	// Ordered, deduplicated URLs per query location
+	rankedURLs := map[uuid.UUID][]string{}
+	seen := map[uuid.UUID]map[string]bool{}
+	positions := map[string]map[uuid.UUID]int{}
+
+	sorted := make([]types.LocationRanking, len(rankings))
+	copy(sorted, rankings)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Position < sorted[j].Position
+	})
+
+	for _, ranking := range sorted {
+		if seen[ranking.QueryLocationID] == nil {
+			seen[ranking.QueryLocationID] = map[string]bool{}
+		}
+
+		if seen[ranking.QueryLocationID][ranking.URL] {
+			continue
+		}
+
+		seen[ranking.QueryLocationID][ranking.URL] = true
+		rankedURLs[ranking.QueryLocationID] = append(rankedURLs[ranking.QueryLocationID], ranking.URL)
+
+		if positions[ranking.URL] == nil {
+			positions[ranking.URL] = map[uuid.UUID]int{}
+		}
+		positions[ranking.URL][ranking.QueryLocationID] = ranking.Position
+	}
+
+	volatility := &types.LocationVolatility{
+		LocationPairs: []types.LocationPairOverlap{},
+		URLs:          []types.URLVolatility{},
+	}
+
+	// Pairwise overlap between query locations
+	totalRBO := 0.0
+	for i := 0; i < len(queryLocations); i++ {
+		for j := i + 1; j < len(queryLocations); j++ {
+			a, b := queryLocations[i], queryLocations[j]
+			rbo := round(RankBiasedOverlap(rankedURLs[a.ID], rankedURLs[b.ID]))
+			totalRBO += rbo
+
+			volatility.LocationPairs = append(volatility.LocationPairs, types.LocationPairOverlap{
+				LocationA: labels[a.ID],
+				LocationB: labels[b.ID],
+				RBO:       rbo,
+			})
+		}
+	}
+
+	if len(volatility.LocationPairs) > 0 {
+		volatility.ConsistencyScore = round(totalRBO / float64(len(volatility.LocationPairs)))
+	}
+
+	// Position statistics per URL
+	for url, urlPositions := range positions {
+		values := []float64{}
+		minPosition, maxPosition := math.MaxInt32, 0
+
+		for _, position := range urlPositions {
+			values = append(values, float64(position))
+			if position < minPosition {
+				minPosition = position
+			}
+			if position > maxPosition {
+				maxPosition = position
+			}
+		}
+
+		missing := []string{}
+		for _, queryLocation := range queryLocations {
+			if _, found := urlPositions[queryLocation.ID]; !found {
+				missing = append(missing, labels[queryLocation.ID])
+			}
+		}
+
+		volatility.URLs = append(volatility.URLs, types.URLVolatility{
+			URL:               url,
+			MinPosition:       minPosition,
+			MaxPosition:       maxPosition,
+			AvgPosition:       round(mean(values)),
+			StdDevPosition:    round(stdDev(values)),
+			LocationHitsCount: len(values),
+			MissingLocations:  missing,
+		})
+	}
+
+	sort.Slice(volatility.URLs, func(i, j int) bool {
+		a, b := volatility.URLs[i], volatility.URLs[j]
+		if a.LocationHitsCount != b.LocationHitsCount {
+			return a.LocationHitsCount > b.LocationHitsCount
+		}
+		if a.AvgPosition != b.AvgPosition {
+			return a.AvgPosition < b.AvgPosition
+		}
+		return a.URL < b.URL
+	})
+
+	return volatility
+}
+
+// LocationLabels returns a readable name for every query location. The device is only included when the
+// query locations span more than one device.
+func LocationLabels(queryLocations []types.QueryLocation) map[uuid.UUID]string {
+	devices := map[string]bool{}
+	for _, queryLocation := range queryLocations {
+		devices[queryLocation.Device] = true
+	}
+
+	labels := map[uuid.UUID]string{}
+	for _, queryLocation := range queryLocations {
+		labels[queryLocation.ID] = queryLocation.Location
+		if len(devices) > 1 {
+			labels[queryLocation.ID] = fmt.Sprintf("%s (%s)", queryLocation.Location, queryLocation.Device)
+		}
+	}
+
+	return labels
+}
+
+func stdDev(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	m := mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+
+	return math.Sqrt(sum / float64(len(values)))
+}
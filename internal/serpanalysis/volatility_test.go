@@ -0,0 +1,61 @@
+package serpanalysis_test
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/serpanalysis"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RankBiasedOverlap(t *testing.T) {
+	require.InDelta(t, 1, serpanalysis.RankBiasedOverlap([]string{"a", "b", "c"}, []string{"a", "b", "c"}), 0.0001)
+	require.InDelta(t, 0, serpanalysis.RankBiasedOverlap([]string{"a", "b", "c"}, []string{"d", "e", "f"}), 0.0001)
+	require.InDelta(t, 0, serpanalysis.RankBiasedOverlap([]string{}, []string{"a"}), 0.0001)
+
+	// Swapping the top results costs more than swapping the bottom results
+	topSwapped := serpanalysis.RankBiasedOverlap([]string{"a", "b", "c", "d"}, []string{"b", "a", "c", "d"})
+	bottomSwapped := serpanalysis.RankBiasedOverlap([]string{"a", "b", "c", "d"}, []string{"a", "b", "d", "c"})
+	require.Less(t, topSwapped, bottomSwapped)
+	require.Less(t, bottomSwapped, 1.0)
+}
+
+func Test_LocationVolatility(t *testing.T) {
+	austin := types.QueryLocation{ID: uuid.Must(uuid.NewV4()), Location: "Austin", Device: "desktop"}
+	denton := types.QueryLocation{ID: uuid.Must(uuid.NewV4()), Location: "Denton", Device: "desktop"}
+	kingfield := types.QueryLocation{ID: uuid.Must(uuid.NewV4()), Location: "Kingfield", Device: "desktop"}
+
+	rankings := []types.LocationRanking{
+		{QueryLocationID: austin.ID, Position: 1, URL: "https://a.com"},
+		{QueryLocationID: austin.ID, Position: 2, URL: "https://b.com"},
+		{QueryLocationID: denton.ID, Position: 1, URL: "https://a.com"},
+		{QueryLocationID: denton.ID, Position: 2, URL: "https://b.com"},
+		{QueryLocationID: kingfield.ID, Position: 3, URL: "https://a.com"},
+		{QueryLocationID: kingfield.ID, Position: 1, URL: "https://c.com"},
+	}
+
+	volatility := serpanalysis.LocationVolatility([]types.QueryLocation{austin, denton, kingfield}, rankings)
+
+	require.Len(t, volatility.LocationPairs, 3)
+	require.Equal(t, "Austin", volatility.LocationPairs[0].LocationA)
+	require.Equal(t, "Denton", volatility.LocationPairs[0].LocationB)
+	require.Equal(t, 1.0, volatility.LocationPairs[0].RBO)
+	require.Less(t, volatility.ConsistencyScore, 1.0)
+	require.Greater(t, volatility.ConsistencyScore, 0.0)
+
+	require.Len(t, volatility.URLs, 3)
+	require.Equal(t, types.URLVolatility{
+		URL:               "https://a.com",
+		MinPosition:       1,
+		MaxPosition:       3,
+		AvgPosition:       1.67,
+		StdDevPosition:    0.94,
+		LocationHitsCount: 3,
+		MissingLocations:  []string{},
+	}, volatility.URLs[0])
+	require.Equal(t, "https://b.com", volatility.URLs[1].URL)
+	require.Equal(t, []string{"Kingfield"}, volatility.URLs[1].MissingLocations)
+	require.Equal(t, "https://c.com", volatility.URLs[2].URL)
+	require.Equal(t, []string{"Austin", "Denton"}, volatility.URLs[2].MissingLocations)
+}
@@ -0,0 +1,126 @@
+package serpanalysis
+
+import (
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/types"
+)
+
+const topTen = 10
+
+// WatchedDomainPositions compares the best positions of the watched domains between a query job and the
+// previous query job of the same keyword. Domains missing from the positions don't rank. Entering or
+// dropping out of the top 10 is only flagged when there's a previous query job to compare with.
+func WatchedDomainPositions(domains []string, positions, previousPositions map[string]int, hasPrevious bool) []types.WatchedDomainPosition {
+	watched := []types.WatchedDomainPosition{}
+
+	for _, domain := range domains {
+		entry := types.WatchedDomainPosition{Domain: domain}
+
+		if position, found := positions[domain]; found {
+			p := position
+			entry.BestPosition = &p
+		}
+
+		if position, found := previousPositions[domain]; found {
+			p := position
+			entry.PreviousBestPosition = &p
+		}
+
+		if hasPrevious {
+			inTopTen := entry.BestPosition != nil && *entry.BestPosition <= topTen
+			wasInTopTen := entry.PreviousBestPosition != nil && *entry.PreviousBestPosition <= topTen
+
+			entry.EnteredTop10 = inTopTen && !wasInTopTen
+			entry.DroppedTop10 = !inTopTen && wasInTopTen
+		}
+
+		watched = append(watched, entry)
+	}
+
+	return watched
+}
+
+// WatchlistAlerts returns an alert for every watched domain that entered or dropped out of the top 10 in the query job
+func WatchlistAlerts(watchlist types.Watchlist, queryJob types.QueryJob, watched []types.WatchedDomainPosition) []types.WatchlistAlert {
+	alerts := []types.WatchlistAlert{}
+
+	for _, entry := range watched {
+		alertType := ""
+		switch {
+		case entry.EnteredTop10:
+			alertType = types.AlertTypeEnteredTop10
+		case entry.DroppedTop10:
+			alertType = types.AlertTypeDroppedTop10
+		default:
+			continue
+		}
+
+		queryJobID := queryJob.ID
+		alerts = append(alerts, types.WatchlistAlert{
+			WatchlistID:      watchlist.ID,
+			QueryJobID:       &queryJobID,
+			Keyword:          queryJob.Keyword,
+			Domain:           entry.Domain,
+			AlertType:        alertType,
+			Position:         entry.BestPosition,
+			PreviousPosition: entry.PreviousBestPosition,
+		})
+	}
+
+	return alerts
+}
+
+// WatchlistReport reports the best position of every watched domain for every keyword, based on the latest
+// completed query job of the keyword and compared to the one before it. completedQueryJobs must be ordered
+// by completion time.
+func WatchlistReport(watchlist types.Watchlist, completedQueryJobs []types.QueryJob, positions []types.DomainBestPosition) *types.WatchlistReport {
+	jobPositions := map[uuid.UUID]map[string]int{}
+	for _, position := range positions {
+		if jobPositions[position.QueryJobID] == nil {
+			jobPositions[position.QueryJobID] = map[string]int{}
+		}
+		jobPositions[position.QueryJobID][position.Domain] = position.BestPosition
+	}
+
+	keywordJobs := map[string][]types.QueryJob{}
+	keywords := []string{}
+	for _, queryJob := range completedQueryJobs {
+		keyword := NormalizeKeyword(queryJob.Keyword)
+		if _, found := keywordJobs[keyword]; !found {
+			keywords = append(keywords, keyword)
+		}
+		keywordJobs[keyword] = append(keywordJobs[keyword], queryJob)
+	}
+
+	sort.Strings(keywords)
+
+	report := &types.WatchlistReport{
+		Watchlist: watchlist,
+		Keywords:  []types.WatchlistKeywordReport{},
+	}
+
+	for _, keyword := range keywords {
+		jobs := keywordJobs[keyword]
+		latest := jobs[len(jobs)-1]
+
+		keywordReport := types.WatchlistKeywordReport{
+			Keyword:     latest.Keyword,
+			QueryJobID:  latest.ID,
+			CompletedAt: *latest.CompletedAt,
+		}
+
+		var previousPositions map[string]int
+		if len(jobs) > 1 {
+			previous := jobs[len(jobs)-2]
+			keywordReport.PreviousQueryJobID = &previous.ID
+			previousPositions = jobPositions[previous.ID]
+		}
+
+		keywordReport.Domains = WatchedDomainPositions(watchlist.Domains, jobPositions[latest.ID], previousPositions, len(jobs) > 1)
+		report.Keywords = append(report.Keywords, keywordReport)
+	}
+
+	return report
+}
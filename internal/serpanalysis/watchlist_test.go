@@ -0,0 +1,70 @@
+package serpanalysis_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/serpanalysis"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NormalizeDomain(t *testing.T) {
+	require.Equal(t, "example.com", serpanalysis.NormalizeDomain("https://www.Example.com/path?q=1"))
+	require.Equal(t, "example.com", serpanalysis.NormalizeDomain(" example.com "))
+	require.Equal(t, "blog.example.com", serpanalysis.NormalizeDomain("blog.example.com:8080"))
+	require.True(t, serpanalysis.MatchesDomain("blog.example.com", "example.com"))
+	require.False(t, serpanalysis.MatchesDomain("notexample.com", "example.com"))
+}
+
+func Test_WatchlistReport(t *testing.T) {
+	completedAt := func(days int) *time.Time {
+		t := time.Date(2022, 1, days, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	firstShoes := types.QueryJob{ID: uuid.Must(uuid.NewV4()), Keyword: "Running Shoes", CompletedAt: completedAt(1)}
+	recipes := types.QueryJob{ID: uuid.Must(uuid.NewV4()), Keyword: "pasta recipes", CompletedAt: completedAt(2)}
+	secondShoes := types.QueryJob{ID: uuid.Must(uuid.NewV4()), Keyword: "running shoes", CompletedAt: completedAt(3)}
+
+	watchlist := types.Watchlist{ID: uuid.Must(uuid.NewV4()), Domains: []string{"nike.com", "adidas.com", "asics.com"}}
+
+	positions := []types.DomainBestPosition{
+		{QueryJobID: firstShoes.ID, Domain: "nike.com", BestPosition: 3},
+		{QueryJobID: firstShoes.ID, Domain: "adidas.com", BestPosition: 15},
+		{QueryJobID: secondShoes.ID, Domain: "nike.com", BestPosition: 12},
+		{QueryJobID: secondShoes.ID, Domain: "adidas.com", BestPosition: 8},
+		{QueryJobID: recipes.ID, Domain: "asics.com", BestPosition: 1},
+	}
+
+	report := serpanalysis.WatchlistReport(watchlist, []types.QueryJob{firstShoes, recipes, secondShoes}, positions)
+
+	require.Len(t, report.Keywords, 2)
+
+	recipesReport := report.Keywords[0]
+	require.Equal(t, recipes.ID, recipesReport.QueryJobID)
+	require.Nil(t, recipesReport.PreviousQueryJobID)
+	require.Equal(t, 1, *recipesReport.Domains[2].BestPosition)
+	require.False(t, recipesReport.Domains[2].EnteredTop10)
+
+	shoesReport := report.Keywords[1]
+	require.Equal(t, secondShoes.ID, shoesReport.QueryJobID)
+	require.Equal(t, firstShoes.ID, *shoesReport.PreviousQueryJobID)
+
+	nike, adidas, asics := shoesReport.Domains[0], shoesReport.Domains[1], shoesReport.Domains[2]
+	require.True(t, nike.DroppedTop10)
+	require.False(t, nike.EnteredTop10)
+	require.True(t, adidas.EnteredTop10)
+	require.Nil(t, asics.BestPosition)
+	require.False(t, asics.EnteredTop10 || asics.DroppedTop10)
+
+	alerts := serpanalysis.WatchlistAlerts(watchlist, secondShoes, shoesReport.Domains)
+	require.Len(t, alerts, 2)
+	require.Equal(t, types.AlertTypeDroppedTop10, alerts[0].AlertType)
+	require.Equal(t, "nike.com", alerts[0].Domain)
+	require.Equal(t, 12, *alerts[0].Position)
+	require.Equal(t, 3, *alerts[0].PreviousPosition)
+	require.Equal(t, types.AlertTypeEnteredTop10, alerts[1].AlertType)
+	require.Equal(t, "adidas.com", alerts[1].Domain)
+}
@@ -4,26 +4,62 @@ import (
 	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
 )
 
 type QueryJob struct {
-	ID                    uuid.UUID  `db:"id" json:"id"`
-	Keyword               string     `db:"keyword" json:"keyword"`
-	CompletedAt           *time.Time `db:"completed_at" json:"completed_at"`
-	ZenserpBatchID        *string    `db:"zenserp_batch_id" json:"zenserp_batch_id"`
-	ZenserpBatchProcessed bool       `db:"zenserp_batch_processed" json:"zenserp_batch_processed"`
-	CreatedAt             time.Time  `db:"created_at" json:"created_at"`
+	ID                    uuid.UUID      `db:"id" json:"id"`
+	Keyword               string         `db:"keyword" json:"keyword"`
+	CompletedAt           *time.Time     `db:"completed_at" json:"completed_at"`
+	ZenserpBatchID        *string        `db:"zenserp_batch_id" json:"zenserp_batch_id"`
+	ZenserpBatchProcessed bool           `db:"zenserp_batch_processed" json:"zenserp_batch_processed"`
+	UserID                *string        `db:"user_id" json:"user_id"`
+	SerpRequestCount      int            `db:"serp_request_count" json:"serp_request_count"`
+	DeviceComparison      bool           `db:"device_comparison" json:"device_comparison"`
+	TargetDomain          *string        `db:"target_domain" json:"target_domain"`
+	TargetBestPosition    *int           `db:"target_best_position" json:"target_best_position"`
+	TargetURLs            pq.StringArray `db:"target_urls" json:"target_urls"`
+	TargetCannibalized    bool           `db:"target_cannibalized" json:"target_cannibalized"`
+	FailedAt              *time.Time     `db:"failed_at" json:"failed_at"`
+	FailureReason         *string        `db:"failure_reason" json:"failure_reason"`
+	RecrawlStartedAt      *time.Time     `db:"recrawl_started_at" json:"recrawl_started_at"`
+	RecrawlCompletedAt    *time.Time     `db:"recrawl_completed_at" json:"recrawl_completed_at"`
+	CreatedAt             time.Time      `db:"created_at" json:"created_at"`
+}
+
+// NewQueryJob is a query job to create along with its query locations
+type NewQueryJob struct {
+	Keyword          string
+	UserID           *string
+	DeviceComparison bool
+	TargetDomain     *string
+	Locations        []NewQueryLocation
+}
+
+// NewQueryLocation is a query location to create, the cache key identifies its search in the SERP cache
+type NewQueryLocation struct {
+	Device       string
+	SearchEngine string
+	Num          string
+	Country      string
+	Location     string
+	CacheKey     string
 }
 
 type QueryLocation struct {
-	ID           uuid.UUID `db:"id"`
-	QueryJobID   uuid.UUID `db:"query_job_id"`
-	Device       string    `db:"device"`
-	SearchEngine string    `db:"search_engine"`
-	Num          string    `db:"num"`
-	Country      string    `db:"country"`
-	Location     string    `db:"location"`
-	CreatedAt    time.Time `db:"created_at"`
+	ID                 uuid.UUID      `db:"id" json:"id"`
+	QueryJobID         uuid.UUID      `db:"query_job_id" json:"query_job_id"`
+	Device             string         `db:"device" json:"device"`
+	SearchEngine       string         `db:"search_engine" json:"search_engine"`
+	Num                string         `db:"num" json:"num"`
+	Country            string         `db:"country" json:"country"`
+	Location           string         `db:"location" json:"location"`
+	Cached             bool           `db:"cached" json:"cached"`
+	TargetPosition     *int           `db:"target_position" json:"target_position"`
+	TargetURL          *string        `db:"target_url" json:"target_url"`
+	TargetURLs         pq.StringArray `db:"target_urls" json:"target_urls"`
+	TargetCannibalized bool           `db:"target_cannibalized" json:"target_cannibalized"`
+	CreatedAt          time.Time      `db:"created_at" json:"created_at"`
 }
 
 type QueryItem struct {
@@ -34,9 +70,24 @@ type QueryItem struct {
 	Title           string     `db:"title"`
 	URL             string     `db:"url"`
 	Body            *string    `db:"body"`
+	MainContent     *string    `db:"main_content"`
 	ProcessedAt     *time.Time `db:"processed_at"`
 	CreatedAt       time.Time  `db:"created_at"`
 	ErrorProcessing bool       `db:"error_processing"`
+	Blocked         bool       `db:"blocked"`
+	PageID          *uuid.UUID `db:"page_id"`
+}
+
+// Page is a snapshot of a scrape of a URL, shared by the query items of every query job crawled with it. The body
+// is loaded separately
+type Page struct {
+	ID          uuid.UUID      `db:"id" json:"id"`
+	URL         string         `db:"url" json:"url"`
+	Title       string         `db:"title" json:"title"`
+	Description string         `db:"description" json:"description"`
+	H1          pq.StringArray `db:"h1" json:"h1"`
+	ScrapedAt   time.Time      `db:"scraped_at" json:"scraped_at"`
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
 }
 
 type QueryJobPositionHit struct {
@@ -45,14 +96,529 @@ type QueryJobPositionHit struct {
 	LocationHitsCount int     `db:"location_hits_count" json:"location_hits_count"`
 }
 
+// FetchDiagnostic describes the last fetch of a url of a query job. A nil status code means no response was
+// received, and a nil failure category means the fetch succeeded.
+type FetchDiagnostic struct {
+	QueryJobID      uuid.UUID      `db:"query_job_id" json:"query_job_id"`
+	URL             string         `db:"url" json:"url"`
+	Cached          bool           `db:"cached" json:"cached"`
+	StatusCode      *int           `db:"status_code" json:"status_code"`
+	FinalURL        string         `db:"final_url" json:"final_url"`
+	Redirects       pq.StringArray `db:"redirects" json:"redirects"`
+	ResponseTimeMs  int            `db:"response_time_ms" json:"response_time_ms"`
+	ContentLength   *int64         `db:"content_length" json:"content_length"`
+	ContentType     string         `db:"content_type" json:"content_type"`
+	Error           *string        `db:"error" json:"error"`
+	FailureCategory *string        `db:"failure_category" json:"failure_category"`
+	FetchedAt       time.Time      `db:"fetched_at" json:"fetched_at"`
+	Attempt         int            `db:"attempt" json:"attempt"`
+	Rendered        bool           `db:"rendered" json:"rendered"`
+}
+
+// ScrapeRetry is a scrape of a url of a query job due to be queued again at RetryAt
+type ScrapeRetry struct {
+	QueryJobID    uuid.UUID `db:"query_job_id"`
+	URL           string    `db:"url"`
+	Attempt       int       `db:"attempt"`
+	SkipPageCache bool      `db:"skip_page_cache"`
+	RetryAt       time.Time `db:"retry_at"`
+}
+
 type UrlInfo struct {
-	Title string `json:"title"`
-	URL   string `json:"url"`
-	Body  string `json:"body"`
-	Links []Link `json:"links"`
+	Title       string           `json:"title"`
+	URL         string           `json:"url"`
+	Body        string           `json:"body"`
+	MainContent string           `json:"main_content"`
+	Links       []Link           `json:"links"`
+	Fetch       *FetchDiagnostic `json:"fetch"`
 }
 
 type Link struct {
 	Text string `db:"text" json:"text"`
 	URL  string `db:"url" json:"url"`
 }
+
+type ZenserpUserUsage struct {
+	UserID       *string `db:"user_id" json:"user_id"`
+	RequestCount int     `db:"request_count" json:"request_count"`
+}
+
+type LocationRanking struct {
+	QueryJobID      uuid.UUID `db:"query_job_id" json:"query_job_id"`
+	QueryLocationID uuid.UUID `db:"query_location_id" json:"query_location_id"`
+	Device          string    `db:"device" json:"device"`
+	Location        string    `db:"location" json:"location"`
+	Position        int       `db:"position" json:"position"`
+	URL             string    `db:"url" json:"url"`
+	Title           string    `db:"title" json:"title"`
+}
+
+type DeviceComparison struct {
+	DesktopURLCount int                   `json:"desktop_url_count"`
+	MobileURLCount  int                   `json:"mobile_url_count"`
+	SharedURLCount  int                   `json:"shared_url_count"`
+	Overlap         float64               `json:"overlap"`
+	SharedURLs      []DeviceURLComparison `json:"shared_urls"`
+	DesktopOnlyURLs []DeviceURLPosition   `json:"desktop_only_urls"`
+	MobileOnlyURLs  []DeviceURLPosition   `json:"mobile_only_urls"`
+}
+
+type DeviceURLComparison struct {
+	URL                string  `json:"url"`
+	DesktopAvgPosition float64 `json:"desktop_avg_position"`
+	MobileAvgPosition  float64 `json:"mobile_avg_position"`
+	PositionDelta      float64 `json:"position_delta"`
+}
+
+type DeviceURLPosition struct {
+	URL               string  `json:"url"`
+	AvgPosition       float64 `json:"avg_position"`
+	LocationHitsCount int     `json:"location_hits_count"`
+}
+
+type LocationVolatility struct {
+	ConsistencyScore float64               `json:"consistency_score"`
+	LocationPairs    []LocationPairOverlap `json:"location_pairs"`
+	URLs             []URLVolatility       `json:"urls"`
+}
+
+type LocationPairOverlap struct {
+	LocationA string  `json:"location_a"`
+	LocationB string  `json:"location_b"`
+	RBO       float64 `json:"rbo"`
+}
+
+type URLVolatility struct {
+	URL               string   `json:"url"`
+	MinPosition       int      `json:"min_position"`
+	MaxPosition       int      `json:"max_position"`
+	AvgPosition       float64  `json:"avg_position"`
+	StdDevPosition    float64  `json:"stddev_position"`
+	LocationHitsCount int      `json:"location_hits_count"`
+	MissingLocations  []string `json:"missing_locations"`
+}
+
+type KeywordClustering struct {
+	TopN          int                  `json:"top_n"`
+	MinSharedURLs int                  `json:"min_shared_urls"`
+	Clusters      []KeywordCluster     `json:"clusters"`
+	KeywordPairs  []KeywordPairOverlap `json:"keyword_pairs"`
+}
+
+type KeywordCluster struct {
+	PrimaryKeyword string           `json:"primary_keyword"`
+	Keywords       []ClusterKeyword `json:"keywords"`
+	SharedURLs     []string         `json:"shared_urls"`
+}
+
+type ClusterKeyword struct {
+	QueryJobID uuid.UUID `json:"query_job_id"`
+	Keyword    string    `json:"keyword"`
+}
+
+type KeywordPairOverlap struct {
+	KeywordA       string `json:"keyword_a"`
+	KeywordB       string `json:"keyword_b"`
+	SharedURLCount int    `json:"shared_url_count"`
+}
+
+// Content scopes of the analytics, the main content of a page leaves out its navigation, banners, sidebars and
+// footers
+const (
+	ContentScopeMain = "main"
+	ContentScopeFull = "full"
+)
+
+type URLContent struct {
+	URL        string `db:"url" json:"url"`
+	Title      string `db:"title" json:"title"`
+	BodyLength int    `db:"body_length" json:"body_length"`
+}
+
+type QueryJobComparison struct {
+	QueryJobID      uuid.UUID            `json:"query_job_id"`
+	OtherQueryJobID uuid.UUID            `json:"other_query_job_id"`
+	Locations       []LocationComparison `json:"locations"`
+	ContentChanges  []URLContentChange   `json:"content_changes"`
+}
+
+type LocationComparison struct {
+	Location string              `json:"location"`
+	Device   string              `json:"device"`
+	Entered  []RankedURL         `json:"entered"`
+	Dropped  []RankedURL         `json:"dropped"`
+	Moved    []URLPositionChange `json:"moved"`
+}
+
+type RankedURL struct {
+	URL      string `json:"url"`
+	Position int    `json:"position"`
+}
+
+type URLPositionChange struct {
+	URL            string `json:"url"`
+	Position       int    `json:"position"`
+	OtherPosition  int    `json:"other_position"`
+	PositionChange int    `json:"position_change"`
+}
+
+type URLContentChange struct {
+	URL              string `json:"url"`
+	Title            string `json:"title"`
+	OtherTitle       string `json:"other_title"`
+	TitleChanged     bool   `json:"title_changed"`
+	BodyLength       int    `json:"body_length"`
+	OtherBodyLength  int    `json:"other_body_length"`
+	BodyLengthChange int    `json:"body_length_change"`
+}
+
+type PageSnapshot struct {
+	ID          uuid.UUID  `db:"id" json:"id"`
+	URL         string     `db:"url" json:"url"`
+	QueryJobID  *uuid.UUID `db:"query_job_id" json:"query_job_id"`
+	ContentHash string     `db:"content_hash" json:"content_hash"`
+	Title       string     `db:"title" json:"title"`
+	Content     string     `db:"content" json:"-"`
+	WordCount   int        `db:"word_count" json:"word_count"`
+	FirstSeenAt time.Time  `db:"first_seen_at" json:"first_seen_at"`
+	LastSeenAt  time.Time  `db:"last_seen_at" json:"last_seen_at"`
+}
+
+type PageChange struct {
+	URL                  string    `json:"url"`
+	PreviousSeenAt       time.Time `json:"previous_seen_at"`
+	ChangedAt            time.Time `json:"changed_at"`
+	PreviousTitle        string    `json:"previous_title"`
+	Title                string    `json:"title"`
+	TitleChanged         bool      `json:"title_changed"`
+	PreviousWordCount    int       `json:"previous_word_count"`
+	WordCount            int       `json:"word_count"`
+	WordCountDelta       int       `json:"word_count_delta"`
+	AddedSectionsCount   int       `json:"added_sections_count"`
+	RemovedSectionsCount int       `json:"removed_sections_count"`
+	AddedSections        []string  `json:"added_sections"`
+	RemovedSections      []string  `json:"removed_sections"`
+}
+
+type Watchlist struct {
+	ID         uuid.UUID      `db:"id" json:"id"`
+	Name       string         `db:"name" json:"name"`
+	Domains    pq.StringArray `db:"domains" json:"domains"`
+	WebhookURL *string        `db:"webhook_url" json:"webhook_url"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+}
+
+const (
+	AlertTypeEnteredTop10 = "entered_top_10"
+	AlertTypeDroppedTop10 = "dropped_top_10"
+)
+
+type WatchlistAlert struct {
+	ID               uuid.UUID  `db:"id" json:"id"`
+	WatchlistID      uuid.UUID  `db:"watchlist_id" json:"watchlist_id"`
+	QueryJobID       *uuid.UUID `db:"query_job_id" json:"query_job_id"`
+	Keyword          string     `db:"keyword" json:"keyword"`
+	Domain           string     `db:"domain" json:"domain"`
+	AlertType        string     `db:"alert_type" json:"alert_type"`
+	Position         *int       `db:"position" json:"position"`
+	PreviousPosition *int       `db:"previous_position" json:"previous_position"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+}
+
+type DomainBestPosition struct {
+	QueryJobID   uuid.UUID `db:"query_job_id" json:"query_job_id"`
+	Domain       string    `db:"domain" json:"domain"`
+	BestPosition int       `db:"best_position" json:"best_position"`
+}
+
+type WatchlistReport struct {
+	Watchlist Watchlist                `json:"watchlist"`
+	Keywords  []WatchlistKeywordReport `json:"keywords"`
+}
+
+type WatchlistKeywordReport struct {
+	Keyword            string                  `json:"keyword"`
+	QueryJobID         uuid.UUID               `json:"query_job_id"`
+	CompletedAt        time.Time               `json:"completed_at"`
+	PreviousQueryJobID *uuid.UUID              `json:"previous_query_job_id"`
+	Domains            []WatchedDomainPosition `json:"domains"`
+}
+
+type WatchedDomainPosition struct {
+	Domain               string `json:"domain"`
+	BestPosition         *int   `json:"best_position"`
+	PreviousBestPosition *int   `json:"previous_best_position"`
+	EnteredTop10         bool   `json:"entered_top_10"`
+	DroppedTop10         bool   `json:"dropped_top_10"`
+}
+
+type TargetRanking struct {
+	QueryLocationID uuid.UUID `json:"query_location_id"`
+	Position        *int      `json:"position"`
+	URL             *string   `json:"url"`
+	URLs            []string  `json:"urls"`
+	Cannibalized    bool      `json:"cannibalized"`
+}
+
+type TargetSummary struct {
+	BestPosition *int     `json:"best_position"`
+	URLs         []string `json:"urls"`
+	Cannibalized bool     `json:"cannibalized"`
+}
+
+type ShareOfVoice struct {
+	CTRCurve  []float64             `json:"ctr_curve"`
+	Snapshots int                   `json:"snapshots"`
+	Keywords  []ShareOfVoiceKeyword `json:"keywords"`
+	Domains   []DomainShareOfVoice  `json:"domains"`
+}
+
+type ShareOfVoiceKeyword struct {
+	Keyword      string      `json:"keyword"`
+	SearchVolume int         `json:"search_volume"`
+	QueryJobIDs  []uuid.UUID `json:"query_job_ids"`
+}
+
+type DomainShareOfVoice struct {
+	Domain      string    `json:"domain"`
+	Visibility  float64   `json:"visibility"`
+	Share       float64   `json:"share"`
+	ShareChange float64   `json:"share_change"`
+	Trend       []float64 `json:"trend"`
+}
+
+const (
+	WebhookEventQueryJobCreated     = "query_job.created"
+	WebhookEventQueryJobSerpFetched = "query_job.serp_fetched"
+	WebhookEventQueryJobCompleted   = "query_job.completed"
+	WebhookEventQueryJobFailed      = "query_job.failed"
+	WebhookEventPing                = "ping"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+type WebhookEndpoint struct {
+	ID         uuid.UUID      `db:"id" json:"id"`
+	URL        string         `db:"url" json:"url"`
+	Secret     string         `db:"secret" json:"-"`
+	EventTypes pq.StringArray `db:"event_types" json:"event_types"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID                uuid.UUID  `db:"id" json:"id"`
+	WebhookEndpointID uuid.UUID  `db:"webhook_endpoint_id" json:"webhook_endpoint_id"`
+	EventType         string     `db:"event_type" json:"event_type"`
+	QueryJobID        *uuid.UUID `db:"query_job_id" json:"query_job_id"`
+	Payload           string     `db:"payload" json:"payload"`
+	Status            string     `db:"status" json:"status"`
+	Attempts          int        `db:"attempts" json:"attempts"`
+	ResponseStatus    *int       `db:"response_status" json:"response_status"`
+	Error             *string    `db:"error" json:"error"`
+	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
+	DeliveredAt       *time.Time `db:"delivered_at" json:"delivered_at"`
+}
+
+type ExportRanking struct {
+	QueryLocationID uuid.UUID `db:"query_location_id"`
+	Device          string    `db:"device"`
+	Country         string    `db:"country"`
+	Location        string    `db:"location"`
+	Cached          bool      `db:"cached"`
+	Position        int       `db:"position"`
+	URL             string    `db:"url"`
+	Title           string    `db:"title"`
+}
+
+type ExportURLStatus struct {
+	URL         string     `db:"url"`
+	Title       string     `db:"title"`
+	CrawlStatus string     `db:"crawl_status"`
+	ProcessedAt *time.Time `db:"processed_at"`
+	LinkCount   int        `db:"link_count"`
+}
+
+type KeywordReport struct {
+	QueryJob         QueryJob           `json:"query_job"`
+	GeneratedAt      time.Time          `json:"generated_at"`
+	TopCompetitors   []ReportCompetitor `json:"top_competitors"`
+	Locations        []ReportLocation   `json:"locations"`
+	CrawledPages     int                `json:"crawled_pages"`
+	MedianBodyLength int                `json:"median_body_length"`
+	BodyLengths      []ReportBucket     `json:"body_lengths"`
+	TitleWords       []ReportWordCount  `json:"title_words"`
+}
+
+type ReportCompetitor struct {
+	Domain        string  `json:"domain"`
+	BestPosition  int     `json:"best_position"`
+	AvgPosition   float64 `json:"avg_position"`
+	LocationCount int     `json:"location_count"`
+	URLCount      int     `json:"url_count"`
+	IsTarget      bool    `json:"is_target"`
+}
+
+type ReportLocation struct {
+	Device   string          `json:"device"`
+	Location string          `json:"location"`
+	Rankings []ReportRanking `json:"rankings"`
+}
+
+type ReportRanking struct {
+	Position int    `json:"position"`
+	URL      string `json:"url"`
+	Title    string `json:"title"`
+}
+
+type ReportBucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+type ReportWordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// ContentSearchResult is a crawled page matching a search phrase, the snippet is HTML escaped with its matches
+// wrapped in <mark> tags
+type ContentSearchResult struct {
+	QueryJobID    uuid.UUID `db:"query_job_id" json:"query_job_id"`
+	Keyword       string    `db:"keyword" json:"keyword"`
+	URL           string    `db:"url" json:"url"`
+	Title         string    `db:"title" json:"title"`
+	BestPosition  int       `db:"best_position" json:"best_position"`
+	LocationCount int       `db:"location_count" json:"location_count"`
+	Rank          float64   `db:"rank" json:"rank"`
+	Snippet       string    `db:"snippet" json:"snippet"`
+}
+
+// ContentMetrics describe the main content of a crawled page
+type ContentMetrics struct {
+	PageID            uuid.UUID `db:"page_id" json:"page_id"`
+	WordCount         int       `db:"word_count" json:"word_count"`
+	ParagraphCount    int       `db:"paragraph_count" json:"paragraph_count"`
+	AvgSentenceLength float64   `db:"avg_sentence_length" json:"avg_sentence_length"`
+	FleschReadingEase float64   `db:"flesch_reading_ease" json:"flesch_reading_ease"`
+	HeadingCount      int       `db:"heading_count" json:"heading_count"`
+	ImageCount        int       `db:"image_count" json:"image_count"`
+	InternalLinkCount int       `db:"internal_link_count" json:"internal_link_count"`
+	ExternalLinkCount int       `db:"external_link_count" json:"external_link_count"`
+	ListCount         int       `db:"list_count" json:"list_count"`
+	TableCount        int       `db:"table_count" json:"table_count"`
+	ComputedAt        time.Time `db:"computed_at" json:"computed_at"`
+}
+
+// URLContentMetrics are the content metrics of a url crawled by a query job, along with its best position
+type URLContentMetrics struct {
+	URL          string `db:"url" json:"url"`
+	BestPosition int    `db:"best_position" json:"best_position"`
+	ContentMetrics
+}
+
+// ContentMetricsSummary is the distribution of the content metrics of the pages crawled by a query job
+type ContentMetricsSummary struct {
+	QueryJobID uuid.UUID `json:"query_job_id"`
+	PageCount  int       `json:"page_count"`
+	// TopPageCount is the number of pages the recommended ranges are computed from
+	TopPageCount int                         `json:"top_page_count"`
+	Metrics      []ContentMetricDistribution `json:"metrics"`
+}
+
+// ContentMetricDistribution is the distribution of a content metric, along with the range recommended to compete
+// with the top ranking pages
+type ContentMetricDistribution struct {
+	Metric         string  `json:"metric"`
+	Min            float64 `json:"min"`
+	P25            float64 `json:"p25"`
+	Median         float64 `json:"median"`
+	P75            float64 `json:"p75"`
+	Max            float64 `json:"max"`
+	RecommendedMin float64 `json:"recommended_min"`
+	RecommendedMax float64 `json:"recommended_max"`
+}
+
+// KeywordUsage describes how a page uses the keyword of a query job. The match counts and density are over the
+// main content of the page, the density being the percentage of its words which are part of an exact match.
+type KeywordUsage struct {
+	InTitle           bool    `db:"in_title" json:"in_title"`
+	InH1              bool    `db:"in_h1" json:"in_h1"`
+	InURL             bool    `db:"in_url" json:"in_url"`
+	InDescription     bool    `db:"in_description" json:"in_description"`
+	InFirst100Words   bool    `db:"in_first_100_words" json:"in_first_100_words"`
+	ExactMatchCount   int     `db:"exact_match_count" json:"exact_match_count"`
+	PartialMatchCount int     `db:"partial_match_count" json:"partial_match_count"`
+	WordCount         int     `db:"word_count" json:"word_count"`
+	Density           float64 `db:"density" json:"density"`
+}
+
+// QueryItemKeywordUsage is the keyword usage of the page of a query item, along with its position
+type QueryItemKeywordUsage struct {
+	QueryItemID uuid.UUID `db:"query_item_id" json:"query_item_id"`
+	URL         string    `db:"url" json:"url"`
+	Position    int       `db:"position" json:"position"`
+	KeywordUsage
+}
+
+// KeywordUsageSummary correlates how the pages ranking for the keyword of a query job use it with their position
+type KeywordUsageSummary struct {
+	QueryJobID     uuid.UUID          `json:"query_job_id"`
+	Keyword        string             `json:"keyword"`
+	QueryItemCount int                `json:"query_item_count"`
+	Placements     []KeywordPlacement `json:"placements"`
+	Frequencies    []KeywordFrequency `json:"frequencies"`
+}
+
+// KeywordPlacement compares the average position of the query items whose page has the keyword in a placement,
+// like its title, with the ones whose page doesn't. An average position is nil when there are no such items.
+type KeywordPlacement struct {
+	Placement          string   `json:"placement"`
+	PresentCount       int      `json:"present_count"`
+	PresentRate        float64  `json:"present_rate"`
+	AvgPositionPresent *float64 `json:"avg_position_present"`
+	AvgPositionAbsent  *float64 `json:"avg_position_absent"`
+}
+
+// KeywordFrequency is the distribution of a keyword frequency metric and its rank correlation with position. A
+// negative correlation means pages with higher values rank better; it's nil when it can't be computed.
+type KeywordFrequency struct {
+	Metric      string   `json:"metric"`
+	Average     float64  `json:"average"`
+	Median      float64  `json:"median"`
+	Correlation *float64 `json:"correlation"`
+}
+
+// CrawlHealth summarises how the urls of a query job were crawled
+type CrawlHealth struct {
+	QueryJobID           uuid.UUID          `json:"query_job_id"`
+	URLCount             int                `json:"url_count"`
+	Processed            int                `json:"processed"`
+	Errored              int                `json:"errored"`
+	Blocked              int                `json:"blocked"`
+	Pending              int                `json:"pending"`
+	Cached               int                `json:"cached"`
+	Redirected           int                `json:"redirected"`
+	Rendered             int                `json:"rendered"`
+	SuccessRate          float64            `json:"success_rate"`
+	AvgResponseTimeMs    int                `json:"avg_response_time_ms"`
+	MedianResponseTimeMs int                `json:"median_response_time_ms"`
+	P95ResponseTimeMs    int                `json:"p95_response_time_ms"`
+	StatusCodes          []CrawlHealthCount `json:"status_codes"`
+	FailureCategories    []CrawlHealthCount `json:"failure_categories"`
+	ContentTypes         []CrawlHealthCount `json:"content_types"`
+	SlowestURLs          []CrawlHealthURL   `json:"slowest_urls"`
+}
+
+type CrawlHealthCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type CrawlHealthURL struct {
+	URL            string `json:"url"`
+	ResponseTimeMs int    `json:"response_time_ms"`
+}
@@ -0,0 +1,157 @@
+package watchlists
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/api/eventschema"
+	"github.com/jponc/competitive-analysis/internal/repository/dbrepository"
+	"github.com/jponc/competitive-analysis/internal/serpanalysis"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/jponc/competitive-analysis/pkg/webhook"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Service struct {
+	repository    *dbrepository.Repository
+	webhookClient *webhook.Client
+}
+
+// NewService instantiates the watchlists service
+func NewService(repository *dbrepository.Repository, webhookClient *webhook.Client) *Service {
+	s := &Service{
+		repository:    repository,
+		webhookClient: webhookClient,
+	}
+
+	return s
+}
+
+// WatchlistAlertsWebhookPayload is posted to the webhook URL of a watchlist when it gets new alerts
+type WatchlistAlertsWebhookPayload struct {
+	WatchlistID   uuid.UUID              `json:"watchlist_id"`
+	WatchlistName string                 `json:"watchlist_name"`
+	Alerts        []types.WatchlistAlert `json:"alerts"`
+}
+
+// EvaluateWatchlists compares the watched domains positions of a completed query job against the previous
+// completed query job of the same keyword, storing an alert for every domain that entered or dropped out of the top 10
+func (s *Service) EvaluateWatchlists(ctx context.Context, snsEvent events.SNSEvent) {
+	if s.repository == nil {
+		log.Fatalf("repository not defined")
+	}
+
+	if s.webhookClient == nil {
+		log.Fatalf("webhookClient not defined")
+	}
+
+	if err := s.repository.Connect(); err != nil {
+		log.Fatalf("can't connect to DB")
+	}
+
+	// Unmarshal msg
+	snsMsg := snsEvent.Records[0].SNS.Message
+
+	var msg eventschema.QueryJobCompletedMessage
+	err := json.Unmarshal([]byte(snsMsg), &msg)
+	if err != nil {
+		log.Fatalf("unable to unarmarshal message: %v", err)
+	}
+
+	queryJobID, err := uuid.FromString(msg.QueryJobID)
+	if err != nil {
+		log.Fatalf("unable to convert query job id string to UUID: %v", err)
+	}
+
+	queryJob, err := s.repository.GetQueryJob(ctx, queryJobID)
+	if err != nil {
+		log.Fatalf("unable to get query job (%s): %v", queryJobID.String(), err)
+	}
+
+	watchlists, err := s.repository.GetWatchlists(ctx)
+	if err != nil {
+		log.Fatalf("unable to get watchlists: %v", err)
+	}
+
+	if len(*watchlists) == 0 {
+		log.Infof("no watchlists to evaluate for query job (%s)", queryJobID.String())
+		s.close()
+		return
+	}
+
+	previousQueryJob, err := s.repository.GetPreviousCompletedQueryJob(ctx, *queryJob)
+	if err != nil {
+		log.Fatalf("unable to get previous query job of (%s): %v", queryJobID.String(), err)
+	}
+
+	// nothing to compare with, alerts only fire on changes between successive query jobs
+	if previousQueryJob == nil {
+		log.Infof("query job (%s) has no previous query job for keyword (%s)", queryJobID.String(), queryJob.Keyword)
+		s.close()
+		return
+	}
+
+	for _, watchlist := range *watchlists {
+		positions, err := s.repository.GetDomainBestPositions(ctx, []uuid.UUID{queryJob.ID, previousQueryJob.ID}, watchlist.Domains)
+		if err != nil {
+			log.Fatalf("unable to get domain best positions for watchlist (%s): %v", watchlist.ID.String(), err)
+		}
+
+		jobPositions := map[uuid.UUID]map[string]int{
+			queryJob.ID:         {},
+			previousQueryJob.ID: {},
+		}
+		for _, position := range *positions {
+			jobPositions[position.QueryJobID][position.Domain] = position.BestPosition
+		}
+
+		watched := serpanalysis.WatchedDomainPositions(watchlist.Domains, jobPositions[queryJob.ID], jobPositions[previousQueryJob.ID], true)
+		alerts := serpanalysis.WatchlistAlerts(watchlist, *queryJob, watched)
+
+		// a redelivered completion evaluates the query job again, only the alerts not stored yet are notified
+		newAlerts := []types.WatchlistAlert{}
+		for _, alert := range alerts {
+			created, err := s.repository.CreateWatchlistAlert(ctx, alert)
+			if err != nil {
+				log.Fatalf("unable to create watchlist alert for watchlist (%s): %v", watchlist.ID.String(), err)
+			}
+
+			if created {
+				newAlerts = append(newAlerts, alert)
+			}
+		}
+
+		if len(newAlerts) == 0 {
+			continue
+		}
+
+		log.Infof("created %d alerts for watchlist (%s) from query job (%s)", len(newAlerts), watchlist.ID.String(), queryJobID.String())
+
+		if watchlist.WebhookURL == nil {
+			continue
+		}
+
+		// Alerts are already stored, don't fail the whole evaluation if the webhook is unreachable
+		payload := WatchlistAlertsWebhookPayload{
+			WatchlistID:   watchlist.ID,
+			WatchlistName: watchlist.Name,
+			Alerts:        newAlerts,
+		}
+
+		err = s.webhookClient.Post(ctx, *watchlist.WebhookURL, payload)
+		if err != nil {
+			log.Errorf("unable to send alerts webhook for watchlist (%s): %v", watchlist.ID.String(), err)
+		}
+	}
+
+	s.close()
+}
+
+func (s *Service) close() {
+	if err := s.repository.Close(); err != nil {
+		log.Fatalf("can't close DB connection")
+	}
+}
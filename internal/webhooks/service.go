@@ -0,0 +1,218 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/api/eventschema"
+	"github.com/jponc/competitive-analysis/internal/repository/dbrepository"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/jponc/competitive-analysis/pkg/sns"
+	"github.com/jponc/competitive-analysis/pkg/webhook"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	maxDeliveryAttempts  = 4
+	initialRetryInterval = 2 * time.Second
+)
+
+type Service struct {
+	repository    *dbrepository.Repository
+	snsClient     *sns.Client
+	webhookClient *webhook.Client
+	retryInterval time.Duration
+}
+
+// NewService instantiates the webhooks service
+func NewService(repository *dbrepository.Repository, snsClient *sns.Client, webhookClient *webhook.Client) *Service {
+	s := &Service{
+		repository:    repository,
+		snsClient:     snsClient,
+		webhookClient: webhookClient,
+		retryInterval: initialRetryInterval,
+	}
+
+	return s
+}
+
+// WebhookEventPayload is the signed JSON body sent to webhook endpoints
+type WebhookEventPayload struct {
+	EventType  string          `json:"event_type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	QueryJob   *types.QueryJob `json:"query_job"`
+}
+
+// DispatchWebhookEvent delivers a webhook event to its webhook endpoint, logging the delivery. An event without
+// an endpoint is published again once per subscribed endpoint, so each endpoint is delivered and retried on its own.
+func (s *Service) DispatchWebhookEvent(ctx context.Context, snsEvent events.SNSEvent) {
+	if s.repository == nil {
+		log.Fatalf("repository not defined")
+	}
+
+	if s.snsClient == nil {
+		log.Fatalf("snsClient not defined")
+	}
+
+	if s.webhookClient == nil {
+		log.Fatalf("webhookClient not defined")
+	}
+
+	if err := s.repository.Connect(); err != nil {
+		log.Fatalf("can't connect to DB")
+	}
+
+	// Unmarshal msg
+	snsMsg := snsEvent.Records[0].SNS.Message
+
+	var msg eventschema.WebhookEventMessage
+	err := json.Unmarshal([]byte(snsMsg), &msg)
+	if err != nil {
+		log.Fatalf("unable to unarmarshal message: %v", err)
+	}
+
+	if msg.WebhookEndpointID == "" {
+		s.fanOut(ctx, msg)
+
+		if err := s.repository.Close(); err != nil {
+			log.Fatalf("can't close DB connection")
+		}
+
+		return
+	}
+
+	payload := WebhookEventPayload{
+		EventType:  msg.EventType,
+		OccurredAt: msg.OccurredAt,
+	}
+
+	var queryJobID *uuid.UUID
+	if msg.QueryJobID != "" {
+		id, err := uuid.FromString(msg.QueryJobID)
+		if err != nil {
+			log.Fatalf("unable to convert query job id string to UUID: %v", err)
+		}
+		queryJobID = &id
+
+		payload.QueryJob, err = s.repository.GetQueryJob(ctx, id)
+		if err != nil {
+			log.Fatalf("unable to get query job (%s): %v", id.String(), err)
+		}
+	}
+
+	webhookEndpoint, err := s.repository.GetWebhookEndpoint(ctx, uuid.FromStringOrNil(msg.WebhookEndpointID))
+	if err != nil {
+		log.Fatalf("unable to get webhook endpoint (%s): %v", msg.WebhookEndpointID, err)
+	}
+
+	// the endpoint was deleted since the event was published
+	if webhookEndpoint == nil {
+		log.Infof("webhook endpoint (%s) not found, skipping %s", msg.WebhookEndpointID, msg.EventType)
+
+		if err := s.repository.Close(); err != nil {
+			log.Fatalf("can't close DB connection")
+		}
+
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Fatalf("unable to marshal webhook payload: %v", err)
+	}
+
+	delivery, err := s.repository.CreateWebhookDelivery(ctx, webhookEndpoint.ID, msg.EventType, queryJobID, string(body))
+	if err != nil {
+		log.Fatalf("unable to create webhook delivery: %v", err)
+	}
+
+	s.deliver(ctx, *webhookEndpoint, delivery, body)
+
+	err = s.repository.UpdateWebhookDelivery(ctx, *delivery)
+	if err != nil {
+		log.Errorf("unable to update webhook delivery: %v", err)
+	}
+
+	log.Infof("webhook delivery (%s) of %s to %s: %s", delivery.ID.String(), msg.EventType, webhookEndpoint.URL, delivery.Status)
+
+	if err := s.repository.Close(); err != nil {
+		log.Fatalf("can't close DB connection")
+	}
+}
+
+// fanOut publishes the webhook event once per webhook endpoint subscribed to its event type
+func (s *Service) fanOut(ctx context.Context, msg eventschema.WebhookEventMessage) {
+	webhookEndpoints, err := s.repository.GetWebhookEndpointsForEvent(ctx, msg.EventType)
+	if err != nil {
+		log.Fatalf("unable to get webhook endpoints: %v", err)
+	}
+
+	for _, webhookEndpoint := range *webhookEndpoints {
+		endpointMsg := msg
+		endpointMsg.WebhookEndpointID = webhookEndpoint.ID.String()
+
+		err = s.snsClient.Publish(ctx, eventschema.WebhookEvent, endpointMsg)
+		if err != nil {
+			log.Fatalf("failed to publish SNS: %v", err)
+		}
+	}
+
+	log.Infof("%s published to %d webhook endpoints", msg.EventType, len(*webhookEndpoints))
+}
+
+// deliver sends the body to the webhook endpoint, retrying with exponential backoff on network errors,
+// server errors and rate limiting. The outcome is recorded in the delivery.
+func (s *Service) deliver(ctx context.Context, webhookEndpoint types.WebhookEndpoint, delivery *types.WebhookDelivery, body []byte) {
+	headers := map[string]string{
+		"X-Webhook-Event":    delivery.EventType,
+		"X-Webhook-Delivery": delivery.ID.String(),
+	}
+
+	interval := s.retryInterval
+
+	for delivery.Attempts < maxDeliveryAttempts {
+		if delivery.Attempts > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return
+			}
+			interval *= 2
+		}
+
+		delivery.Attempts++
+
+		status, err := s.webhookClient.PostSigned(ctx, webhookEndpoint.URL, webhookEndpoint.Secret, body, headers)
+
+		delivery.ResponseStatus = nil
+		if status != 0 {
+			delivery.ResponseStatus = &status
+		}
+
+		if err == nil {
+			now := time.Now()
+			delivery.Status = types.WebhookDeliverySucceeded
+			delivery.Error = nil
+			delivery.DeliveredAt = &now
+			return
+		}
+
+		errMsg := err.Error()
+		delivery.Status = types.WebhookDeliveryFailed
+		delivery.Error = &errMsg
+
+		if !retryable(status) {
+			return
+		}
+	}
+}
+
+// retryable checks if a delivery should be retried based on the response status, 0 meaning no response
+func retryable(status int) bool {
+	return status == 0 || status >= http.StatusInternalServerError || status == http.StatusTooManyRequests || status == http.StatusRequestTimeout
+}
@@ -0,0 +1,87 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jponc/competitive-analysis/internal/types"
+	"github.com/jponc/competitive-analysis/pkg/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T, statuses []int) (*Service, types.WebhookEndpoint, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[len(statuses)-1]
+		if calls < len(statuses) {
+			status = statuses[calls]
+		}
+		calls++
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	s := NewService(nil, nil, webhook.NewClient(server.Client()))
+	s.retryInterval = time.Millisecond
+
+	webhookEndpoint := types.WebhookEndpoint{ID: uuid.Must(uuid.NewV4()), URL: server.URL, Secret: "secret"}
+
+	return s, webhookEndpoint, &calls
+}
+
+func Test_DeliverRetriesUntilSuccess(t *testing.T) {
+	s, webhookEndpoint, calls := newTestService(t, []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK})
+	delivery := &types.WebhookDelivery{ID: uuid.Must(uuid.NewV4()), EventType: types.WebhookEventPing}
+
+	s.deliver(context.Background(), webhookEndpoint, delivery, []byte(`{}`))
+
+	require.Equal(t, 3, *calls)
+	require.Equal(t, 3, delivery.Attempts)
+	require.Equal(t, types.WebhookDeliverySucceeded, delivery.Status)
+	require.Equal(t, http.StatusOK, *delivery.ResponseStatus)
+	require.Nil(t, delivery.Error)
+	require.NotNil(t, delivery.DeliveredAt)
+}
+
+func Test_DeliverGivesUpAfterMaxAttempts(t *testing.T) {
+	s, webhookEndpoint, calls := newTestService(t, []int{http.StatusInternalServerError})
+	delivery := &types.WebhookDelivery{ID: uuid.Must(uuid.NewV4()), EventType: types.WebhookEventPing}
+
+	s.deliver(context.Background(), webhookEndpoint, delivery, []byte(`{}`))
+
+	require.Equal(t, maxDeliveryAttempts, *calls)
+	require.Equal(t, maxDeliveryAttempts, delivery.Attempts)
+	require.Equal(t, types.WebhookDeliveryFailed, delivery.Status)
+	require.Equal(t, http.StatusInternalServerError, *delivery.ResponseStatus)
+	require.NotNil(t, delivery.Error)
+	require.Nil(t, delivery.DeliveredAt)
+}
+
+func Test_DeliverDoesNotRetryClientErrors(t *testing.T) {
+	s, webhookEndpoint, calls := newTestService(t, []int{http.StatusGone})
+	delivery := &types.WebhookDelivery{ID: uuid.Must(uuid.NewV4()), EventType: types.WebhookEventPing}
+
+	s.deliver(context.Background(), webhookEndpoint, delivery, []byte(`{}`))
+
+	require.Equal(t, 1, *calls)
+	require.Equal(t, types.WebhookDeliveryFailed, delivery.Status)
+}
+
+func Test_DeliverStopsRetryingWhenCancelled(t *testing.T) {
+	s, webhookEndpoint, calls := newTestService(t, []int{http.StatusServiceUnavailable})
+	s.retryInterval = time.Hour
+	delivery := &types.WebhookDelivery{ID: uuid.Must(uuid.NewV4()), EventType: types.WebhookEventPing}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	s.deliver(ctx, webhookEndpoint, delivery, []byte(`{}`))
+
+	require.Equal(t, 1, *calls)
+	require.Equal(t, 1, delivery.Attempts)
+	require.Equal(t, types.WebhookDeliveryFailed, delivery.Status)
+}
@@ -1,4 +1,5 @@
 import { Client } from "pg";
+import { normalizeURL } from "./normalizeURL";
 
 const migrations = {
   v00_add_uuid_extension: async (client: Client) => {
@@ -150,6 +151,463 @@ const migrations = {
       ADD CONSTRAINT fk_query_job FOREIGN KEY (query_job_id) REFERENCES query_job(id) ON DELETE CASCADE;
     `);
   },
+  v18_add_user_id_and_serp_request_count_to_query_job: async (client: Client) => {
+    await client.query(`
+      ALTER TABLE query_job
+      ADD COLUMN user_id TEXT,
+      ADD COLUMN serp_request_count INTEGER NOT NULL DEFAULT 0;
+    `);
+  },
+  v19_create_zenserp_usage: async (client: Client) => {
+    await client.query(`
+      CREATE TABLE zenserp_usage
+        (
+           id             UUID DEFAULT uuid_generate_v4(),
+           query_job_id   UUID,
+           user_id        TEXT,
+           request_count  INTEGER NOT NULL,
+           created_at     TIMESTAMP NOT NULL DEFAULT NOW(),
+           PRIMARY KEY(id),
+           CONSTRAINT fk_query_job FOREIGN KEY(query_job_id) REFERENCES query_job(id) ON DELETE SET NULL
+        );
+    `);
+  },
+  v20_add_zenserp_usage_created_at_idx: async (client: Client) => {
+    await client.query(`
+      CREATE INDEX zenserp_usage_created_at_idx ON zenserp_usage (created_at);
+    `);
+  },
+  v21_add_cached_to_query_location: async (client: Client) => {
+    await client.query(`
+      ALTER TABLE query_location ADD COLUMN cached BOOLEAN NOT NULL DEFAULT FALSE;
+    `);
+  },
+  v22_create_serp_cache: async (client: Client) => {
+    await client.query(`
+      CREATE TABLE serp_cache
+        (
+           id                 UUID DEFAULT uuid_generate_v4(),
+           cache_key          TEXT NOT NULL,
+           query_location_id  UUID NOT NULL,
+           created_at         TIMESTAMP NOT NULL DEFAULT NOW(),
+           PRIMARY KEY(id),
+           CONSTRAINT fk_query_location FOREIGN KEY(query_location_id) REFERENCES query_location(id) ON DELETE CASCADE
+        );
+    `);
+  },
+  v23_add_serp_cache_key_idx: async (client: Client) => {
+    await client.query(`
+      CREATE INDEX serp_cache_key_created_at_idx ON serp_cache (cache_key, created_at DESC);
+    `);
+  },
+  v24_add_device_comparison_to_query_job: async (client: Client) => {
+    await client.query(`
+      ALTER TABLE query_job ADD COLUMN device_comparison BOOLEAN NOT NULL DEFAULT FALSE;
+    `);
+  },
+  v25_create_page_snapshot: async (client: Client) => {
+    await client.query(`
+      CREATE TABLE page_snapshot
+        (
+           id             UUID DEFAULT uuid_generate_v4(),
+           url            TEXT NOT NULL,
+           query_job_id   UUID,
+           content_hash   TEXT NOT NULL,
+           title          TEXT NOT NULL,
+           content        TEXT NOT NULL,
+           word_count     INTEGER NOT NULL,
+           first_seen_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+           last_seen_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+           PRIMARY KEY(id),
+           CONSTRAINT fk_query_job FOREIGN KEY(query_job_id) REFERENCES query_job(id) ON DELETE SET NULL
+        );
+    `);
+  },
+  v26_add_page_snapshot_url_idx: async (client: Client) => {
+    await client.query(`
+      CREATE INDEX page_snapshot_url_first_seen_at_idx ON page_snapshot (url, first_seen_at DESC);
+    `);
+  },
+  v27_create_watchlist: async (client: Client) => {
+    await client.query(`
+      CREATE TABLE watchlist
+        (
+           id           UUID DEFAULT uuid_generate_v4(),
+           name         TEXT NOT NULL,
+           domains      TEXT[] NOT NULL,
+           webhook_url  TEXT,
+           created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+           PRIMARY KEY(id)
+        );
+    `);
+  },
+  v28_create_watchlist_alert: async (client: Client) => {
+    await client.query(`
+      CREATE TABLE watchlist_alert
+        (
+           id                 UUID DEFAULT uuid_generate_v4(),
+           watchlist_id       UUID NOT NULL,
+           query_job_id       UUID,
+           keyword            TEXT NOT NULL,
+           domain             TEXT NOT NULL,
+           alert_type         TEXT NOT NULL,
+           position           INTEGER,
+           previous_position  INTEGER,
+           created_at         TIMESTAMP NOT NULL DEFAULT NOW(),
+           PRIMARY KEY(id),
+           CONSTRAINT fk_watchlist FOREIGN KEY(watchlist_id) REFERENCES watchlist(id) ON DELETE CASCADE,
+           CONSTRAINT fk_query_job FOREIGN KEY(query_job_id) REFERENCES query_job(id) ON DELETE SET NULL
+        );
+    `);
+  },
+  v29_add_watchlist_alert_idx: async (client: Client) => {
+    await client.query(`
+      CREATE INDEX watchlist_alert_watchlist_id_created_at_idx ON watchlist_alert (watchlist_id, created_at DESC);
+    `);
+  },
+  v30_add_query_job_completed_at_idx: async (client: Client) => {
+    await client.query(`
+      CREATE INDEX query_job_completed_at_idx ON query_job (completed_at);
+    `);
+  },
+  v31_add_query_job_target_domain: async (client: Client) => {
+    await client.query(`
+      ALTER TABLE query_job
+        ADD COLUMN target_domain TEXT,
+        ADD COLUMN target_best_position INTEGER,
+        ADD COLUMN target_urls TEXT[] NOT NULL DEFAULT '{}',
+        ADD COLUMN target_cannibalized BOOLEAN NOT NULL DEFAULT false;
+    `);
+  },
+  v32_add_query_location_target_ranking: async (client: Client) => {
+    await client.query(`
+      ALTER TABLE query_location
+        ADD COLUMN target_position INTEGER,
+        ADD COLUMN target_url TEXT,
+        ADD COLUMN target_urls TEXT[] NOT NULL DEFAULT '{}',
+        ADD COLUMN target_cannibalized BOOLEAN NOT NULL DEFAULT false;
+    `);
+  },
+  v33_add_query_job_failed_at: async (client: Client) => {
+    await client.query(`
+      ALTER TABLE query_job
+        ADD COLUMN failed_at TIMESTAMP,
+        ADD COLUMN failure_reason TEXT;
+    `);
+  },
+  v34_create_webhook_endpoint: async (client: Client) => {
+    await client.query(`
+      CREATE TABLE webhook_endpoint
+        (
+           id           UUID DEFAULT uuid_generate_v4(),
+           url          TEXT NOT NULL,
+           secret       TEXT NOT NULL,
+           event_types  TEXT[] NOT NULL DEFAULT '{}',
+           created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+           PRIMARY KEY(id)
+        );
+    `);
+  },
+  v35_create_webhook_delivery: async (client: Client) => {
+    await client.query(`
+      CREATE TABLE webhook_delivery
+        (
+           id                   UUID DEFAULT uuid_generate_v4(),
+           webhook_endpoint_id  UUID NOT NULL,
+           event_type           TEXT NOT NULL,
+           query_job_id         UUID,
+           payload              TEXT NOT NULL,
+           status               TEXT NOT NULL DEFAULT 'pending',
+           attempts             INTEGER NOT NULL DEFAULT 0,
+           response_status      INTEGER,
+           error                TEXT,
+           created_at           TIMESTAMP NOT NULL DEFAULT NOW(),
+           delivered_at         TIMESTAMP,
+           PRIMARY KEY(id),
+           CONSTRAINT fk_webhook_endpoint FOREIGN KEY(webhook_endpoint_id) REFERENCES webhook_endpoint(id) ON DELETE CASCADE,
+           CONSTRAINT fk_query_job FOREIGN KEY(query_job_id) REFERENCES query_job(id) ON DELETE SET NULL
+        );
+    `);
+  },
+  v36_add_webhook_delivery_idx: async (client: Client) => {
+    await client.query(`
+      CREATE INDEX webhook_delivery_webhook_endpoint_id_created_at_idx ON webhook_delivery (webhook_endpoint_id, created_at DESC);
+    `);
+  },
+  v37_add_query_item_search_vector: async (client: Client) => {
+    await client.query(`
+      ALTER TABLE query_item ADD COLUMN search_vector TSVECTOR;
+
+      UPDATE query_item
+      SET search_vector = setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', left(body, 200000)), 'B')
+      WHERE body IS NOT NULL AND error_processing = false;
+    `);
+  },
+  v38_add_query_item_search_vector_idx: async (client: Client) => {
+    await client.query(`
+      CREATE INDEX query_item_search_vector_idx ON query_item USING GIN (search_vector);
+    `);
+  },
+  v39_create_page: async (client: Client) => {
+    await client.query(`
+      CREATE TABLE page
+        (
+           id             UUID DEFAULT uuid_generate_v4(),
+           url            TEXT NOT NULL,
+           title          TEXT NOT NULL,
+           description    TEXT NOT NULL DEFAULT '',
+           body           TEXT NOT NULL,
+           search_vector  TSVECTOR,
+           scraped_at     TIMESTAMP NOT NULL DEFAULT NOW(),
+           created_at     TIMESTAMP NOT NULL DEFAULT NOW(),
+           PRIMARY KEY(id),
+           UNIQUE(url)
+        );
+    `);
+  },
+  v40_move_query_item_body_to_page: async (client: Client) => {
+    // pages are keyed by the normalized url the crawler looks them up by, which is computed here
+    const { rows } = await client.query<{ url: string }>(`
+      SELECT DISTINCT url
+      FROM query_item
+      WHERE body IS NOT NULL AND error_processing = false
+    `);
+
+    await client.query("BEGIN");
+    try {
+      await client.query(`
+        ALTER TABLE query_item ADD COLUMN page_id UUID REFERENCES page(id) ON DELETE SET NULL;
+
+        CREATE TEMPORARY TABLE page_url (url TEXT PRIMARY KEY, page_url TEXT NOT NULL) ON COMMIT DROP;
+      `);
+
+      await client.query(
+        `INSERT INTO page_url (url, page_url) SELECT * FROM unnest($1::text[], $2::text[])`,
+        [rows.map((row) => row.url), rows.map((row) => normalizeURL(row.url))]
+      );
+
+      await client.query(`
+        INSERT INTO page (url, title, body, search_vector, scraped_at)
+        SELECT DISTINCT ON (pu.page_url) pu.page_url, qi.title, qi.body, qi.search_vector, qi.processed_at
+        FROM query_item qi
+        INNER JOIN page_url pu ON pu.url = qi.url
+        WHERE qi.body IS NOT NULL AND qi.error_processing = false AND qi.processed_at IS NOT NULL
+        ORDER BY pu.page_url, qi.processed_at DESC;
+
+        UPDATE query_item qi
+        SET page_id = p.id
+        FROM page_url pu
+        INNER JOIN page p ON p.url = pu.page_url
+        WHERE pu.url = qi.url AND qi.body IS NOT NULL AND qi.error_processing = false;
+
+        ALTER TABLE query_item DROP COLUMN body, DROP COLUMN search_vector;
+      `);
+
+      await client.query("COMMIT");
+    } catch (e) {
+      await client.query("ROLLBACK");
+      throw e;
+    }
+  },
+  v41_add_page_idx: async (client: Client) => {
+    await client.query(`
+      CREATE INDEX page_search_vector_idx ON page USING GIN (search_vector);
+      CREATE INDEX query_item_page_id_idx ON query_item (page_id);
+    `);
+  },
+  v42_create_page_link: async (client: Client) => {
+    await client.query(`
+      CREATE TABLE page_link
+        (
+           id       UUID DEFAULT uuid_generate_v4(),
+           page_id  UUID NOT NULL,
+           text     TEXT NOT NULL,
+           url      TEXT NOT NULL,
+           PRIMARY KEY(id),
+           CONSTRAINT fk_page FOREIGN KEY(page_id) REFERENCES page(id) ON DELETE CASCADE
+        );
+
+      INSERT INTO page_link (page_id, text, url)
+      SELECT qi.page_id, l.text, l.url
+      FROM link l
+      INNER JOIN (
+        SELECT DISTINCT ON (page_id) id, page_id
+        FROM query_item
+        WHERE page_id IS NOT NULL
+        ORDER BY page_id, processed_at DESC
+      ) qi ON qi.id = l.query_item_id;
+
+      DROP TABLE link;
+    `);
+  },
+  v43_add_page_link_page_id_idx: async (client: Client) => {
+    await client.query(`
+      CREATE INDEX page_link_page_id_idx ON page_link (page_id);
+    `);
+  },
+  v44_add_query_item_blocked: async (client: Client) => {
+    await client.query(`
+      ALTER TABLE query_item ADD COLUMN blocked BOOLEAN NOT NULL DEFAULT false;
+    `);
+  },
+  v45_create_robots_txt: async (client: Client) => {
+    await client.query(`
+      CREATE TABLE robots_txt
+        (
+           origin      TEXT NOT NULL,
+           content     TEXT NOT NULL,
+           fetched_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+           PRIMARY KEY(origin)
+        );
+    `);
+  },
+  v46_create_domain_fetch: async (client: Client) => {
+    await client.query(`
+      CREATE TABLE domain_fetch
+        (
+           host           TEXT NOT NULL,
+           next_fetch_at  TIMESTAMP NOT NULL,
+           PRIMARY KEY(host)
+        );
+    `);
+  },
+  v47_create_fetch_diagnostic: async (client: Client) => {
+    await client.query(`
+      CREATE TABLE fetch_diagnostic
+        (
+           query_job_id      UUID NOT NULL,
+           url               TEXT NOT NULL,
+           cached            BOOLEAN NOT NULL DEFAULT false,
+           status_code       INTEGER,
+           final_url         TEXT NOT NULL DEFAULT '',
+           redirects         TEXT[] NOT NULL DEFAULT '{}',
+           response_time_ms  INTEGER NOT NULL DEFAULT 0,
+           content_length    BIGINT,
+           content_type      TEXT NOT NULL DEFAULT '',
+           error             TEXT,
+           failure_category  TEXT,
+           fetched_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+           PRIMARY KEY(query_job_id, url),
+           CONSTRAINT fk_query_job FOREIGN KEY(query_job_id) REFERENCES query_job(id) ON DELETE CASCADE
+        );
+    `);
+  },
+  v48_create_scrape_retry: async (client: Client) => {
+    await client.query(`
+      CREATE TABLE scrape_retry
+        (
+           query_job_id     UUID NOT NULL,
+           url              TEXT NOT NULL,
+           attempt          INTEGER NOT NULL,
+           skip_page_cache  BOOLEAN NOT NULL DEFAULT false,
+           retry_at         TIMESTAMP NOT NULL,
+           PRIMARY KEY(query_job_id, url),
+           CONSTRAINT fk_query_job FOREIGN KEY(query_job_id) REFERENCES query_job(id) ON DELETE CASCADE
+        );
+
+      CREATE INDEX scrape_retry_retry_at_idx ON scrape_retry (retry_at);
+    `);
+  },
+  v49_add_fetch_diagnostic_attempt: async (client: Client) => {
+    await client.query(`
+      ALTER TABLE fetch_diagnostic ADD COLUMN attempt INTEGER NOT NULL DEFAULT 1;
+    `);
+  },
+  v50_add_page_main_content: async (client: Client) => {
+    await client.query(`
+      ALTER TABLE page ADD COLUMN main_content TEXT;
+    `);
+  },
+  v51_add_fetch_diagnostic_rendered: async (client: Client) => {
+    await client.query(`
+      ALTER TABLE fetch_diagnostic ADD COLUMN rendered BOOLEAN NOT NULL DEFAULT false;
+    `);
+  },
+  v52_create_content_metric: async (client: Client) => {
+    await client.query(`
+      CREATE TABLE content_metric
+        (
+           page_id              UUID NOT NULL,
+           word_count           INTEGER NOT NULL,
+           paragraph_count      INTEGER NOT NULL,
+           avg_sentence_length  DOUBLE PRECISION NOT NULL,
+           flesch_reading_ease  DOUBLE PRECISION NOT NULL,
+           heading_count        INTEGER NOT NULL,
+           image_count          INTEGER NOT NULL,
+           internal_link_count  INTEGER NOT NULL,
+           external_link_count  INTEGER NOT NULL,
+           list_count           INTEGER NOT NULL,
+           table_count          INTEGER NOT NULL,
+           computed_at          TIMESTAMP NOT NULL DEFAULT NOW(),
+           PRIMARY KEY(page_id),
+           CONSTRAINT fk_page FOREIGN KEY(page_id) REFERENCES page(id) ON DELETE CASCADE
+        );
+    `);
+  },
+  v53_create_keyword_usage: async (client: Client) => {
+    await client.query(`
+      ALTER TABLE page ADD COLUMN h1 TEXT[] NOT NULL DEFAULT '{}';
+
+      CREATE TABLE keyword_usage
+        (
+           query_item_id        UUID NOT NULL,
+           in_title             BOOLEAN NOT NULL,
+           in_h1                BOOLEAN NOT NULL,
+           in_url               BOOLEAN NOT NULL,
+           in_description       BOOLEAN NOT NULL,
+           in_first_100_words   BOOLEAN NOT NULL,
+           exact_match_count    INTEGER NOT NULL,
+           partial_match_count  INTEGER NOT NULL,
+           word_count           INTEGER NOT NULL,
+           density              DOUBLE PRECISION NOT NULL,
+           computed_at          TIMESTAMP NOT NULL DEFAULT NOW(),
+           PRIMARY KEY(query_item_id),
+           CONSTRAINT fk_query_item FOREIGN KEY(query_item_id) REFERENCES query_item(id) ON DELETE CASCADE
+        );
+    `);
+  },
+  v54_add_zenserp_usage_query_job_id_idx: async (client: Client) => {
+    await client.query(`
+      CREATE INDEX zenserp_usage_query_job_id_idx ON zenserp_usage (query_job_id);
+    `);
+  },
+  v55_make_page_snapshots: async (client: Client) => {
+    // every scrape stores a new page so the query items crawled with a previous one keep its content
+    await client.query(`
+      ALTER TABLE page DROP CONSTRAINT page_url_key;
+      CREATE INDEX page_url_scraped_at_idx ON page (url, scraped_at DESC);
+    `);
+  },
+  v56_add_query_job_recrawl: async (client: Client) => {
+    await client.query(`
+      ALTER TABLE query_job
+        ADD COLUMN recrawl_started_at TIMESTAMP,
+        ADD COLUMN recrawl_completed_at TIMESTAMP;
+    `);
+  },
+  v57_make_page_h1_nullable: async (client: Client) => {
+    // pages scraped before headings were extracted got no headings by default, which can't be told apart from
+    // pages without any, so both are marked unknown and scraped again instead of being reused
+    await client.query(`
+      ALTER TABLE page ALTER COLUMN h1 DROP NOT NULL, ALTER COLUMN h1 DROP DEFAULT;
+      UPDATE page SET h1 = NULL WHERE h1 = '{}';
+    `);
+  },
+  v58_add_watchlist_alert_unique: async (client: Client) => {
+    // redelivered query job completions stored the same alerts again, only the first one of each is kept
+    await client.query(`
+      DELETE FROM watchlist_alert a
+      USING watchlist_alert b
+      WHERE a.watchlist_id = b.watchlist_id
+        AND a.query_job_id = b.query_job_id
+        AND a.domain = b.domain
+        AND a.alert_type = b.alert_type
+        AND (a.created_at, a.id) > (b.created_at, b.id);
+
+      ALTER TABLE watchlist_alert
+        ADD CONSTRAINT watchlist_alert_unique UNIQUE (watchlist_id, query_job_id, domain, alert_type);
+    `);
+  },
 };
 
 export default migrations;
@@ -0,0 +1,69 @@
+// trackingParams are query parameters that don't change the content of a page
+const trackingParams = ["gclid", "fbclid", "msclkid", "dclid"];
+
+// queryEscape escapes like Go's url.QueryEscape: only letters, digits and -_.~ are kept, spaces become +
+const queryEscape = (s: string): string =>
+  encodeURIComponent(s)
+    .replace(/[!'()*]/g, (c) => "%" + c.charCodeAt(0).toString(16).toUpperCase())
+    .replace(/%20/g, "+");
+
+// normalizeURL mirrors webscraper.NormalizeURL, which pages are keyed by: lowercased scheme and host, no
+// default port, fragment or tracking parameters, and query parameters sorted by key. URLs that can't be
+// parsed are returned as is.
+export const normalizeURL = (rawURL: string): string => {
+  let u: URL;
+  try {
+    u = new URL(rawURL.trim());
+  } catch (e) {
+    return rawURL;
+  }
+
+  if (u.host === "") {
+    return rawURL;
+  }
+
+  // the query is parsed like url.ParseQuery, which skips the parameters with a semicolon or an invalid escape.
+  // The values of a parameter keep their order, like url.Values.
+  const params = new Map<string, string[]>();
+  u.search
+    .slice(1)
+    .split("&")
+    .forEach((param) => {
+      if (param === "" || param.includes(";")) {
+        return;
+      }
+
+      const separator = param.indexOf("=");
+      const rawKey = separator === -1 ? param : param.slice(0, separator);
+      const rawValue = separator === -1 ? "" : param.slice(separator + 1);
+
+      let key: string;
+      let value: string;
+      try {
+        key = decodeURIComponent(rawKey.replace(/\+/g, " "));
+        value = decodeURIComponent(rawValue.replace(/\+/g, " "));
+      } catch (e) {
+        return;
+      }
+
+      const lowerKey = key.toLowerCase();
+      if (trackingParams.indexOf(lowerKey) !== -1 || lowerKey.startsWith("utm_")) {
+        return;
+      }
+
+      params.set(key, [...(params.get(key) || []), value]);
+    });
+
+  const pairs: string[] = [];
+  Array.from(params.keys())
+    .sort()
+    .forEach((key) => {
+      params.get(key)!.forEach((value) => pairs.push(`${queryEscape(key)}=${queryEscape(value)}`));
+    });
+  const query = pairs.join("&");
+
+  // a trailing "?" without parameters is kept, like url.URL.ForceQuery
+  const forceQuery = u.search === "" && u.href.replace(/#.*$/, "").endsWith("?");
+
+  return `${u.protocol}//${u.username ? u.username + (u.password ? ":" + u.password : "") + "@" : ""}${u.host}${u.pathname}${query || forceQuery ? "?" + query : ""}`;
+};
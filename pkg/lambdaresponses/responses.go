@@ -21,26 +21,23 @@ func Respond500() (events.APIGatewayProxyResponse, error) {
 }
 
 func Respond400(err error) (events.APIGatewayProxyResponse, error) {
-	resBody := errorResponseBody{
-		Error: err.Error(),
-	}
+	return respondError(400, err)
+}
 
-	body, err := json.Marshal(resBody)
-	if err != nil {
-		return Respond500()
-	}
+func Respond404(err error) (events.APIGatewayProxyResponse, error) {
+	return respondError(404, err)
+}
 
-	return events.APIGatewayProxyResponse{
-		Headers: map[string]string{
-			"Access-Control-Allow-Origin":      "*",
-			"Access-Control-Allow-Credentials": "true",
-		},
-		Body:       string(body),
-		StatusCode: 400,
-	}, nil
+func Respond402(err error) (events.APIGatewayProxyResponse, error) {
+	return respondError(402, err)
 }
 
-func Respond404(err error) (events.APIGatewayProxyResponse, error) {
+func Respond429(err error) (events.APIGatewayProxyResponse, error) {
+	return respondError(429, err)
+}
+
+// respondError responds with the status code and the error message as the JSON body
+func respondError(statusCode int, err error) (events.APIGatewayProxyResponse, error) {
 	resBody := errorResponseBody{
 		Error: err.Error(),
 	}
@@ -56,7 +53,7 @@ func Respond404(err error) (events.APIGatewayProxyResponse, error) {
 			"Access-Control-Allow-Credentials": "true",
 		},
 		Body:       string(body),
-		StatusCode: 404,
+		StatusCode: statusCode,
 	}, nil
 }
 
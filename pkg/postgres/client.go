@@ -44,6 +44,30 @@ func (c *Client) ExecContext(ctx context.Context, query string, args ...interfac
 	return c.db.ExecContext(ctx, query, args...)
 }
 
+// QueryxContext returns the rows of the query to iterate over, the caller must close them
+func (c *Client) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	return c.db.QueryxContext(ctx, query, args...)
+}
+
+// WithTransaction runs fn within a transaction, which is committed when fn succeeds and rolled back otherwise
+func (c *Client) WithTransaction(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := c.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
 func (c *Client) Close() error {
 	return c.db.Close()
 }
@@ -0,0 +1,73 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awsS3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-xray-sdk-go/xray"
+)
+
+type Client struct {
+	awsS3Client *awsS3.S3
+	uploader    *s3manager.Uploader
+	bucket      string
+}
+
+// NewClient instantiates a S3 client for the bucket
+func NewClient(awsRegion, bucket string) (*Client, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(awsRegion),
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot create aws session: %v", err)
+	}
+
+	awsS3Client := awsS3.New(sess)
+	xray.AWS(awsS3Client.Client)
+
+	c := &Client{
+		awsS3Client: awsS3Client,
+		uploader:    s3manager.NewUploaderWithClient(awsS3Client),
+		bucket:      bucket,
+	}
+
+	return c, nil
+}
+
+// Upload streams the body to the key using a multipart upload, so the body never has to be fully in memory
+func (c *Client) Upload(ctx context.Context, key, contentType, contentDisposition string, body io.Reader) error {
+	_, err := c.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:             aws.String(c.bucket),
+		Key:                aws.String(key),
+		ContentType:        aws.String(contentType),
+		ContentDisposition: aws.String(contentDisposition),
+		Body:               body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %v", key, err)
+	}
+
+	return nil
+}
+
+// PresignGetURL returns a URL to download the key that's valid for the given duration
+func (c *Client) PresignGetURL(key string, expiry time.Duration) (string, error) {
+	req, _ := c.awsS3Client.GetObjectRequest(&awsS3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+
+	url, err := req.Presign(expiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign %s: %v", key, err)
+	}
+
+	return url, nil
+}
@@ -0,0 +1,35 @@
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// NewCSVWriter instantiates a Writer producing RFC 4180 CSV
+func NewCSVWriter(w io.Writer) Writer {
+	return &csvWriter{
+		w: csv.NewWriter(w),
+	}
+}
+
+func (c *csvWriter) WriteRow(row []interface{}) error {
+	record := make([]string, len(row))
+	for i, value := range row {
+		s, err := formatValue(value)
+		if err != nil {
+			return err
+		}
+		record[i] = s
+	}
+
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
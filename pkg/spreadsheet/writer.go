@@ -0,0 +1,49 @@
+package spreadsheet
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Writer writes rows one at a time so large tables never have to be held in memory. Supported cell values
+// are string, int, float64, bool, time.Time and nil.
+type Writer interface {
+	WriteRow(row []interface{}) error
+	// Close flushes the remaining data, it doesn't close the underlying io.Writer
+	Close() error
+}
+
+// ContentType returns the MIME type of the format
+func ContentType(format string) string {
+	switch format {
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv"
+	}
+}
+
+func formatValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		return v.UTC().Format(time.RFC3339), nil
+	default:
+		return "", fmt.Errorf("unsupported cell value type %T", value)
+	}
+}
@@ -0,0 +1,103 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CSVWriter(t *testing.T) {
+	var b bytes.Buffer
+	w := NewCSVWriter(&b)
+
+	require.NoError(t, w.WriteRow([]interface{}{"url", "position", "avg", "processed", "crawled_at", "title"}))
+	require.NoError(t, w.WriteRow([]interface{}{"https://a.com/?q=1,2", 3, 1.5, true, time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC), nil}))
+	require.NoError(t, w.Close())
+
+	require.Equal(t, "url,position,avg,processed,crawled_at,title\n\"https://a.com/?q=1,2\",3,1.5,true,2021-12-01T10:00:00Z,\n", b.String())
+}
+
+func Test_CSVWriterUnsupportedType(t *testing.T) {
+	w := NewCSVWriter(io.Discard)
+	require.Error(t, w.WriteRow([]interface{}{struct{}{}}))
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     string `xml:"r,attr"`
+		Cells []struct {
+			R      string `xml:"r,attr"`
+			T      string `xml:"t,attr"`
+			V      string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func Test_XLSXWriter(t *testing.T) {
+	var b bytes.Buffer
+	w, err := NewXLSXWriter(&b, "Rankings")
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteRow([]interface{}{"url", "position", "processed"}))
+	require.NoError(t, w.WriteRow([]interface{}{"https://a.com/?a=1&b=<2>", 3, false}))
+	require.NoError(t, w.WriteRow([]interface{}{nil, 1.25, true}))
+	require.NoError(t, w.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+
+	require.Contains(t, files, "[Content_Types].xml")
+	require.Contains(t, files, "_rels/.rels")
+	require.Contains(t, files, "xl/_rels/workbook.xml.rels")
+	require.Contains(t, files["xl/workbook.xml"], `name="Rankings"`)
+
+	sheet := xlsxSheet{}
+	require.NoError(t, xml.Unmarshal([]byte(files["xl/worksheets/sheet1.xml"]), &sheet))
+	require.Len(t, sheet.Rows, 3)
+
+	require.Equal(t, "1", sheet.Rows[0].R)
+	require.Equal(t, "A1", sheet.Rows[0].Cells[0].R)
+	require.Equal(t, "url", sheet.Rows[0].Cells[0].Inline)
+
+	require.Equal(t, "https://a.com/?a=1&b=<2>", sheet.Rows[1].Cells[0].Inline)
+	require.Equal(t, "B2", sheet.Rows[1].Cells[1].R)
+	require.Equal(t, "3", sheet.Rows[1].Cells[1].V)
+	require.Equal(t, "b", sheet.Rows[1].Cells[2].T)
+	require.Equal(t, "0", sheet.Rows[1].Cells[2].V)
+
+	// nil cells are left out
+	require.Len(t, sheet.Rows[2].Cells, 2)
+	require.Equal(t, "B3", sheet.Rows[2].Cells[0].R)
+	require.Equal(t, "1.25", sheet.Rows[2].Cells[0].V)
+}
+
+func Test_ColumnName(t *testing.T) {
+	require.Equal(t, "A", columnName(0))
+	require.Equal(t, "Z", columnName(25))
+	require.Equal(t, "AA", columnName(26))
+	require.Equal(t, "AB", columnName(27))
+	require.Equal(t, "ZZ", columnName(701))
+	require.Equal(t, "AAA", columnName(702))
+}
+
+func Test_Truncate(t *testing.T) {
+	require.Equal(t, "abc", truncate("abc", 5))
+	require.Equal(t, "ab", truncate("abc", 2))
+	require.Equal(t, "caf", truncate("café", 4))
+}
@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// SignatureHeader holds the timestamp and HMAC-SHA256 signature of a signed request, e.g. "t=1638316800,v1=5257a8..."
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+type Client struct {
+	httpClient *http.Client
+}
+
+// NewClient instantiates a webhook client
+func NewClient(httpClient *http.Client) *Client {
+	c := &Client{
+		httpClient: httpClient,
+	}
+
+	return c
+}
+
+// Post sends the payload as JSON to the webhook URL, non 2xx responses are returned as errors
+func (c *Client) Post(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %v", err)
+	}
+
+	_, err = c.send(ctx, url, body, map[string]string{})
+	return err
+}
+
+// PostSigned sends the JSON body to the webhook URL signed with the secret, along with the extra headers.
+// The response status code is returned whenever a response was received, even for non 2xx responses.
+func (c *Client) PostSigned(ctx context.Context, url, secret string, body []byte, headers map[string]string) (int, error) {
+	signedHeaders := map[string]string{
+		SignatureHeader: Sign(secret, time.Now(), body),
+	}
+	for key, value := range headers {
+		signedHeaders[key] = value
+	}
+
+	return c.send(ctx, url, body, signedHeaders)
+}
+
+// Sign returns the signature header value of the body. Receivers verify it by computing the HMAC-SHA256 of
+// "<timestamp>.<body>" with the shared secret.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return fmt.Sprintf("t=%s,v1=%s", t, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func (c *Client) send(ctx context.Context, url string, body []byte, headers map[string]string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("can't initialise http request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send webhook: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return res.StatusCode, fmt.Errorf("webhook returned non 2xx status: %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
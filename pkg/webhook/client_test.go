@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Sign(t *testing.T) {
+	timestamp := time.Unix(1638316800, 0)
+	body := []byte(`{"event_type":"ping"}`)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1638316800." + string(body)))
+
+	require.Equal(t, "t=1638316800,v1="+hex.EncodeToString(mac.Sum(nil)), Sign("secret", timestamp, body))
+	require.NotEqual(t, Sign("secret", timestamp, body), Sign("other", timestamp, body))
+}
+
+func Test_PostSigned(t *testing.T) {
+	var receivedSignature, receivedEvent, receivedBody string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		receivedBody = string(b)
+		receivedSignature = r.Header.Get(SignatureHeader)
+		receivedEvent = r.Header.Get("X-Webhook-Event")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.Client())
+	body := []byte(`{"event_type":"ping"}`)
+
+	status, err := c.PostSigned(context.Background(), server.URL, "secret", body, map[string]string{"X-Webhook-Event": "ping"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, status)
+	require.Equal(t, string(body), receivedBody)
+	require.Equal(t, "ping", receivedEvent)
+	require.True(t, strings.HasPrefix(receivedSignature, "t="))
+	require.Contains(t, receivedSignature, ",v1=")
+}
+
+func Test_PostSignedNonOKStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.Client())
+
+	status, err := c.PostSigned(context.Background(), server.URL, "secret", []byte(`{}`), nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, status)
+}
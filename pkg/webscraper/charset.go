@@ -0,0 +1,83 @@
+package webscraper
+
+import (
+	"bytes"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// metaCharsetPrescanLength is how far into an HTML page its charset declaration is looked for
+const metaCharsetPrescanLength = 4096
+
+// metaCharsetRegexp matches both <meta charset="..."> and <meta http-equiv="Content-Type" content="...; charset=...">
+var metaCharsetRegexp = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([\w:.-]+)`)
+
+// mediaType returns the lower cased media type of the content type, without its parameters
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = strings.Split(contentType, ";")[0]
+	}
+
+	return strings.ToLower(strings.TrimSpace(mt))
+}
+
+// decodeHTML converts the HTML page to UTF-8, see decodeText. A charset declared by the meta tags of the page
+// is used when there's no byte order mark nor charset in the content type.
+func decodeHTML(body []byte, contentType string) ([]byte, error) {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+	if !certain {
+		enc, name = metaCharset(body)
+	}
+
+	return decode(body, enc, name)
+}
+
+// decodeText converts the text document to UTF-8 using the charset of its byte order mark or of the content
+// type. Without any, the document is assumed to be UTF-8 when it's valid UTF-8 and Windows-1252 otherwise, like
+// browsers do.
+func decodeText(body []byte, contentType string) ([]byte, error) {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+	if !certain {
+		enc, name = nil, ""
+	}
+
+	return decode(body, enc, name)
+}
+
+func decode(body []byte, enc encoding.Encoding, name string) ([]byte, error) {
+	if enc == nil {
+		if utf8.Valid(body) {
+			enc, name = encoding.Nop, "utf-8"
+		} else {
+			enc, name = charmap.Windows1252, "windows-1252"
+		}
+	}
+
+	if name == "utf-8" {
+		return bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")), nil
+	}
+
+	return enc.NewDecoder().Bytes(body)
+}
+
+// metaCharset returns the encoding declared by the meta tags of the HTML page, nil when there's none or it's
+// unknown
+func metaCharset(body []byte) (encoding.Encoding, string) {
+	if len(body) > metaCharsetPrescanLength {
+		body = body[:metaCharsetPrescanLength]
+	}
+
+	match := metaCharsetRegexp.FindSubmatch(body)
+	if match == nil {
+		return nil, ""
+	}
+
+	return charset.Lookup(string(match[1]))
+}
@@ -0,0 +1,48 @@
+package webscraper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+)
+
+func Test_DecodeHTML(t *testing.T) {
+	latin1, err := charmap.ISO8859_1.NewEncoder().String("<html><head><title>Café crème</title></head></html>")
+	require.NoError(t, err)
+
+	shiftJIS, err := japanese.ShiftJIS.NewEncoder().String(`<html><head><meta charset="Shift_JIS"><title>日本語のページ</title></head></html>`)
+	require.NoError(t, err)
+
+	httpEquiv, err := charmap.Windows1252.NewEncoder().String(`<html><head><meta http-equiv="Content-Type" content="text/html; charset=windows-1252"><title>Über</title></head></html>`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		contains    string
+	}{
+		{"header charset", latin1, "text/html; charset=ISO-8859-1", "Café crème"},
+		{"meta charset", shiftJIS, "text/html", "日本語のページ"},
+		{"meta http-equiv", httpEquiv, "text/html", "Über"},
+		{"undeclared utf-8", "<html><head><title>Naïve</title></head></html>", "text/html", "Naïve"},
+		{"undeclared invalid utf-8", latin1, "text/html", "Café crème"},
+		{"utf-8 byte order mark", "\xef\xbb\xbf<title>Déjà vu</title>", "text/html; charset=ISO-8859-1", "<title>Déjà vu</title>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := decodeHTML([]byte(tt.body), tt.contentType)
+			require.NoError(t, err)
+			require.Contains(t, string(decoded), tt.contains)
+		})
+	}
+}
+
+func Test_MediaType(t *testing.T) {
+	require.Equal(t, "text/html", mediaType("Text/HTML; charset=UTF-8"))
+	require.Equal(t, "application/pdf", mediaType("application/pdf"))
+	require.Equal(t, "text/plain", mediaType("text/plain; charset"))
+}
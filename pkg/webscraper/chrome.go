@@ -0,0 +1,162 @@
+package webscraper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	// renderTimeout caps the time to load and render a page
+	renderTimeout = 30 * time.Second
+	// renderSettleDelay leaves time to the scripts to fill in the page once it's loaded
+	renderSettleDelay = time.Second
+)
+
+// ChromeFetcher renders the documents with a headless Chrome launched locally, running their scripts. Each
+// document is rendered in a new tab of the same browser.
+type ChromeFetcher struct {
+	browserCtx context.Context
+	cancel     context.CancelFunc
+
+	startOnce sync.Once
+	startErr  error
+}
+
+// NewChromeFetcher instantiates a fetcher rendering with the Chrome executable, identifying itself with the
+// user agent. The executable is looked up in the usual install locations when execPath is empty. The browser is
+// launched by the first fetch and stays open until Close.
+func NewChromeFetcher(execPath, userAgent string) *ChromeFetcher {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.UserAgent(userAgent),
+		chromedp.DisableGPU,
+		chromedp.NoSandbox,
+	)
+
+	if execPath != "" {
+		opts = append(opts, chromedp.ExecPath(execPath))
+	}
+
+	allocatorCtx, cancelAllocator := chromedp.NewExecAllocator(context.Background(), opts...)
+	browserCtx, cancelBrowser := chromedp.NewContext(allocatorCtx)
+
+	f := &ChromeFetcher{
+		browserCtx: browserCtx,
+		cancel: func() {
+			cancelBrowser()
+			cancelAllocator()
+		},
+	}
+
+	return f
+}
+
+// Close closes the browser
+func (f *ChromeFetcher) Close() {
+	f.cancel()
+}
+
+// start launches the browser. It runs once, without a timeout: the first run of a context owns the browser
+// and cancelling it would close the browser.
+func (f *ChromeFetcher) start() error {
+	f.startOnce.Do(func() {
+		f.startErr = chromedp.Run(f.browserCtx)
+	})
+
+	return f.startErr
+}
+
+// Fetch loads the link in a new tab and returns the HTML of the page once rendered
+func (f *ChromeFetcher) Fetch(ctx context.Context, link string) (*FetchResponse, error) {
+	if err := f.start(); err != nil {
+		return nil, fmt.Errorf("failed to launch browser: %w", err)
+	}
+
+	// the browser is running, this context opens a tab in it that closes on cancel
+	tabCtx, cancelTab := chromedp.NewContext(f.browserCtx)
+	defer cancelTab()
+
+	tabCtx, cancelTimeout := context.WithTimeout(tabCtx, renderTimeout)
+	defer cancelTimeout()
+
+	// the tab doesn't derive from the context of the request, stop rendering when it's done
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancelTimeout()
+		case <-tabCtx.Done():
+		}
+	}()
+
+	var mu sync.Mutex
+	var documentRequestID network.RequestID
+	var document *network.Response
+	redirects := []string{}
+
+	// the first document requested by the tab is the page, the next ones are its frames
+	chromedp.ListenTarget(tabCtx, func(ev interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch e := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			if e.Type != network.ResourceTypeDocument {
+				return
+			}
+
+			if documentRequestID == "" {
+				documentRequestID = e.RequestID
+			}
+
+			if e.RequestID == documentRequestID && e.RedirectResponse != nil {
+				redirects = append(redirects, e.RedirectResponse.URL)
+			}
+		case *network.EventResponseReceived:
+			if e.RequestID == documentRequestID {
+				document = e.Response
+			}
+		}
+	})
+
+	var html string
+	err := chromedp.Run(tabCtx,
+		network.Enable(),
+		chromedp.Navigate(link),
+		chromedp.Sleep(renderSettleDelay),
+		chromedp.OuterHTML("html", &html, chromedp.ByQuery),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render link (%s): %w", link, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if document == nil {
+		return nil, fmt.Errorf("no document received for link (%s)", link)
+	}
+
+	// the rendered page is serialised back to HTML in UTF-8, whatever the charset it was served in
+	contentType := document.MimeType
+	if contentType == "text/html" {
+		contentType = "text/html; charset=utf-8"
+	}
+
+	fetchResponse := &FetchResponse{
+		StatusCode:    int(document.Status),
+		Status:        fmt.Sprintf("%d %s", document.Status, document.StatusText),
+		FinalURL:      document.URL,
+		Redirects:     redirects,
+		ContentType:   contentType,
+		ContentLength: int64(len(html)),
+		Body:          io.NopCloser(strings.NewReader(html)),
+	}
+
+	return fetchResponse, nil
+}
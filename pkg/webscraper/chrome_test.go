@@ -0,0 +1,89 @@
+package webscraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+// chromeExecutables are the names Chrome is installed under, looked up in the PATH
+var chromeExecutables = []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "headless-shell"}
+
+func lookChrome(t *testing.T) string {
+	for _, name := range chromeExecutables {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+
+	t.Skip("chrome is not installed")
+	return ""
+}
+
+func Test_ChromeFetcherRendersScripts(t *testing.T) {
+	execPath := lookChrome(t)
+
+	text := strings.Repeat("Rendered text filled in by the scripts of the page. ", 10)
+	page := `<html><head><title>App</title></head><body><div id="root"></div>
+<script>document.getElementById("root").innerHTML = "<p>` + text + `</p>";</script></body></html>`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/app", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	fetcher := NewChromeFetcher(execPath, "TestBot/1.0")
+	defer fetcher.Close()
+
+	c := NewClient(server.Client(), "TestBot/1.0", fetcher)
+
+	res, err := c.Render(context.Background(), server.URL+"/old")
+	require.NoError(t, err)
+	require.Equal(t, "App", res.Title)
+	require.Contains(t, res.Body, strings.TrimSpace(text))
+
+	d := res.Diagnostics
+	require.True(t, d.Rendered)
+	require.Equal(t, http.StatusOK, d.StatusCode)
+	require.Equal(t, server.URL+"/app", d.FinalURL)
+	require.Equal(t, []string{server.URL + "/old"}, d.Redirects)
+	require.Equal(t, "text/html; charset=utf-8", d.ContentType)
+}
+
+func Test_ChromeFetcherReusesBrowser(t *testing.T) {
+	execPath := lookChrome(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Page</title></head><body><p>Page</p></body></html>`))
+	}))
+	defer server.Close()
+
+	fetcher := NewChromeFetcher(execPath, "TestBot/1.0")
+	defer fetcher.Close()
+
+	_, err := fetcher.Fetch(context.Background(), server.URL+"/first")
+	require.NoError(t, err)
+
+	browser := chromedp.FromContext(fetcher.browserCtx).Browser
+	require.NotNil(t, browser)
+	pid := browser.Process().Pid
+
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/second")
+	require.NoError(t, err)
+
+	browser = chromedp.FromContext(fetcher.browserCtx).Browser
+	require.NotNil(t, browser)
+	require.Equal(t, pid, browser.Process().Pid)
+}
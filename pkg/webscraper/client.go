@@ -1,18 +1,40 @@
 package webscraper
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"math"
 	"net/http"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/PuerkitoBio/goquery"
 	log "github.com/sirupsen/logrus"
 )
 
+// minStaticTextLength is the characters of text below which a static HTML page needs rendering, when a renderer
+// is set, as it's likely filled in by scripts
+const minStaticTextLength = 250
+
+// maxDocumentSize is the number of bytes of a document above which it isn't scraped
+const maxDocumentSize = 10 * 1024 * 1024
+
 type Client struct {
-	httpClient *http.Client
+	httpFetcher *HTTPFetcher
+	renderer    Fetcher
+	userAgent   string
+}
+
+// ContentStructure counts the blocks of a document
+type ContentStructure struct {
+	Paragraphs int
+	Headings   int
+	Images     int
+	Lists      int
+	Tables     int
 }
 
 type Link struct {
@@ -23,51 +45,188 @@ type Link struct {
 type ScrapeResult struct {
 	Title       string
 	Description string
-	Body        string
+	// H1 are the texts of the top level headings of the page
+	H1   []string
+	Body string
+	// Sections are the unique lines of text of the body, in document order
+	Sections []string
+	// MainContent is the body without the navigation, banners, sidebars and footers of the page
+	MainContent string
+	// MainSections are the unique lines of text of the main content, in document order
+	MainSections []string
+	// Structure counts the blocks of the main content
+	Structure   ContentStructure
 	Links       []Link
+	Diagnostics Diagnostics
 }
 
-func NewClient(httpClient *http.Client) *Client {
+// NewClient instantiates a webscraper client identifying itself with the user agent, which is also used to
+// pick the robots.txt rules. Pages are fetched with the http client, and can be rendered with the renderer when
+// their static HTML has too little text. Pages are never rendered when the renderer is nil.
+func NewClient(httpClient *http.Client, userAgent string, renderer Fetcher) *Client {
 	c := &Client{
-		httpClient,
+		httpFetcher: NewHTTPFetcher(httpClient, userAgent),
+		renderer:    renderer,
+		userAgent:   userAgent,
 	}
 
 	return c
 }
 
+// UserAgent returns the user agent the client sends
+func (c *Client) UserAgent() string {
+	return c.userAgent
+}
+
+// Scrape fetches and parses the HTML page, PDF or plain text document of the link. Failures are returned as a
+// *ScrapeError holding the diagnostics of the fetch.
 func (c *Client) Scrape(ctx context.Context, link string) (*ScrapeResult, error) {
-	res, err := c.httpClient.Get(link)
+	return c.scrape(ctx, c.httpFetcher, link)
+}
+
+// NeedsRendering returns whether the scraped page looks like a shell filled in by scripts, which Render can fill
+// in. It's always false without a renderer.
+func (c *Client) NeedsRendering(scrapeResult *ScrapeResult) bool {
+	if c.renderer == nil {
+		return false
+	}
+
+	mt := mediaType(scrapeResult.Diagnostics.ContentType)
+	if mt != "text/html" && mt != "application/xhtml+xml" {
+		return false
+	}
+
+	return utf8.RuneCountInString(scrapeResult.Body) < minStaticTextLength
+}
+
+// Render fetches and parses the page of the link with the renderer, running its scripts. The page is fetched
+// again, so the caller is expected to apply the same robots.txt rules and rate limits as for Scrape.
+func (c *Client) Render(ctx context.Context, link string) (*ScrapeResult, error) {
+	if c.renderer == nil {
+		return nil, fmt.Errorf("no renderer to render link (%s)", link)
+	}
+
+	rendered, err := c.scrape(ctx, c.renderer, link)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get link (%s): %v", link, err)
+		return nil, err
+	}
+
+	rendered.Diagnostics.Rendered = true
+
+	return rendered, nil
+}
+
+func (c *Client) scrape(ctx context.Context, fetcher Fetcher, link string) (*ScrapeResult, error) {
+	start := time.Now()
+
+	res, err := fetcher.Fetch(ctx, link)
+	if err != nil {
+		return nil, newScrapeError(Diagnostics{FinalURL: link}, start, errorCategory(err), fmt.Errorf("failed to get link (%s): %v", link, err))
 	}
 
 	defer res.Body.Close()
 
+	diagnostics := responseDiagnostics(res)
+
 	if res.StatusCode != 200 {
-		return nil, fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
+		return nil, newScrapeError(diagnostics, start, statusCategory(res.StatusCode), fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status))
 	}
 
-	contentType := res.Header.Get("Content-Type")
-	if !strings.HasPrefix(contentType, "text/html") {
-		return nil, fmt.Errorf("content type is not text/html: %s", contentType)
+	// the content type is sniffed from the body when the response doesn't have one
+	contentType := res.ContentType
+	if contentType != "" && documentParser(contentType) == nil {
+		return nil, newScrapeError(diagnostics, start, FailureUnsupportedType, fmt.Errorf("content type is not supported: %s", contentType))
 	}
 
-	doc, err := goquery.NewDocumentFromReader(res.Body)
+	// one byte past the limit is read to tell documents of the maximum size from larger ones
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxDocumentSize+1))
 	if err != nil {
-		log.Fatal(err)
+		return nil, newScrapeError(diagnostics, start, errorCategory(err), fmt.Errorf("failed to read body of link (%s): %v", link, err))
+	}
+
+	if len(body) > maxDocumentSize {
+		return nil, newScrapeError(diagnostics, start, FailureTooLarge, fmt.Errorf("document is larger than %d bytes", maxDocumentSize))
+	}
+
+	diagnostics.ContentLength = int64(len(body))
+	diagnostics.ResponseTime = time.Since(start)
+
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+		diagnostics.ContentType = contentType
+	}
+
+	parse := documentParser(contentType)
+	if parse == nil {
+		return nil, newScrapeError(diagnostics, start, FailureUnsupportedType, fmt.Errorf("content type is not supported: %s", contentType))
+	}
+
+	scrapeResult, err := parse(body, contentType)
+	if err != nil {
+		return nil, newScrapeError(diagnostics, start, FailureParse, fmt.Errorf("failed to parse link (%s): %v", link, err))
+	}
+
+	log.Infof("\n[%s] - %s\n", link, scrapeResult.Title)
+
+	scrapeResult.Diagnostics = diagnostics
+
+	return scrapeResult, nil
+}
+
+// documentParser returns the parser of documents of the content type, nil when it isn't supported
+func documentParser(contentType string) func(body []byte, contentType string) (*ScrapeResult, error) {
+	switch mediaType(contentType) {
+	case "text/html", "application/xhtml+xml":
+		return parseHTML
+	case "application/pdf":
+		return parsePDF
+	case "text/plain":
+		return parseText
+	default:
+		return nil
+	}
+}
+
+// parseHTML extracts the title, description, links and text of an HTML page
+func parseHTML(body []byte, contentType string) (*ScrapeResult, error) {
+	decoded, err := decodeHTML(body, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(decoded))
+	if err != nil {
+		return nil, err
 	}
 
 	// Remove
 	doc.Find("script").Remove()
 	doc.Find("br").Remove()
-	doc.Find("img").Remove()
 	doc.Find("iframe").Remove()
 	doc.Find("style").Remove()
 
+	// the whole body is the main content of pages without paragraphs of text
+	mainNodes := mainContentNodes(doc)
+	structureNodes := mainNodes
+	if len(mainNodes) == 0 {
+		structureNodes = doc.Find("body").Nodes
+	}
+
+	// images are counted before being removed
+	structure := countStructure(structureNodes)
+	doc.Find("img").Remove()
+
 	// Title
 	title := doc.Find("title").Text()
 
-	log.Infof("\n[%s] - %s\n", link, title)
+	// Top level headings
+	h1 := []string{}
+	doc.Find("h1").Each(func(_ int, item *goquery.Selection) {
+		text := strings.Join(strings.Fields(item.Text()), " ")
+		if text != "" {
+			h1 = append(h1, text)
+		}
+	})
 
 	// Links
 	links := []Link{}
@@ -96,31 +255,44 @@ func (c *Client) Scrape(ctx context.Context, link string) (*ScrapeResult, error)
 		}
 	})
 
-	// BODY
-	bodyContentsMap := map[string]bool{}
+	// BODY, unique lines of text in document order
+	bodyContents := []string{}
+	seenBodyContents := map[string]bool{}
 	doc.Find("body *").Each(func(_ int, item *goquery.Selection) {
 		b := strings.TrimSpace(item.Text())
-		for _, line := range strings.Split(b, "\n") {
-			lineCompact := strings.TrimSpace(line)
-
-			if lineCompact != "" {
-				bodyContentsMap[lineCompact] = true
-			}
-		}
+		bodyContents = appendLines(bodyContents, seenBodyContents, b)
 	})
 
-	// get body
-	bodyContents := []string{}
-	for k := range bodyContentsMap {
-		bodyContents = append(bodyContents, k)
+	mainContents := contentLines(mainNodes)
+	if len(mainContents) == 0 {
+		mainContents = bodyContents
 	}
 
 	scrapeResult := &ScrapeResult{
-		Title:       title,
-		Description: description,
-		Body:        strings.Join(bodyContents, " "),
-		Links:       links,
+		Title:        title,
+		Description:  description,
+		H1:           h1,
+		Body:         strings.Join(bodyContents, " "),
+		Sections:     bodyContents,
+		MainContent:  strings.Join(mainContents, " "),
+		MainSections: mainContents,
+		Structure:    structure,
+		Links:        links,
 	}
 
 	return scrapeResult, nil
 }
+
+// appendLines appends the lines of the text not seen yet, with their whitespace collapsed
+func appendLines(lines []string, seen map[string]bool, text string) []string {
+	for _, line := range strings.Split(text, "\n") {
+		lineCompact := strings.Join(strings.Fields(line), " ")
+
+		if lineCompact != "" && !seen[lineCompact] {
+			seen[lineCompact] = true
+			lines = append(lines, lineCompact)
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,250 @@
+package webscraper
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPage = `<html><head><title>Test page</title><meta name="description" content="A test page"></head>
+<body><h1>Heading</h1><p>First paragraph</p><a href="/other">Other page</a></body></html>`
+
+func testServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(testPage))
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/unavailable", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.Repeat("a", maxDocumentSize+1)))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func Test_ScrapeDiagnostics(t *testing.T) {
+	server := testServer()
+	defer server.Close()
+
+	c := NewClient(server.Client(), "TestBot/1.0", nil)
+
+	res, err := c.Scrape(context.Background(), server.URL+"/old")
+	require.NoError(t, err)
+	require.Equal(t, "Test page", res.Title)
+	require.Equal(t, "A test page", res.Description)
+	require.Equal(t, []string{"Heading"}, res.H1)
+
+	d := res.Diagnostics
+	require.Equal(t, http.StatusOK, d.StatusCode)
+	require.Equal(t, server.URL+"/page", d.FinalURL)
+	require.Equal(t, []string{server.URL + "/old", server.URL + "/moved"}, d.Redirects)
+	require.Equal(t, int64(len(testPage)), d.ContentLength)
+	require.Equal(t, "text/html; charset=utf-8", d.ContentType)
+	require.Empty(t, d.FailureCategory)
+}
+
+func Test_ScrapeFailureCategories(t *testing.T) {
+	server := testServer()
+	defer server.Close()
+
+	httpClient := server.Client()
+	httpClient.Timeout = 50 * time.Millisecond
+	c := NewClient(httpClient, "TestBot/1.0", nil)
+
+	tests := []struct {
+		path       string
+		category   string
+		statusCode int
+	}{
+		{"/unavailable", FailureServerError, http.StatusServiceUnavailable},
+		{"/missing", FailureClientError, http.StatusNotFound},
+		{"/json", FailureUnsupportedType, http.StatusOK},
+		{"/large", FailureTooLarge, http.StatusOK},
+		{"/slow", FailureTimeout, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := c.Scrape(context.Background(), server.URL+tt.path)
+
+			var scrapeErr *ScrapeError
+			require.True(t, errors.As(err, &scrapeErr))
+			require.Equal(t, tt.category, scrapeErr.Diagnostics.FailureCategory)
+			require.Equal(t, tt.statusCode, scrapeErr.Diagnostics.StatusCode)
+			require.NotEmpty(t, scrapeErr.Diagnostics.Error)
+		})
+	}
+}
+
+func Test_ScrapeDocuments(t *testing.T) {
+	documents := map[string]struct {
+		contentType string
+		body        []byte
+	}{
+		"/latin1":   {"text/html; charset=ISO-8859-1", []byte("<html><head><title>Caf\xe9</title></head><body><p>Cr\xe8me br\xfbl\xe9e</p></body></html>")},
+		"/guide":    {"application/pdf", testPDF(t, "Running shoes guide")},
+		"/notes":    {"text/plain; charset=utf-8", []byte("Release notes\nFaster crawls")},
+		"/untyped":  {"", []byte("<!DOCTYPE html><html><head><title>Sniffed</title></head></html>")},
+		"/download": {"application/octet-stream", []byte{0x00, 0x01}},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		document := documents[r.URL.Path]
+		if document.contentType != "" {
+			w.Header().Set("Content-Type", document.contentType)
+		} else {
+			// stops net/http from sniffing the content type
+			w.Header()["Content-Type"] = nil
+		}
+		w.Write(document.body)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.Client(), "TestBot/1.0", nil)
+
+	tests := []struct {
+		path     string
+		title    string
+		sections []string
+	}{
+		{"/latin1", "Café", []string{"Crème brûlée"}},
+		{"/guide", "Running shoes guide", []string{"Best running shoes", "Cushioning matters for long runs", "Trail shoes grip better"}},
+		{"/notes", "Release notes", []string{"Release notes", "Faster crawls"}},
+		{"/untyped", "Sniffed", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			res, err := c.Scrape(context.Background(), server.URL+tt.path)
+			require.NoError(t, err)
+			require.Equal(t, tt.title, res.Title)
+			require.Equal(t, tt.sections, res.Sections)
+		})
+	}
+
+	_, err := c.Scrape(context.Background(), server.URL+"/download")
+
+	var scrapeErr *ScrapeError
+	require.True(t, errors.As(err, &scrapeErr))
+	require.Equal(t, FailureUnsupportedType, scrapeErr.Diagnostics.FailureCategory)
+}
+
+func Test_ScrapeSendsUserAgent(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(testPage))
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.Client(), "TestBot/1.0", nil).Scrape(context.Background(), server.URL)
+	require.NoError(t, err)
+	require.Equal(t, "TestBot/1.0", userAgent)
+}
+
+func Test_StatusCategory(t *testing.T) {
+	require.Equal(t, FailureRateLimited, statusCategory(http.StatusTooManyRequests))
+	require.Equal(t, FailureServerError, statusCategory(http.StatusBadGateway))
+	require.Equal(t, FailureClientError, statusCategory(http.StatusForbidden))
+	require.Equal(t, FailureUnexpectedStatus, statusCategory(http.StatusNoContent))
+}
+
+type stubRenderer struct {
+	html    string
+	err     error
+	fetched []string
+}
+
+func (r *stubRenderer) Fetch(ctx context.Context, link string) (*FetchResponse, error) {
+	r.fetched = append(r.fetched, link)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return &FetchResponse{
+		StatusCode:    http.StatusOK,
+		Status:        "200 OK",
+		FinalURL:      link,
+		Redirects:     []string{},
+		ContentType:   "text/html; charset=utf-8",
+		ContentLength: int64(len(r.html)),
+		Body:          io.NopCloser(strings.NewReader(r.html)),
+	}, nil
+}
+
+func Test_RenderScriptedPages(t *testing.T) {
+	const shell = `<html><head><title>App</title></head><body><div id="root"></div><script src="/app.js"></script></body></html>`
+	renderedPage := `<html><head><title>App</title></head><body><div id="root"><p>` + strings.Repeat("Rendered text filled in by the scripts of the page. ", 10) + `</p></div></body></html>`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/app" {
+			w.Write([]byte(shell))
+			return
+		}
+		w.Write([]byte(`<html><body><p>` + strings.Repeat("Static text served with the page. ", 10) + `</p></body></html>`))
+	}))
+	defer server.Close()
+
+	renderer := &stubRenderer{html: renderedPage}
+	c := NewClient(server.Client(), "TestBot/1.0", renderer)
+
+	res, err := c.Scrape(context.Background(), server.URL+"/app")
+	require.NoError(t, err)
+	require.True(t, c.NeedsRendering(res))
+	require.Empty(t, renderer.fetched)
+
+	res, err = c.Render(context.Background(), server.URL+"/app")
+	require.NoError(t, err)
+	require.True(t, res.Diagnostics.Rendered)
+	require.Contains(t, res.Body, "Rendered text filled in by the scripts")
+	require.Equal(t, []string{server.URL + "/app"}, renderer.fetched)
+
+	// pages with enough static text don't need rendering
+	res, err = c.Scrape(context.Background(), server.URL+"/static")
+	require.NoError(t, err)
+	require.False(t, c.NeedsRendering(res))
+
+	// nor do any without a renderer
+	c = NewClient(server.Client(), "TestBot/1.0", nil)
+
+	res, err = c.Scrape(context.Background(), server.URL+"/app")
+	require.NoError(t, err)
+	require.False(t, c.NeedsRendering(res))
+
+	_, err = c.Render(context.Background(), server.URL+"/app")
+	require.Error(t, err)
+
+	// rendering failures are scrape errors
+	c = NewClient(server.Client(), "TestBot/1.0", &stubRenderer{err: errors.New("chrome not found")})
+
+	_, err = c.Render(context.Background(), server.URL+"/app")
+
+	var scrapeErr *ScrapeError
+	require.True(t, errors.As(err, &scrapeErr))
+}
@@ -0,0 +1,142 @@
+package webscraper
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Failure categories of a fetch
+const (
+	FailureTimeout           = "timeout"
+	FailureDNS               = "dns"
+	FailureTLS               = "tls"
+	FailureConnection        = "connection"
+	FailureTooManyRedirects  = "too_many_redirects"
+	FailureRateLimited       = "http_429"
+	FailureClientError       = "http_4xx"
+	FailureServerError       = "http_5xx"
+	FailureUnexpectedStatus  = "http_status"
+	FailureUnsupportedType   = "unsupported_content_type"
+	FailureTooLarge          = "too_large"
+	FailureParse             = "parse"
+	FailureBlockedByRobots   = "robots_blocked"
+	FailureRobotsUnreachable = "robots_unreachable"
+	FailureUnknown           = "unknown"
+)
+
+// Diagnostics describe how a URL was fetched, whether it succeeded or not
+type Diagnostics struct {
+	StatusCode int
+	// FinalURL is the URL the redirects, if any, led to
+	FinalURL string
+	// Redirects are the URLs redirected from, in order
+	Redirects     []string
+	ResponseTime  time.Duration
+	ContentLength int64
+	ContentType   string
+	// Rendered is whether the page was rendered by a browser, its static HTML having too little text
+	Rendered bool
+	Error    string
+	// FailureCategory is empty when the fetch succeeded
+	FailureCategory string
+}
+
+// ScrapeError is returned when a URL can't be scraped, along with the diagnostics of the fetch
+type ScrapeError struct {
+	Diagnostics Diagnostics
+	Err         error
+}
+
+func (e *ScrapeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ScrapeError) Unwrap() error {
+	return e.Err
+}
+
+// newScrapeError completes the diagnostics with the failure
+func newScrapeError(diagnostics Diagnostics, start time.Time, category string, err error) *ScrapeError {
+	diagnostics.ResponseTime = time.Since(start)
+	diagnostics.FailureCategory = category
+	diagnostics.Error = err.Error()
+
+	return &ScrapeError{
+		Diagnostics: diagnostics,
+		Err:         err,
+	}
+}
+
+// responseDiagnostics returns the diagnostics known once the response headers are received
+func responseDiagnostics(res *FetchResponse) Diagnostics {
+	return Diagnostics{
+		StatusCode:    res.StatusCode,
+		FinalURL:      res.FinalURL,
+		Redirects:     res.Redirects,
+		ContentLength: res.ContentLength,
+		ContentType:   res.ContentType,
+	}
+}
+
+// redirectChain returns the URLs that were redirected from to get the response, in order
+func redirectChain(res *http.Response) []string {
+	chain := []string{}
+	for req := res.Request; req.Response != nil; req = req.Response.Request {
+		chain = append([]string{req.Response.Request.URL.String()}, chain...)
+	}
+
+	return chain
+}
+
+// errorCategory returns the failure category of an error returned by the http client
+func errorCategory(err error) string {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return FailureDNS
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return FailureTimeout
+	}
+
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var certificateInvalidErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	var recordHeaderErr tls.RecordHeaderError
+	if errors.As(err, &unknownAuthorityErr) || errors.As(err, &certificateInvalidErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &recordHeaderErr) || strings.Contains(err.Error(), "tls: ") {
+		return FailureTLS
+	}
+
+	if strings.Contains(err.Error(), "stopped after") {
+		return FailureTooManyRedirects
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return FailureConnection
+	}
+
+	return FailureUnknown
+}
+
+// statusCategory returns the failure category of a non 200 status code
+func statusCategory(statusCode int) string {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return FailureRateLimited
+	case statusCode >= http.StatusInternalServerError:
+		return FailureServerError
+	case statusCode >= http.StatusBadRequest:
+		return FailureClientError
+	default:
+		return FailureUnexpectedStatus
+	}
+}
@@ -0,0 +1,128 @@
+package webscraper
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+const (
+	// maxTextTitleLength caps the title of a plain text document, taken from its first line
+	maxTextTitleLength = 120
+	// pdfWordSpacing is the gap between two glyphs of a PDF, relative to the font size, above which they're
+	// considered to be in different words
+	pdfWordSpacing = 0.15
+)
+
+// parsePDF extracts the title, subject and text of a PDF document. The title falls back to the first line of
+// text when the document doesn't have one.
+func parsePDF(body []byte, _ string) (scrapeResult *ScrapeResult, err error) {
+	// the pdf reader panics on malformed documents
+	defer func() {
+		if r := recover(); r != nil {
+			scrapeResult = nil
+			err = fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	// text of the pages, unique lines in document order
+	bodyContents := []string{}
+	seenBodyContents := map[string]bool{}
+	for i := 1; i <= reader.NumPage(); i++ {
+		for _, line := range pageLines(reader.Page(i)) {
+			bodyContents = appendLines(bodyContents, seenBodyContents, line)
+		}
+	}
+
+	info := reader.Trailer().Key("Info")
+
+	title := strings.TrimSpace(info.Key("Title").Text())
+	if title == "" {
+		title = firstLine(bodyContents)
+	}
+
+	scrapeResult = &ScrapeResult{
+		Title:        title,
+		Description:  strings.TrimSpace(info.Key("Subject").Text()),
+		H1:           []string{},
+		Body:         strings.Join(bodyContents, " "),
+		Sections:     bodyContents,
+		MainContent:  strings.Join(bodyContents, " "),
+		MainSections: bodyContents,
+		Structure:    ContentStructure{Paragraphs: len(bodyContents)},
+		Links:        []Link{},
+	}
+
+	return scrapeResult, nil
+}
+
+// pageLines returns the lines of text of the PDF page. The page only positions its glyphs, so a line ends when
+// the next glyph is on another baseline and words are separated where glyphs are spaced apart.
+func pageLines(page pdf.Page) []string {
+	lines := []string{}
+
+	var line strings.Builder
+	texts := page.Content().Text
+
+	var prev *pdf.Text
+	for i, text := range texts {
+		if prev != nil {
+			if math.Abs(text.Y-prev.Y) > prev.FontSize/2 {
+				lines = append(lines, line.String())
+				line.Reset()
+			} else if text.X-(prev.X+prev.W) > text.FontSize*pdfWordSpacing {
+				line.WriteString(" ")
+			}
+		}
+
+		line.WriteString(text.S)
+		prev = &texts[i]
+	}
+
+	return append(lines, line.String())
+}
+
+// parseText extracts the text of a plain text document, its title being its first line
+func parseText(body []byte, contentType string) (*ScrapeResult, error) {
+	decoded, err := decodeText(body, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyContents := appendLines([]string{}, map[string]bool{}, string(decoded))
+
+	scrapeResult := &ScrapeResult{
+		Title:        firstLine(bodyContents),
+		H1:           []string{},
+		Body:         strings.Join(bodyContents, " "),
+		Sections:     bodyContents,
+		MainContent:  strings.Join(bodyContents, " "),
+		MainSections: bodyContents,
+		Structure:    ContentStructure{Paragraphs: len(bodyContents)},
+		Links:        []Link{},
+	}
+
+	return scrapeResult, nil
+}
+
+// firstLine returns the first line of the document capped to maxTextTitleLength characters
+func firstLine(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+
+	runes := []rune(lines[0])
+	if len(runes) > maxTextTitleLength {
+		return strings.TrimSpace(string(runes[:maxTextTitleLength]))
+	}
+
+	return lines[0]
+}
@@ -0,0 +1,61 @@
+package webscraper
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/stretchr/testify/require"
+)
+
+func testPDF(t *testing.T, title string) []byte {
+	doc := gofpdf.New("P", "mm", "A4", "")
+	doc.SetTitle(title, true)
+	doc.SetSubject("Running shoes buying guide", true)
+	doc.SetFont("Helvetica", "", 12)
+
+	doc.AddPage()
+	doc.Cell(0, 10, "Best running shoes")
+	doc.Ln(10)
+	doc.Cell(0, 10, "Cushioning matters for long runs")
+
+	doc.AddPage()
+	doc.Cell(0, 10, "Trail shoes grip better")
+
+	var buf bytes.Buffer
+	require.NoError(t, doc.Output(&buf))
+
+	return buf.Bytes()
+}
+
+func Test_ParsePDF(t *testing.T) {
+	res, err := parsePDF(testPDF(t, "Running shoes guide"), "application/pdf")
+	require.NoError(t, err)
+	require.Equal(t, "Running shoes guide", res.Title)
+	require.Equal(t, "Running shoes buying guide", res.Description)
+	require.Equal(t, []string{"Best running shoes", "Cushioning matters for long runs", "Trail shoes grip better"}, res.Sections)
+	require.Equal(t, "Best running shoes Cushioning matters for long runs Trail shoes grip better", res.Body)
+
+	res, err = parsePDF(testPDF(t, ""), "application/pdf")
+	require.NoError(t, err)
+	require.Equal(t, "Best running shoes", res.Title)
+
+	_, err = parsePDF([]byte("%PDF-1.4 not really a pdf"), "application/pdf")
+	require.Error(t, err)
+}
+
+func Test_ParseText(t *testing.T) {
+	res, err := parseText([]byte("Release notes\r\n\r\n  Faster   crawls\nFaster crawls\nFewer errors\n"), "text/plain")
+	require.NoError(t, err)
+	require.Equal(t, "Release notes", res.Title)
+	require.Equal(t, []string{"Release notes", "Faster crawls", "Fewer errors"}, res.Sections)
+	require.Equal(t, "Release notes Faster crawls Fewer errors", res.Body)
+
+	res, err = parseText([]byte("Caf\xe9"), "text/plain")
+	require.NoError(t, err)
+	require.Equal(t, "Café", res.Title)
+
+	res, err = parseText([]byte("Caf\xe9"), "text/plain; charset=iso-8859-15")
+	require.NoError(t, err)
+	require.Equal(t, "Café", res.Title)
+}
@@ -0,0 +1,75 @@
+package webscraper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Fetcher fetches the document of a link
+type Fetcher interface {
+	Fetch(ctx context.Context, link string) (*FetchResponse, error)
+}
+
+// FetchResponse is a fetched document, its body has to be closed
+type FetchResponse struct {
+	StatusCode int
+	Status     string
+	// FinalURL is the URL the redirects, if any, led to
+	FinalURL string
+	// Redirects are the URLs redirected from, in order
+	Redirects   []string
+	ContentType string
+	// ContentLength is -1 when unknown
+	ContentLength int64
+	Body          io.ReadCloser
+}
+
+// HTTPFetcher fetches the documents as served, without running their scripts
+type HTTPFetcher struct {
+	httpClient *http.Client
+	userAgent  string
+}
+
+// NewHTTPFetcher instantiates a fetcher sending requests with the http client, identifying itself with the
+// user agent
+func NewHTTPFetcher(httpClient *http.Client, userAgent string) *HTTPFetcher {
+	f := &HTTPFetcher{
+		httpClient: httpClient,
+		userAgent:  userAgent,
+	}
+
+	return f
+}
+
+// Fetch requests the link, following its redirects
+func (f *HTTPFetcher) Fetch(ctx context.Context, link string) (*FetchResponse, error) {
+	res, err := f.get(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchResponse := &FetchResponse{
+		StatusCode:    res.StatusCode,
+		Status:        res.Status,
+		FinalURL:      res.Request.URL.String(),
+		Redirects:     redirectChain(res),
+		ContentType:   res.Header.Get("Content-Type"),
+		ContentLength: res.ContentLength,
+		Body:          res.Body,
+	}
+
+	return fetchResponse, nil
+}
+
+func (f *HTTPFetcher) get(ctx context.Context, link string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't initialise http request: %v", err)
+	}
+
+	req.Header.Set("User-Agent", f.userAgent)
+
+	return f.httpClient.Do(req)
+}
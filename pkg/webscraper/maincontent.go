@@ -0,0 +1,389 @@
+package webscraper
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// The main content of a page is found like readability does. Every paragraph of text scores points for its
+// parent and grandparent blocks, more so the longer and denser in text it is. The score of a block is then
+// scaled down by its link density, so menus and link lists lose to article text. The best block, along with
+// its siblings scoring close to it, is the main content.
+const (
+	// minParagraphLength is the characters a paragraph needs to count in the scoring
+	minParagraphLength = 25
+	// minSiblingScore and siblingScoreRatio are the score a sibling of the best block needs to be part of the
+	// main content, in points and relative to the best block
+	minSiblingScore   = 10
+	siblingScoreRatio = 0.2
+	// maxLinkDensity is the ratio of link text above which a list or a section of the main content is dropped
+	maxLinkDensity = 0.5
+)
+
+var (
+	// boilerplateTags are never part of the main content
+	boilerplateTags = map[string]bool{
+		"aside": true, "button": true, "dialog": true, "footer": true, "form": true, "nav": true, "noscript": true,
+		"select": true, "svg": true,
+	}
+	boilerplateRoles = map[string]bool{
+		"alertdialog": true, "banner": true, "complementary": true, "contentinfo": true, "dialog": true,
+		"navigation": true, "search": true,
+	}
+	blockTags = map[string]bool{
+		"address": true, "article": true, "aside": true, "blockquote": true, "dd": true, "div": true, "dl": true,
+		"dt": true, "fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true, "h1": true,
+		"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true,
+		"main": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true, "table": true, "tbody": true,
+		"td": true, "th": true, "thead": true, "tr": true, "ul": true,
+	}
+	// paragraphTags are the blocks scored when they don't hold other blocks
+	paragraphTags = map[string]bool{
+		"blockquote": true, "dd": true, "div": true, "li": true, "p": true, "pre": true, "section": true, "td": true,
+	}
+	// containerTags are dropped from the main content when they're mostly links
+	containerTags = map[string]bool{
+		"div": true, "dl": true, "ol": true, "section": true, "table": true, "ul": true,
+	}
+
+	unlikelyCandidatesRegexp = regexp.MustCompile(`(?i)banner|breadcrumb|comment|consent|cookie|disqus|footer|gdpr|header|menu|modal|navbar|newsletter|popup|promo|related|share|sharing|sidebar|social|sponsor|subscribe|widget`)
+	likelyCandidatesRegexp   = regexp.MustCompile(`(?i)article|body|content|entry|main|post|story|text`)
+	positiveClassRegexp      = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|story|text|blog`)
+	negativeClassRegexp      = regexp.MustCompile(`(?i)comment|footer|masthead|meta|promo|related|share|sidebar|sponsor|widget`)
+)
+
+// mainContentNodes returns the blocks making the main content of the page, nil when none of its blocks has
+// paragraphs of text
+func mainContentNodes(doc *goquery.Document) []*html.Node {
+	body := doc.Find("body")
+	if body.Length() == 0 {
+		return nil
+	}
+
+	scores := map[*html.Node]float64{}
+	// candidates are kept in document order so ties are broken the same way every time
+	candidates := []*html.Node{}
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode || n.Data == "html" {
+			return
+		}
+
+		if _, found := scores[n]; !found {
+			scores[n] = classWeight(n) + tagWeight(n)
+			candidates = append(candidates, n)
+		}
+
+		scores[n] += score
+	}
+
+	walkContent(body.Nodes[0], func(n *html.Node) {
+		if !paragraphTags[n.Data] || hasBlockChild(n) {
+			return
+		}
+
+		text := strings.Join(strings.Fields(nodeText(n)), " ")
+		length := utf8.RuneCountInString(text)
+		if length < minParagraphLength {
+			return
+		}
+
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(length)/100, 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+		}
+	})
+
+	var best *html.Node
+	for _, n := range candidates {
+		scores[n] *= 1 - linkDensity(n)
+
+		if best == nil || scores[n] > scores[best] {
+			best = n
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+
+	// the article holding the best block also holds its title
+	for p := best.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == "article" {
+			best = p
+			break
+		}
+	}
+
+	nodes := []*html.Node{best}
+	if best.Parent != nil && best.Data != "body" {
+		threshold := math.Max(minSiblingScore, scores[best]*siblingScoreRatio)
+
+		nodes = []*html.Node{}
+		for sibling := best.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+			if sibling == best {
+				nodes = append(nodes, sibling)
+				continue
+			}
+
+			if sibling.Type != html.ElementNode || isBoilerplate(sibling) {
+				continue
+			}
+
+			if score, found := scores[sibling]; found && score >= threshold {
+				nodes = append(nodes, sibling)
+				continue
+			}
+
+			// the title of the block, outside of it
+			if sibling.Data == "h1" || sibling.Data == "h2" || sibling.Data == "h3" {
+				nodes = append(nodes, sibling)
+				continue
+			}
+
+			// a lone paragraph of the article, outside of its block
+			if sibling.Data == "p" && utf8.RuneCountInString(nodeText(sibling)) > 80 && linkDensity(sibling) < 0.25 {
+				nodes = append(nodes, sibling)
+			}
+		}
+	}
+
+	return nodes
+}
+
+// contentLines returns the unique lines of text of the blocks
+func contentLines(nodes []*html.Node) []string {
+	lines := []string{}
+	seen := map[string]bool{}
+	for _, n := range nodes {
+		lines = appendLines(lines, seen, blockText(n))
+	}
+
+	return lines
+}
+
+// countStructure counts the paragraphs, headings, images, lists and tables of the blocks, leaving out the same
+// boilerplate as their text
+func countStructure(nodes []*html.Node) ContentStructure {
+	structure := ContentStructure{}
+
+	var count func(n *html.Node)
+	count = func(n *html.Node) {
+		if n.Type != html.ElementNode || isBoilerplate(n) || (containerTags[n.Data] && linkDensity(n) > maxLinkDensity) {
+			return
+		}
+
+		switch n.Data {
+		case "p":
+			if strings.TrimSpace(nodeText(n)) != "" {
+				structure.Paragraphs++
+			}
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			structure.Headings++
+		case "img":
+			structure.Images++
+		case "ul", "ol":
+			structure.Lists++
+		case "table":
+			structure.Tables++
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			count(c)
+		}
+	}
+
+	for _, n := range nodes {
+		count(n)
+	}
+
+	return structure
+}
+
+// walkContent visits the elements under the node, skipping boilerplate
+func walkContent(n *html.Node, visit func(n *html.Node)) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || isBoilerplate(c) {
+			continue
+		}
+
+		visit(c)
+		walkContent(c, visit)
+	}
+}
+
+// isBoilerplate returns whether the element is navigation, a banner, a sidebar or hidden by the page
+func isBoilerplate(n *html.Node) bool {
+	if boilerplateTags[n.Data] || boilerplateRoles[attr(n, "role")] {
+		return true
+	}
+
+	if _, hidden := attrValue(n, "hidden"); hidden || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+
+	style := strings.ReplaceAll(strings.ToLower(attr(n, "style")), " ", "")
+	if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+		return true
+	}
+
+	switch n.Data {
+	case "html", "body", "main", "article":
+		return false
+	case "header":
+		return !hasAncestor(n, "article")
+	}
+
+	names := attr(n, "class") + " " + attr(n, "id")
+
+	return unlikelyCandidatesRegexp.MatchString(names) && !likelyCandidatesRegexp.MatchString(names)
+}
+
+// classWeight favours blocks named like content and penalises the ones named like sidebars or comments
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+
+	for _, name := range []string{attr(n, "class"), attr(n, "id")} {
+		if name == "" {
+			continue
+		}
+
+		if negativeClassRegexp.MatchString(name) {
+			weight -= 25
+		}
+
+		if positiveClassRegexp.MatchString(name) {
+			weight += 25
+		}
+	}
+
+	return weight
+}
+
+func tagWeight(n *html.Node) float64 {
+	switch n.Data {
+	case "article", "main":
+		return 10
+	case "div":
+		return 5
+	case "pre", "td", "blockquote":
+		return 3
+	case "ol", "ul", "dl", "dd", "dt", "li", "form":
+		return -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		return -5
+	default:
+		return 0
+	}
+}
+
+// linkDensity returns the ratio of the text of the element which is link text
+func linkDensity(n *html.Node) float64 {
+	length := utf8.RuneCountInString(strings.TrimSpace(nodeText(n)))
+	if length == 0 {
+		return 0
+	}
+
+	linkLength := 0
+	var count func(n *html.Node)
+	count = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "a" {
+				linkLength += utf8.RuneCountInString(strings.TrimSpace(nodeText(c)))
+				continue
+			}
+
+			count(c)
+		}
+	}
+	count(n)
+
+	return float64(linkLength) / float64(length)
+}
+
+// blockText returns the text of the element with a line per block, leaving out boilerplate and the lists and
+// sections which are mostly links
+func blockText(n *html.Node) string {
+	var b strings.Builder
+
+	var write func(n *html.Node)
+	write = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(strings.NewReplacer("\n", " ", "\r", " ").Replace(n.Data))
+		case html.ElementNode:
+			if isBoilerplate(n) || (containerTags[n.Data] && linkDensity(n) > maxLinkDensity) {
+				return
+			}
+
+			block := blockTags[n.Data]
+			if block {
+				b.WriteString("\n")
+			}
+
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				write(c)
+			}
+
+			if block {
+				b.WriteString("\n")
+			}
+		}
+	}
+	write(n)
+
+	return b.String()
+}
+
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(nodeText(c))
+	}
+
+	return b.String()
+}
+
+func hasBlockChild(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && blockTags[c.Data] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasAncestor(n *html.Node, tag string) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func attr(n *html.Node, key string) string {
+	value, _ := attrValue(n, key)
+	return value
+}
+
+func attrValue(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,69 @@
+package webscraper
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parseFixture(t *testing.T, name string) *ScrapeResult {
+	body, err := os.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+
+	res, err := parseHTML(body, "text/html")
+	require.NoError(t, err)
+
+	return res
+}
+
+func Test_MainContentArticle(t *testing.T) {
+	res := parseFixture(t, "article.html")
+
+	require.Equal(t, []string{
+		"How to choose running shoes",
+		"Choosing running shoes starts with knowing how you run, where you run and how far you go every week.",
+		"Cushioned shoes absorb the impact of long runs on the road, while lighter shoes suit faster sessions on the track.",
+		"Trail or road",
+		"Trail shoes have deeper lugs, a tougher upper and a rock plate, which make them heavier but much safer on rough ground.",
+		"Try shoes on in the afternoon, when your feet are larger.",
+		"Leave a thumb's width between your longest toe and the tip of the shoe.",
+	}, res.MainSections)
+
+	// the full body keeps the boilerplate
+	require.Contains(t, res.Body, "We use cookies")
+	require.Contains(t, res.Body, "Popular posts")
+	require.NotContains(t, res.MainContent, "We use cookies")
+	require.NotContains(t, res.MainContent, "Share on Twitter")
+	require.NotContains(t, res.MainContent, "Subscribe to our newsletter")
+	require.NotContains(t, res.MainContent, "Copyright")
+	require.Less(t, len(res.MainContent), len(res.Body))
+
+	require.Equal(t, ContentStructure{Paragraphs: 3, Headings: 2, Images: 1, Lists: 1}, res.Structure)
+}
+
+func Test_MainContentDocs(t *testing.T) {
+	res := parseFixture(t, "docs.html")
+
+	require.Equal(t, []string{
+		"Configuration reference",
+		"The service is configured with environment variables, which are read once when the process starts.",
+		"Variable",
+		"Description",
+		"PAGE_CACHE_TTL",
+		"How long a scraped page is reused by other query jobs, defaults to a day.",
+		"SCRAPER_DOMAIN_DELAY",
+		"The minimum delay between two fetches of the same domain, in seconds.",
+		"PAGE_CACHE_TTL=24h SCRAPER_DOMAIN_DELAY=1s ./crawler --verbose",
+	}, res.MainSections)
+
+	require.Equal(t, ContentStructure{Paragraphs: 1, Headings: 1, Tables: 1}, res.Structure)
+}
+
+func Test_MainContentFallsBackToBody(t *testing.T) {
+	res := parseFixture(t, "listing.html")
+
+	require.Equal(t, res.Sections, res.MainSections)
+	require.Equal(t, res.Body, res.MainContent)
+	require.Equal(t, ContentStructure{Headings: 1}, res.Structure)
+}
@@ -0,0 +1,49 @@
+package webscraper
+
+import (
+	"net/url"
+	"strings"
+)
+
+// trackingParams are query parameters that don't change the content of a page
+var trackingParams = map[string]bool{
+	"gclid":   true,
+	"fbclid":  true,
+	"msclkid": true,
+	"dclid":   true,
+}
+
+// NormalizeURL returns the canonical form of the URL used to identify a page: lowercased scheme and host,
+// no default port, fragment or tracking parameters, and sorted query parameters. URLs that can't be parsed
+// are returned as is. migrations/normalizeURL.ts mirrors it to key the pages migrated from query items.
+func NormalizeURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = u.Hostname()
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	query := u.Query()
+	for key := range query {
+		if trackingParams[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+
+	// Encode sorts the parameters by key
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
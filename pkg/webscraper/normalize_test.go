@@ -0,0 +1,32 @@
+package webscraper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NormalizeURL(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"already normalized", "https://example.com/page", "https://example.com/page"},
+		{"case of scheme and host", "HTTPS://Example.COM/Page", "https://example.com/Page"},
+		{"default port", "https://example.com:443/page", "https://example.com/page"},
+		{"non default port", "http://example.com:8080/page", "http://example.com:8080/page"},
+		{"empty path", "https://example.com", "https://example.com/"},
+		{"fragment", "https://example.com/page#section-2", "https://example.com/page"},
+		{"tracking parameters", "https://example.com/page?utm_source=google&id=3&gclid=abc", "https://example.com/page?id=3"},
+		{"parameter order", "https://example.com/page?b=2&a=1", "https://example.com/page?a=1&b=2"},
+		{"trailing slash is kept", "https://example.com/page/", "https://example.com/page/"},
+		{"not absolute", "/relative/page", "/relative/page"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, NormalizeURL(tt.url))
+		})
+	}
+}
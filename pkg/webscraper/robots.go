@@ -0,0 +1,258 @@
+package webscraper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRobotsSize is the number of bytes of a robots.txt file that are parsed, the rest is ignored
+const maxRobotsSize = 500 * 1024
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// Robots are the robots.txt rules applying to the user agent of the scraper
+type Robots struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+	// closed is set by the first rule line, even an empty one, so the next user-agent line starts a new group
+	closed bool
+}
+
+// ParseRobots returns the rules of the robots.txt content applying to the user agent. The groups naming the
+// product token of the user agent are used when there are any, even without rules, otherwise the groups of
+// "*" are.
+func ParseRobots(content, userAgent string) *Robots {
+	groups := []*robotsGroup{}
+
+	var group *robotsGroup
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+
+		switch key {
+		case "user-agent":
+			// consecutive user-agent lines share the same rules
+			if group == nil || group.closed {
+				group = &robotsGroup{}
+				groups = append(groups, group)
+			}
+			group.agents = append(group.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if group == nil {
+				continue
+			}
+			group.closed = true
+
+			// an empty disallow allows everything
+			if value == "" {
+				continue
+			}
+			group.rules = append(group.rules, robotsRule{allow: key == "allow", pattern: value})
+		case "crawl-delay":
+			if group == nil {
+				continue
+			}
+			group.closed = true
+
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				group.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	// without a product token only the groups of every crawler apply
+	agents := []string{"*"}
+	if token := productToken(userAgent); token != "" {
+		agents = []string{token, "*"}
+	}
+
+	robots := &Robots{}
+	for _, agent := range agents {
+		matched := false
+		for _, g := range groups {
+			if containsAgent(g.agents, agent) {
+				matched = true
+				robots.rules = append(robots.rules, g.rules...)
+				if g.crawlDelay > robots.crawlDelay {
+					robots.crawlDelay = g.crawlDelay
+				}
+			}
+		}
+
+		if matched {
+			break
+		}
+	}
+
+	return robots
+}
+
+// Allowed returns whether the URL can be fetched. The longest matching rule wins and allow rules win ties.
+func (r *Robots) Allowed(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	if path == "/robots.txt" {
+		return true
+	}
+
+	allowed := true
+	matchedLength := -1
+	for _, rule := range r.rules {
+		if !matchRobotsPattern(rule.pattern, path) {
+			continue
+		}
+
+		if len(rule.pattern) > matchedLength || (len(rule.pattern) == matchedLength && rule.allow) {
+			allowed = rule.allow
+			matchedLength = len(rule.pattern)
+		}
+	}
+
+	return allowed
+}
+
+// CrawlDelay returns the delay between requests asked by the site, 0 when there's none
+func (r *Robots) CrawlDelay() time.Duration {
+	return r.crawlDelay
+}
+
+// FetchRobots returns the robots.txt content of the site of the URL. Sites without one return an empty
+// content, which allows everything. Server errors are returned as a *ScrapeError as the site is unreachable.
+func (c *Client) FetchRobots(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse url (%s): %v", rawURL, err)
+	}
+
+	robotsURL := fmt.Sprintf("%s://%s/robots.txt", u.Scheme, u.Host)
+
+	start := time.Now()
+
+	res, err := c.httpFetcher.Fetch(ctx, robotsURL)
+	if err != nil {
+		return "", newScrapeError(Diagnostics{FinalURL: robotsURL}, start, errorCategory(err), fmt.Errorf("failed to get robots.txt (%s): %v", robotsURL, err))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusInternalServerError {
+		return "", newScrapeError(responseDiagnostics(res), start, FailureRobotsUnreachable, fmt.Errorf("robots.txt status code error: %d %s", res.StatusCode, res.Status))
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return "", nil
+	}
+
+	content, err := io.ReadAll(io.LimitReader(res.Body, maxRobotsSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read robots.txt (%s): %v", robotsURL, err)
+	}
+
+	return string(content), nil
+}
+
+// Origin returns the scheme and host of the URL robots.txt rules apply to, e.g. "https://example.com"
+func Origin(rawURL string) string {
+	u, err := url.Parse(NormalizeURL(rawURL))
+	if err != nil {
+		return rawURL
+	}
+
+	return fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+}
+
+// productToken returns the name of the user agent robots.txt groups refer to, e.g. "examplebot" for
+// "ExampleBot/1.0 (+https://example.com/bot)", or "" when the user agent is empty
+func productToken(userAgent string) string {
+	fields := strings.Fields(userAgent)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	token := fields[0]
+	if i := strings.Index(token, "/"); i >= 0 {
+		token = token[:i]
+	}
+
+	return strings.ToLower(token)
+}
+
+func containsAgent(agents []string, agent string) bool {
+	for _, a := range agents {
+		if a == agent {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchRobotsPattern matches the path against a robots.txt rule, where "*" matches any characters and a
+// trailing "$" anchors the end of the path
+func matchRobotsPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+
+	if len(parts) == 1 {
+		return !anchored || path == parts[0]
+	}
+
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+
+		j := strings.Index(rest, part)
+		if j < 0 {
+			return false
+		}
+		rest = rest[j+len(part):]
+	}
+
+	return true
+}
@@ -0,0 +1,103 @@
+package webscraper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testRobots = `
+# comments are ignored
+User-agent: *
+Disallow: /private/
+Allow: /private/public-page
+Disallow: /*.pdf$
+Disallow: /search?
+Crawl-delay: 2
+
+User-agent: ExampleBot
+User-agent: OtherBot
+Disallow: /no-bots
+Crawl-delay: 0.5
+`
+
+func Test_ParseRobots(t *testing.T) {
+	robots := ParseRobots(testRobots, "CompetitiveAnalysisBot/1.0 (+https://example.com/bot)")
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com/", true},
+		{"https://example.com/private/page", false},
+		{"https://example.com/private/public-page", true},
+		{"https://example.com/files/guide.pdf", false},
+		{"https://example.com/files/guide.pdf?download=1", true},
+		{"https://example.com/search?q=shoes", false},
+		{"https://example.com/search", true},
+		{"https://example.com/robots.txt", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			require.Equal(t, tt.want, robots.Allowed(tt.url))
+		})
+	}
+
+	require.Equal(t, 2*time.Second, robots.CrawlDelay())
+}
+
+func Test_ParseRobotsNamedAgent(t *testing.T) {
+	robots := ParseRobots(testRobots, "OtherBot/2.0")
+
+	require.False(t, robots.Allowed("https://example.com/no-bots"))
+	require.True(t, robots.Allowed("https://example.com/private/page"))
+	require.Equal(t, 500*time.Millisecond, robots.CrawlDelay())
+}
+
+func Test_ParseRobotsEmpty(t *testing.T) {
+	robots := ParseRobots("", "ExampleBot")
+
+	require.True(t, robots.Allowed("https://example.com/anything"))
+	require.Equal(t, time.Duration(0), robots.CrawlDelay())
+}
+
+func Test_ParseRobotsDisallowAll(t *testing.T) {
+	robots := ParseRobots("User-agent: *\nDisallow: /\n", "ExampleBot")
+
+	require.False(t, robots.Allowed("https://example.com/"))
+	require.False(t, robots.Allowed("https://example.com/page"))
+}
+
+func Test_ParseRobotsEmptyDisallowClosesGroup(t *testing.T) {
+	robots := ParseRobots("User-agent: *\nDisallow:\n\nUser-agent: BadBot\nDisallow: /\n", "CompetitiveAnalysisBot/1.0")
+
+	require.True(t, robots.Allowed("https://example.com/page"))
+
+	robots = ParseRobots("User-agent: *\nDisallow:\n\nUser-agent: BadBot\nDisallow: /\n", "BadBot")
+
+	require.False(t, robots.Allowed("https://example.com/page"))
+}
+
+func Test_ParseRobotsNamedAgentWithoutRules(t *testing.T) {
+	robots := ParseRobots("User-agent: *\nDisallow: /\n\nUser-agent: ExampleBot\nDisallow:\n", "ExampleBot/1.0")
+
+	require.True(t, robots.Allowed("https://example.com/page"))
+}
+
+func Test_ParseRobotsWithoutUserAgent(t *testing.T) {
+	content := "User-agent: ExampleBot\nDisallow: /\n\nUser-agent: *\nDisallow: /private\n"
+
+	for _, userAgent := range []string{"", "  "} {
+		robots := ParseRobots(content, userAgent)
+
+		require.True(t, robots.Allowed("https://example.com/page"))
+		require.False(t, robots.Allowed("https://example.com/private"))
+	}
+}
+
+func Test_Origin(t *testing.T) {
+	require.Equal(t, "https://example.com", Origin("HTTPS://Example.com:443/page?q=1"))
+	require.Equal(t, "http://example.com:8080", Origin("http://example.com:8080/page"))
+}
@@ -15,16 +15,15 @@ import (
 )
 
 const (
-	zenserpBaseURL     = "https://app.zenserp.com"
-	searchPath         = "api/v2/search?q=%s&num=%d&search_engine=%s&device=%s"
-	searchCountryPath  = "api/v2/search?q=%s&num=%d&search_engine=%s&device=%s&gl=%s"
-	searchLocationPath = "api/v2/search?q=%s&num=%d&search_engine=%s&device=%s&gl=%s&location=%s"
-	batchPath          = "api/v1/batches"
-	getBatchPath       = "api/v1/batches/%s"
+	zenserpBaseURL = "https://app.zenserp.com"
+	searchPath     = "api/v2/search"
+	batchPath      = "api/v1/batches"
+	getBatchPath   = "api/v1/batches/%s"
+	statusPath     = "api/v2/status"
 )
 
 func (c *Client) do(ctx context.Context, method string, endpoint string, body []byte, contentType string) ([]byte, error) {
-	p := strings.Split(endpoint, "?")
+	p := strings.SplitN(endpoint, "?", 2)
 
 	rel := &url.URL{Path: p[0]}
 
@@ -3,15 +3,41 @@ package zenserp
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strconv"
 )
 
-func (c *Client) Search(ctx context.Context, query, searchEngine, device string, num int) (*QueryResult, error) {
+// SearchOptions holds the optional Zenserp search parameters. Zero values are omitted from the request.
+type SearchOptions struct {
+	// Language sets the interface language (hl), e.g. "en"
+	Language string
+	// Start is the result offset used for pagination (start)
+	Start int
+	// TimeFilter restricts results to a time range (tbs), e.g. "qdr:d" or "qdr:w"
+	TimeFilter string
+	// Vertical selects a search vertical (tbm), e.g. "nws", "isch", "vid" or "shop"
+	Vertical string
+}
+
+func (c *Client) Search(ctx context.Context, query, searchEngine, device string, num int, opts *SearchOptions) (*QueryResult, error) {
+	return c.search(ctx, searchValues(query, searchEngine, device, "", "", num, opts), num)
+}
+
+func (c *Client) SearchWithCountry(ctx context.Context, query, searchEngine, device, country string, num int, opts *SearchOptions) (*QueryResult, error) {
+	return c.search(ctx, searchValues(query, searchEngine, device, country, "", num, opts), num)
+}
+
+func (c *Client) SearchWithLocation(ctx context.Context, query, searchEngine, device, country, location string, num int, opts *SearchOptions) (*QueryResult, error) {
+	return c.search(ctx, searchValues(query, searchEngine, device, country, location, num, opts), num)
+}
+
+func (c *Client) search(ctx context.Context, values url.Values, num int) (*QueryResult, error) {
 	if num > 100 {
 		return nil, fmt.Errorf("result count (num) of %d, not allowed", num)
 	}
 
 	res := &QueryResult{}
-	endpoint := fmt.Sprintf(searchPath, query, num, searchEngine, device)
+	endpoint := fmt.Sprintf("%s?%s", searchPath, values.Encode())
 	err := c.getJSON(ctx, endpoint, res)
 
 	if err != nil {
@@ -21,34 +47,41 @@ func (c *Client) Search(ctx context.Context, query, searchEngine, device string,
 	return res, nil
 }
 
-func (c *Client) SearchWithCountry(ctx context.Context, query, searchEngine, device, country string, num int) (*QueryResult, error) {
-	if num > 100 {
-		return nil, fmt.Errorf("result count (num) of %d, not allowed", num)
+// searchValues builds the encoded query string parameters for a search request
+func searchValues(query, searchEngine, device, country, location string, num int, opts *SearchOptions) url.Values {
+	values := url.Values{}
+	values.Set("q", query)
+	values.Set("num", strconv.Itoa(num))
+	values.Set("search_engine", searchEngine)
+	values.Set("device", device)
+
+	if country != "" {
+		values.Set("gl", country)
 	}
 
-	res := &QueryResult{}
-	endpoint := fmt.Sprintf(searchCountryPath, query, num, searchEngine, device, country)
-	err := c.getJSON(ctx, endpoint, res)
+	if location != "" {
+		values.Set("location", location)
+	}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to query Zenserp (%s): %w", endpoint, err)
+	if opts == nil {
+		return values
 	}
 
-	return res, nil
-}
+	if opts.Language != "" {
+		values.Set("hl", opts.Language)
+	}
 
-func (c *Client) SearchWithLocation(ctx context.Context, query, searchEngine, device, country, location string, num int) (*QueryResult, error) {
-	if num > 100 {
-		return nil, fmt.Errorf("result count (num) of %d, not allowed", num)
+	if opts.Start > 0 {
+		values.Set("start", strconv.Itoa(opts.Start))
 	}
 
-	res := &QueryResult{}
-	endpoint := fmt.Sprintf(searchLocationPath, query, num, searchEngine, device, country, location)
-	err := c.getJSON(ctx, endpoint, res)
+	if opts.TimeFilter != "" {
+		values.Set("tbs", opts.TimeFilter)
+	}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to query Zenserp (%s): %w", endpoint, err)
+	if opts.Vertical != "" {
+		values.Set("tbm", opts.Vertical)
 	}
 
-	return res, nil
+	return values
 }
@@ -0,0 +1,136 @@
+package zenserp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := NewClient("test-key", server.Client(), "")
+	require.NoError(t, err)
+
+	c.baseURL, err = url.Parse(server.URL)
+	require.NoError(t, err)
+
+	return c
+}
+
+func Test_SearchEncoding(t *testing.T) {
+	tests := []struct {
+		name           string
+		search         func(c *Client) (*QueryResult, error)
+		expectedQuery  url.Values
+		expectedAbsent []string
+	}{
+		{
+			name: "encodes reserved characters in the keyword",
+			search: func(c *Client) (*QueryResult, error) {
+				return c.Search(context.Background(), "salt & pepper #1 grinder", "google.com", "desktop", 10, nil)
+			},
+			expectedQuery: url.Values{
+				"q":             {"salt & pepper #1 grinder"},
+				"num":           {"10"},
+				"search_engine": {"google.com"},
+				"device":        {"desktop"},
+			},
+			expectedAbsent: []string{"gl", "location", "hl", "start", "tbs", "tbm"},
+		},
+		{
+			name: "encodes non-ASCII keyword with country",
+			search: func(c *Client) (*QueryResult, error) {
+				return c.SearchWithCountry(context.Background(), "café crème brûlée", "google.fr", "mobile", "FR", 20, nil)
+			},
+			expectedQuery: url.Values{
+				"q":             {"café crème brûlée"},
+				"num":           {"20"},
+				"search_engine": {"google.fr"},
+				"device":        {"mobile"},
+				"gl":            {"FR"},
+			},
+			expectedAbsent: []string{"location"},
+		},
+		{
+			name: "encodes location and options",
+			search: func(c *Client) (*QueryResult, error) {
+				opts := &SearchOptions{
+					Language:   "en",
+					Start:      10,
+					TimeFilter: "qdr:w",
+					Vertical:   "nws",
+				}
+				return c.SearchWithLocation(context.Background(), "best c++ ide?", "google.com", "desktop", "US", "Austin County,Texas,United States", 100, opts)
+			},
+			expectedQuery: url.Values{
+				"q":             {"best c++ ide?"},
+				"num":           {"100"},
+				"search_engine": {"google.com"},
+				"device":        {"desktop"},
+				"gl":            {"US"},
+				"location":      {"Austin County,Texas,United States"},
+				"hl":            {"en"},
+				"start":         {"10"},
+				"tbs":           {"qdr:w"},
+				"tbm":           {"nws"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var receivedQuery url.Values
+			var receivedPath string
+			var receivedAPIKey string
+
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				receivedPath = r.URL.Path
+				receivedQuery = r.URL.Query()
+				receivedAPIKey = r.Header.Get("apikey")
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"query": {"q": "ok"}, "organic": [{"position": 1, "url": "https://example.com"}]}`))
+			})
+
+			res, err := tt.search(c)
+			require.NoError(t, err)
+			require.Equal(t, "/api/v2/search", receivedPath)
+			require.Equal(t, "test-key", receivedAPIKey)
+			require.Len(t, res.ResulItems, 1)
+
+			for key, value := range tt.expectedQuery {
+				require.Equal(t, value, receivedQuery[key], key)
+			}
+
+			for _, key := range tt.expectedAbsent {
+				_, found := receivedQuery[key]
+				require.False(t, found, key)
+			}
+		})
+	}
+}
+
+func Test_SearchRejectsLargeNum(t *testing.T) {
+	called := false
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	_, err := c.Search(context.Background(), "keyword", "google.com", "desktop", 101, nil)
+	require.Error(t, err)
+	require.False(t, called)
+}
+
+func Test_SearchNonOKStatus(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	_, err := c.Search(context.Background(), "keyword", "google.com", "desktop", 10, nil)
+	require.Error(t, err)
+}
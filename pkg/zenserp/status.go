@@ -0,0 +1,18 @@
+package zenserp
+
+import (
+	"context"
+	"fmt"
+)
+
+// Status returns the account status, including the number of remaining search credits
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var s Status
+
+	err := c.getJSON(ctx, statusPath, &s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	return &s, nil
+}
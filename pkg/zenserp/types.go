@@ -1,5 +1,10 @@
 package zenserp
 
+import (
+	"net/url"
+	"strings"
+)
+
 type QueryInfo struct {
 	Query        string `json:"q"`
 	SearchEngine string `json:"search_engine"`
@@ -58,3 +63,25 @@ type Batch struct {
 	State   string                          `json:"state"`
 	Results []QueryResultWithoutDescription `json:"jobs"`
 }
+
+type Status struct {
+	RemainingRequests int `json:"remaining_requests"`
+}
+
+// CacheKey returns a key identifying the search parameters of the job. Parameters are normalized so
+// the same search typed differently (casing, extra whitespace) maps to the same key.
+func (j Job) CacheKey() string {
+	values := url.Values{}
+	values.Set("q", normalize(j.Query))
+	values.Set("num", normalize(j.Num))
+	values.Set("search_engine", normalize(j.SearchEngine))
+	values.Set("device", normalize(j.Device))
+	values.Set("gl", normalize(j.Country))
+	values.Set("location", normalize(j.Location))
+
+	return values.Encode()
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
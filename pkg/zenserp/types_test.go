@@ -0,0 +1,37 @@
+package zenserp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_JobCacheKey(t *testing.T) {
+	job := Job{
+		Query:        "Best Running Shoes",
+		Num:          "100",
+		SearchEngine: "google.com",
+		Device:       "desktop",
+		Country:      "US",
+		Location:     "Austin County,Texas,United States",
+	}
+
+	sameSearch := Job{
+		Query:        "  best   running shoes ",
+		Num:          "100",
+		SearchEngine: "Google.com",
+		Device:       "Desktop",
+		Country:      "us",
+		Location:     "austin county,texas,united states",
+	}
+
+	otherDevice := job
+	otherDevice.Device = "mobile"
+
+	otherLocation := job
+	otherLocation.Location = "Kingfield,Maine,United States"
+
+	require.Equal(t, job.CacheKey(), sameSearch.CacheKey())
+	require.NotEqual(t, job.CacheKey(), otherDevice.CacheKey())
+	require.NotEqual(t, job.CacheKey(), otherLocation.CacheKey())
+}